@@ -25,12 +25,21 @@ Options:
   --target=...          Environment target (e.g. es2017, chrome58, firefox57,
                         safari11, edge16, node10, default esnext)
   --platform=...        Platform target (browser | node, default browser)
-  --external:M          Exclude module M from the bundle
-  --format=...          Output format (iife | cjs | esm, no default when not
-                        bundling, otherwise default is iife when platform
-                        is browser and cjs when platform is node)
-  --splitting           Enable code splitting (currently only for esm)
-  --global-name=...     The name of the global for the IIFE format
+  --external:M          Exclude module M from the bundle (can use a * wildcard)
+  --packages=external   Exclude all package imports from the bundle
+  --alias:X=Y           Substitute package X with package or path Y
+  --format=...          Output format (iife | cjs | esm | umd | system, no
+                        default when not bundling, otherwise default is iife
+                        when platform is browser and cjs when platform is node)
+  --splitting           Enable code splitting (currently only for esm,
+                        system, cjs, and iife)
+  --manual-chunk:N=P    Put packages or paths P (comma-separated, can use
+                        * wildcards) in a chunk named N when splitting
+  --min-chunk-size=...  Merge shared chunks smaller than this many bytes
+                        with other shared chunks when splitting
+  --global-name=...     The name of the global for the IIFE and UMD formats
+  --global:M=N          Use the global variable N for external module M in
+                        the UMD format when there is no module system
 
   --minify              Sets all --minify-* flags
   --minify-whitespace   Remove whitespace
@@ -52,21 +61,41 @@ Advanced options:
   --error-limit=...         Maximum error count or 0 to disable (default 10)
   --log-level=...           Disable logging (info | warning | error | silent,
                             default info)
+  --log-format=...          Format of log messages (text | json, default text)
+  --log-override:X=Y        Use log level Y for messages with ID X (warning |
+                            error | silent)
   --resolve-extensions=...  A comma-separated list of implicit extensions
                             (default ".tsx,.ts,.jsx,.mjs,.cjs,.js,.css,.json")
   --metafile=...            Write metadata about the build to a JSON file
+  --explain=...             Print the shortest import chain from an entry
+                            point to this file or package
+  --detect-cycles           Warn about import cycles, especially ones that read
+                            a binding before it may have been initialized
+  --analyze                 Print a report of the largest input files in each
+                            output file (requires --metafile, use
+                            --analyze=gzip to include gzip-compressed sizes)
   --pure:N                  Mark the name N as a pure function for tree shaking
   --inject:F                Import the file F into all input files and
                             automatically replace matching globals with imports
   --tsconfig=...            Use this tsconfig.json file instead of other ones
+  --import-map=...          Resolve imports using this browser import map
   --out-extension:.js=.mjs  Use a custom output extension instead of ".js"
   --main-fields=...         Override the main file order in package.json
                             (default "browser,module,main" when platform is
                             browser and "main,module" when platform is node)
+  --conditions=...          Custom conditions to use for the "exports" field
+                            in package.json (e.g. development,production)
   --public-path=...         Set the base URL for the "file" loader
   --color=...               Force use of color terminal escapes (true | false)
   --charset=utf8            Do not escape UTF-8 code points
   --avoid-tdz               An optimization for large bundles in Safari
+  --watch                   Watch mode: rebuild on file system changes
+  --serve=...               Start a local HTTP server on this host:port for
+                            the build output instead of writing files
+  --servedir=...            Serve static files from this directory when
+                            using --serve
+  --hmr                     Push changed modules to the browser when using
+                            --serve instead of reloading the page
 
 Examples:
   # Produces dist/entry_point.js and dist/entry_point.js.map
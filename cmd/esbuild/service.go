@@ -6,6 +6,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -25,6 +26,7 @@ type responseCallback = func(interface{})
 type serviceType struct {
 	mutex           sync.Mutex
 	callbacks       map[uint32]responseCallback
+	cancelFuncs     map[uint32]context.CancelFunc
 	nextID          uint32
 	outgoingPackets chan outgoingPacket
 }
@@ -37,6 +39,7 @@ type outgoingPacket struct {
 func runService() {
 	service := serviceType{
 		callbacks:       make(map[uint32]responseCallback),
+		cancelFuncs:     make(map[uint32]context.CancelFunc),
 		outgoingPackets: make(chan outgoingPacket),
 	}
 	buffer := make([]byte, 16*1024)
@@ -84,9 +87,20 @@ func runService() {
 
 			// Clone the input and run it on another goroutine
 			clone := append([]byte{}, packet...)
+			p, ok := decodePacket(clone)
+			if !ok {
+				continue
+			}
+
+			// The context for a request that can be cancelled is created here
+			// instead of on the other goroutine. Otherwise a "cancel" packet that
+			// comes right after the request could be handled first and do nothing.
+			ctx, done := service.contextForPacket(p)
 			waitGroup.Add(1)
 			go func() {
-				if result := service.handleIncomingPacket(clone); result != nil {
+				result := service.handleIncomingPacket(ctx, p)
+				done()
+				if result != nil {
 					service.outgoingPackets <- outgoingPacket{bytes: result, isFinal: true}
 				} else {
 					waitGroup.Done()
@@ -127,12 +141,7 @@ func (service *serviceType) sendRequest(request interface{}) interface{} {
 	return <-result
 }
 
-func (service *serviceType) handleIncomingPacket(bytes []byte) (result []byte) {
-	p, ok := decodePacket(bytes)
-	if !ok {
-		return nil
-	}
-
+func (service *serviceType) handleIncomingPacket(ctx context.Context, p packet) (result []byte) {
 	if p.isRequest {
 		// Catch panics in the code below so they get passed to the caller
 		defer func() {
@@ -151,10 +160,19 @@ func (service *serviceType) handleIncomingPacket(bytes []byte) (result []byte) {
 		command := request["command"].(string)
 		switch command {
 		case "build":
-			return service.handleBuildRequest(p.id, request)
+			return service.handleBuildRequest(ctx, p.id, request)
 
 		case "transform":
-			return service.handleTransformRequest(p.id, request)
+			return service.handleTransformRequest(ctx, p.id, request)
+
+		case "cancel":
+			// This stops an in-progress build or transform request early. That
+			// request still gets a response, which will contain an error.
+			service.cancelRequest(uint32(request["requestId"].(int)))
+			return encodePacket(packet{
+				id:    p.id,
+				value: make(map[string]interface{}),
+			})
 
 		case "error":
 			// This just exists so that errors during JavaScript API setup get printed
@@ -190,6 +208,42 @@ func (service *serviceType) handleIncomingPacket(bytes []byte) (result []byte) {
 	return nil
 }
 
+// Requests that can be cancelled are given a context that is cancelled by a
+// later "cancel" request with the same request ID. The returned function must
+// be called when the request is done.
+func (service *serviceType) contextForPacket(p packet) (context.Context, func()) {
+	if p.isRequest {
+		if request, ok := p.value.(map[string]interface{}); ok {
+			if command, ok := request["command"].(string); ok && (command == "build" || command == "transform") {
+				return service.contextForRequest(p.id)
+			}
+		}
+	}
+	return context.Background(), func() {}
+}
+
+func (service *serviceType) contextForRequest(id uint32) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	service.cancelFuncs[id] = cancel
+	return ctx, func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		delete(service.cancelFuncs, id)
+		cancel()
+	}
+}
+
+// Cancelling a request that has already finished does nothing
+func (service *serviceType) cancelRequest(id uint32) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	if cancel, ok := service.cancelFuncs[id]; ok {
+		cancel()
+	}
+}
+
 func encodeErrorPacket(id uint32, err error) []byte {
 	return encodePacket(packet{
 		id: id,
@@ -199,7 +253,7 @@ func encodeErrorPacket(id uint32, err error) []byte {
 	})
 }
 
-func (service *serviceType) handleBuildRequest(id uint32, request map[string]interface{}) []byte {
+func (service *serviceType) handleBuildRequest(ctx context.Context, id uint32, request map[string]interface{}) []byte {
 	write := request["write"].(bool)
 	flags := decodeStringArray(request["flags"].([]interface{}))
 
@@ -234,7 +288,7 @@ func (service *serviceType) handleBuildRequest(id uint32, request map[string]int
 	}
 
 	options.Write = write
-	result := api.Build(options)
+	result := api.BuildWithContext(ctx, options)
 	response := map[string]interface{}{
 		"errors":   encodeMessages(result.Errors),
 		"warnings": encodeMessages(result.Warnings),
@@ -251,7 +305,7 @@ func (service *serviceType) handleBuildRequest(id uint32, request map[string]int
 	})
 }
 
-func (service *serviceType) handleTransformRequest(id uint32, request map[string]interface{}) []byte {
+func (service *serviceType) handleTransformRequest(ctx context.Context, id uint32, request map[string]interface{}) []byte {
 	inputFS := request["inputFS"].(bool)
 	input := request["input"].(string)
 	flags := decodeStringArray(request["flags"].([]interface{}))
@@ -275,7 +329,7 @@ func (service *serviceType) handleTransformRequest(id uint32, request map[string
 		transformInput = string(bytes)
 	}
 
-	result := api.Transform(transformInput, options)
+	result := api.TransformWithContext(ctx, transformInput, options)
 	codeFS := false
 	mapFS := false
 
@@ -336,42 +390,71 @@ func encodeOutputFiles(outputFiles []api.OutputFile) []interface{} {
 func encodeMessages(msgs []api.Message) []interface{} {
 	values := make([]interface{}, len(msgs))
 	for i, msg := range msgs {
-		value := make(map[string]interface{})
-		values[i] = value
-		value["text"] = msg.Text
-
-		// Some messages won't have a location
-		loc := msg.Location
-		if loc == nil {
-			value["location"] = nil
-		} else {
-			value["location"] = map[string]interface{}{
-				"file":     loc.File,
-				"line":     loc.Line,
-				"column":   loc.Column,
-				"length":   loc.Length,
-				"lineText": loc.LineText,
+		notes := make([]interface{}, len(msg.Notes))
+		for j, note := range msg.Notes {
+			notes[j] = map[string]interface{}{
+				"text":     note.Text,
+				"location": encodeLocation(note.Location),
 			}
 		}
+		values[i] = map[string]interface{}{
+			"id":       msg.ID,
+			"text":     msg.Text,
+			"location": encodeLocation(msg.Location),
+			"notes":    notes,
+		}
 	}
 	return values
 }
 
+func encodeLocation(loc *api.Location) interface{} {
+	// Some messages won't have a location
+	if loc == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"file":     loc.File,
+		"line":     loc.Line,
+		"column":   loc.Column,
+		"length":   loc.Length,
+		"lineText": loc.LineText,
+	}
+}
+
 func decodeMessageToPrivate(obj map[string]interface{}) logger.Msg {
-	msg := logger.Msg{Text: obj["text"].(string)}
+	msg := logger.Msg{
+		Text:     obj["text"].(string),
+		Location: decodeLocationToPrivate(obj["location"]),
+	}
 
-	// Some messages won't have a location
-	loc := obj["location"]
-	if loc != nil {
-		loc := loc.(map[string]interface{})
-		msg.Location = &logger.MsgLocation{
-			File:     loc["file"].(string),
-			Line:     loc["line"].(int),
-			Column:   loc["column"].(int),
-			Length:   loc["length"].(int),
-			LineText: loc["lineText"].(string),
+	// These are optional for messages that come from plugins
+	if id, ok := obj["id"].(string); ok {
+		msg.ID = logger.MsgID(id)
+	}
+	if notes, ok := obj["notes"].([]interface{}); ok {
+		for _, note := range notes {
+			note := note.(map[string]interface{})
+			msg.Notes = append(msg.Notes, logger.MsgData{
+				Text:     note["text"].(string),
+				Location: decodeLocationToPrivate(note["location"]),
+			})
 		}
 	}
 
 	return msg
 }
+
+func decodeLocationToPrivate(value interface{}) *logger.MsgLocation {
+	// Some messages won't have a location
+	if value == nil {
+		return nil
+	}
+	loc := value.(map[string]interface{})
+	return &logger.MsgLocation{
+		File:     loc["file"].(string),
+		Line:     loc["line"].(int),
+		Column:   loc["column"].(int),
+		Length:   loc["length"].(int),
+		LineText: loc["lineText"].(string),
+	}
+}
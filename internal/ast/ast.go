@@ -57,9 +57,19 @@ type ImportRecord struct {
 	// Tell the printer to wrap this call to "require()" in "__toModule(...)"
 	WrapWithToModule bool
 
+	// If true, this is an "import()" expression that was rewritten by code
+	// splitting to point to the chunk for another entry point
+	IsChunkImport bool
+
 	// True for require calls like this: "try { require() } catch {}". In this
 	// case we shouldn't generate an error if the path could not be resolved.
 	IsInsideTryBody bool
 
+	// If present, this is the first place where a binding imported by this
+	// record is read while the module is being evaluated (i.e. not inside a
+	// function). That read will fail if the imported module is part of an
+	// import cycle and hasn't been evaluated yet.
+	TopLevelReadRange *logger.Range
+
 	Kind ImportKind
 }
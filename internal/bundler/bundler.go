@@ -16,10 +16,10 @@ import (
 	"unicode/utf8"
 
 	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/cache"
 	"github.com/evanw/esbuild/internal/compat"
 	"github.com/evanw/esbuild/internal/config"
 	"github.com/evanw/esbuild/internal/css_ast"
-	"github.com/evanw/esbuild/internal/css_parser"
 	"github.com/evanw/esbuild/internal/fs"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
@@ -60,6 +60,16 @@ type file struct {
 	// loader.
 	additionalFiles []OutputFile
 
+	// The file and import record that first reached this file during a breadth-
+	// first traversal of the import graph starting from the entry points. This
+	// is nil for entry points and for files that aren't reachable. Following
+	// these links gives the shortest import chain from an entry point.
+	firstImporter *importerRef
+
+	// The package containing this file, if any. This is used to warn about
+	// multiple copies of the same package in the bundle.
+	packageInfo *resolver.PackageInfo
+
 	isEntryPoint bool
 
 	// If true, this file was listed as not having side effects by a package.json
@@ -67,6 +77,11 @@ type file struct {
 	ignoreIfUnused bool
 }
 
+type importerRef struct {
+	sourceIndex       uint32
+	importRecordIndex uint32
+}
+
 type fileRepr interface {
 	importRecords() []ast.ImportRecord
 }
@@ -114,11 +129,13 @@ type parseArgs struct {
 	fs              fs.FS
 	log             logger.Log
 	res             resolver.Resolver
+	caches          *cache.CacheSet
 	keyPath         logger.Path
 	prettyPath      string
 	sourceIndex     uint32
 	importSource    *logger.Source
 	flags           parseFlags
+	packageInfo     *resolver.PackageInfo
 	importPathRange logger.Range
 	options         config.Options
 	results         chan parseResult
@@ -153,18 +170,18 @@ func parseFile(args parseArgs) {
 		}
 		loader = stdin.Loader
 		absResolveDir = stdin.AbsResolveDir
-	} else if args.keyPath.Namespace == "file" {
-		// Read normal modules from disk
-		var err error
-		source.Contents, err = args.fs.ReadFile(args.keyPath.Text)
-		if err != nil {
-			if err == syscall.ENOENT {
-				args.log.AddRangeError(args.importSource, args.importPathRange,
-					fmt.Sprintf("Could not read from file: %s", args.keyPath.Text))
-			} else {
-				args.log.AddRangeError(args.importSource, args.importPathRange,
-					fmt.Sprintf("Cannot read file %q: %s", args.res.PrettyPath(args.keyPath), err.Error()))
-			}
+	} else {
+		loadResult, ok := runOnLoadPlugins(
+			args.options.Plugins,
+			args.res,
+			args.fs,
+			args.log,
+			&source,
+			args.importSource,
+			args.importPathRange,
+			args.options.ExtensionToLoader,
+		)
+		if !ok {
 			if args.inject != nil {
 				args.inject <- config.InjectedFile{
 					SourceIndex: source.Index,
@@ -173,11 +190,19 @@ func parseFile(args parseArgs) {
 			args.results <- parseResult{}
 			return
 		}
-		loader = loaderFromFileExtension(args.options.ExtensionToLoader, args.fs.Base(args.keyPath.Text))
-		absResolveDir = args.fs.Dir(args.keyPath.Text)
-	} else if source.KeyPath.Namespace == resolver.BrowserFalseNamespace {
-		// Force disabled modules to be empty
-		loader = config.LoaderJS
+		loader = loadResult.loader
+		absResolveDir = loadResult.absResolveDir
+	}
+
+	// Don't bother parsing the file if the build has been cancelled
+	if args.options.CancelFlag.DidCancel() {
+		if args.inject != nil {
+			args.inject <- config.InjectedFile{
+				SourceIndex: source.Index,
+			}
+		}
+		args.results <- parseResult{}
+		return
 	}
 
 	_, base, ext := js_ast.PlatformIndependentPathDirBaseExt(source.KeyPath.Text)
@@ -186,37 +211,38 @@ func parseFile(args parseArgs) {
 		file: file{
 			source:         source,
 			loader:         loader,
+			packageInfo:    args.packageInfo,
 			ignoreIfUnused: args.flags.ignoreIfUnused,
 		},
 	}
 
 	switch loader {
 	case config.LoaderJS:
-		ast, ok := js_parser.Parse(args.log, source, args.options)
+		ast, ok := args.caches.JSCache.Parse(args.log, source, args.options)
 		result.file.repr = &reprJS{ast: ast}
 		result.ok = ok
 
 	case config.LoaderJSX:
 		args.options.JSX.Parse = true
-		ast, ok := js_parser.Parse(args.log, source, args.options)
+		ast, ok := args.caches.JSCache.Parse(args.log, source, args.options)
 		result.file.repr = &reprJS{ast: ast}
 		result.ok = ok
 
 	case config.LoaderTS:
 		args.options.TS.Parse = true
-		ast, ok := js_parser.Parse(args.log, source, args.options)
+		ast, ok := args.caches.JSCache.Parse(args.log, source, args.options)
 		result.file.repr = &reprJS{ast: ast}
 		result.ok = ok
 
 	case config.LoaderTSX:
 		args.options.TS.Parse = true
 		args.options.JSX.Parse = true
-		ast, ok := js_parser.Parse(args.log, source, args.options)
+		ast, ok := args.caches.JSCache.Parse(args.log, source, args.options)
 		result.file.repr = &reprJS{ast: ast}
 		result.ok = ok
 
 	case config.LoaderCSS:
-		ast := css_parser.Parse(args.log, source, args.options)
+		ast := args.caches.CSSCache.Parse(args.log, source, args.options)
 		result.file.repr = &reprCSS{ast: ast}
 		result.ok = true
 
@@ -237,7 +263,7 @@ func parseFile(args parseArgs) {
 		result.ok = true
 
 	case config.LoaderBase64:
-		mimeType := guessMimeType(ext, source.Contents)
+		mimeType := GuessMimeType(ext, source.Contents)
 		encoded := base64.StdEncoding.EncodeToString([]byte(source.Contents))
 		expr := js_ast.Expr{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(encoded)}}
 		ast := js_parser.LazyExportAST(args.log, source, args.options, expr, "")
@@ -256,7 +282,7 @@ func parseFile(args parseArgs) {
 		result.ok = true
 
 	case config.LoaderDataURL:
-		mimeType := guessMimeType(ext, source.Contents)
+		mimeType := GuessMimeType(ext, source.Contents)
 		encoded := base64.StdEncoding.EncodeToString([]byte(source.Contents))
 		url := "data:" + mimeType + ";base64," + encoded
 		expr := js_ast.Expr{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(url)}}
@@ -304,6 +330,15 @@ func parseFile(args parseArgs) {
 			fmt.Sprintf("File could not be loaded: %s", args.prettyPath))
 	}
 
+	// The parsed AST may be shared with other builds via the cache, so clone
+	// the import records since the scanner mutates them below
+	switch repr := result.file.repr.(type) {
+	case *reprJS:
+		repr.ast.ImportRecords = append([]ast.ImportRecord{}, repr.ast.ImportRecords...)
+	case *reprCSS:
+		repr.ast.ImportRecords = append([]ast.ImportRecord{}, repr.ast.ImportRecords...)
+	}
+
 	// This must come before we send on the "results" channel to avoid deadlock
 	if args.inject != nil {
 		var exports []string
@@ -329,7 +364,8 @@ func parseFile(args parseArgs) {
 
 	// Run the resolver on the parse thread so it's not run on the main thread.
 	// That way the main thread isn't blocked if the resolver takes a while.
-	if args.options.Mode == config.ModeBundle && !args.skipResolve {
+	// The resolved paths won't be used if the build has been cancelled.
+	if args.options.Mode == config.ModeBundle && !args.skipResolve && !args.options.CancelFlag.DidCancel() {
 		records := result.file.repr.importRecords()
 		result.resolveResults = make([]*resolver.ResolveResult, len(records))
 
@@ -361,17 +397,24 @@ func parseFile(args parseArgs) {
 				}
 
 				// Run the resolver and log an error if the path couldn't be resolved
-				var resolveResult *resolver.ResolveResult
-				if absResolveDir != "" {
-					resolveResult = args.res.Resolve(absResolveDir, record.Path.Text, record.Kind)
-				}
+				resolveResult, didLogError, debug := runOnResolvePlugins(
+					args.options.Plugins,
+					args.res,
+					args.log,
+					args.fs,
+					&source,
+					record.Range,
+					record.Path.Text,
+					record.Kind,
+					absResolveDir,
+				)
 				cache[record.Path.Text] = resolveResult
 
 				// All "require.resolve()" imports should be external because we don't
 				// want to waste effort traversing into them
 				if record.Kind == ast.ImportRequireResolve {
 					if !record.IsInsideTryBody && (resolveResult == nil || !resolveResult.IsExternal) {
-						args.log.AddRangeWarning(&source, record.Range,
+						args.log.AddRangeWarning(logger.MsgIDRequireResolveNotExternal, &source, record.Range,
 							fmt.Sprintf("%q should be marked as external for use with \"require.resolve\"", record.Path.Text))
 					}
 					continue
@@ -382,7 +425,7 @@ func parseFile(args parseArgs) {
 					// external imports instead of causing errors. This matches a common
 					// code pattern for conditionally importing a module with a graceful
 					// fallback.
-					if !record.IsInsideTryBody {
+					if !didLogError && !record.IsInsideTryBody {
 						hint := ""
 						if resolver.IsPackagePath(record.Path.Text) {
 							hint = " (mark it as external to exclude it from the bundle)"
@@ -392,8 +435,8 @@ func parseFile(args parseArgs) {
 								hint = " (set platform to \"node\" when building for node)"
 							}
 						}
-						args.log.AddRangeError(&source, record.Range,
-							fmt.Sprintf("Could not resolve %q%s", record.Path.Text, hint))
+						args.log.AddRangeErrorWithNotes(&source, record.Range,
+							fmt.Sprintf("Could not resolve %q%s", record.Path.Text, hint), debug.Notes)
 					}
 					continue
 				}
@@ -419,7 +462,216 @@ func parseFile(args parseArgs) {
 	args.results <- result
 }
 
-func guessMimeType(extension string, contents string) string {
+func runOnResolvePlugins(
+	plugins []config.Plugin,
+	res resolver.Resolver,
+	log logger.Log,
+	fs fs.FS,
+	importSource *logger.Source,
+	importPathRange logger.Range,
+	path string,
+	kind ast.ImportKind,
+	absResolveDir string,
+) (*resolver.ResolveResult, bool, resolver.DebugMeta) {
+	resolverArgs := config.OnResolveArgs{
+		Path:       path,
+		Importer:   importSource.KeyPath,
+		ResolveDir: absResolveDir,
+	}
+	applyPath := logger.Path{Text: path, Namespace: importSource.KeyPath.Namespace}
+
+	// Apply resolver plugins in order until one succeeds
+	for _, plugin := range plugins {
+		for _, onResolve := range plugin.OnResolve {
+			if !config.PluginAppliesToPath(applyPath, onResolve.Filter, onResolve.Namespace) {
+				continue
+			}
+
+			result := onResolve.Callback(resolverArgs)
+			pluginName := result.PluginName
+			if pluginName == "" {
+				pluginName = plugin.Name
+			}
+			didLogError := logPluginMessages(log, pluginName, result.Msgs, result.ThrownError, importSource, importPathRange)
+
+			// Stop now if there was an error
+			if didLogError {
+				return nil, true, resolver.DebugMeta{}
+			}
+
+			// Otherwise, continue on to the next resolver if this resolver didn't succeed
+			if result.Path.Text == "" {
+				if result.External {
+					result.Path = logger.Path{Text: path}
+				} else {
+					continue
+				}
+			}
+
+			// Paths in the file namespace must be absolute paths
+			if result.Path.Namespace == "file" && !fs.IsAbs(result.Path.Text) {
+				log.AddRangeError(importSource, importPathRange,
+					fmt.Sprintf("Plugin %q returned a non-absolute path: %s (set a namespace if this is not a file path)",
+						pluginName, result.Path.Text))
+				return nil, true, resolver.DebugMeta{}
+			}
+
+			return &resolver.ResolveResult{
+				PathPair:   resolver.PathPair{Primary: result.Path},
+				IsExternal: result.External,
+			}, false, resolver.DebugMeta{}
+		}
+	}
+
+	// Resolve relative to the resolve directory by default. All paths in the
+	// "file" namespace automatically have a resolve directory. Loader plugins
+	// can also configure a custom resolve directory for files in other namespaces.
+	if absResolveDir == "" {
+		return nil, false, resolver.DebugMeta{}
+	}
+	var importer string
+	if importSource != nil && importSource.KeyPath.Namespace == "file" {
+		importer = importSource.KeyPath.Text
+	}
+	result, debug := res.Resolve(absResolveDir, importer, path, kind)
+	return result, false, debug
+}
+
+type loaderPluginResult struct {
+	loader        config.Loader
+	absResolveDir string
+}
+
+func runOnLoadPlugins(
+	plugins []config.Plugin,
+	res resolver.Resolver,
+	fs fs.FS,
+	log logger.Log,
+	source *logger.Source,
+	importSource *logger.Source,
+	importPathRange logger.Range,
+	extensionToLoader map[string]config.Loader,
+) (loaderPluginResult, bool) {
+	loaderArgs := config.OnLoadArgs{
+		Path: source.KeyPath,
+	}
+
+	// Force disabled modules to be empty
+	if source.KeyPath.Namespace == resolver.BrowserFalseNamespace {
+		return loaderPluginResult{loader: config.LoaderJS}, true
+	}
+
+	// Apply loader plugins in order until one succeeds
+	for _, plugin := range plugins {
+		for _, onLoad := range plugin.OnLoad {
+			if !config.PluginAppliesToPath(source.KeyPath, onLoad.Filter, onLoad.Namespace) {
+				continue
+			}
+
+			result := onLoad.Callback(loaderArgs)
+			pluginName := result.PluginName
+			if pluginName == "" {
+				pluginName = plugin.Name
+			}
+			didLogError := logPluginMessages(log, pluginName, result.Msgs, result.ThrownError, importSource, importPathRange)
+
+			// Stop now if there was an error
+			if didLogError {
+				return loaderPluginResult{}, false
+			}
+
+			// Otherwise, continue on to the next loader if this loader didn't succeed
+			if result.Contents == nil {
+				continue
+			}
+
+			source.Contents = *result.Contents
+			loader := result.Loader
+			if loader == config.LoaderNone {
+				loader = loaderFromFileExtension(extensionToLoader, fs.Base(source.KeyPath.Text))
+				if loader == config.LoaderNone {
+					loader = config.LoaderJS
+				}
+			}
+			absResolveDir := result.AbsResolveDir
+			if absResolveDir == "" && source.KeyPath.Namespace == "file" {
+				absResolveDir = fs.Dir(source.KeyPath.Text)
+			}
+			return loaderPluginResult{
+				loader:        loader,
+				absResolveDir: absResolveDir,
+			}, true
+		}
+	}
+
+	// Read normal modules from disk
+	if source.KeyPath.Namespace == "file" {
+		contents, err := fs.ReadFile(source.KeyPath.Text)
+		if err != nil {
+			if err == syscall.ENOENT {
+				log.AddRangeError(importSource, importPathRange,
+					fmt.Sprintf("Could not read from file: %s", source.KeyPath.Text))
+			} else {
+				log.AddRangeError(importSource, importPathRange,
+					fmt.Sprintf("Cannot read file %q: %s", res.PrettyPath(source.KeyPath), err.Error()))
+			}
+			return loaderPluginResult{}, false
+		}
+		source.Contents = contents
+		return loaderPluginResult{
+			loader:        loaderFromFileExtension(extensionToLoader, fs.Base(source.KeyPath.Text)),
+			absResolveDir: fs.Dir(source.KeyPath.Text),
+		}, true
+	}
+
+	// Otherwise, there is no way to load this path. This causes the caller to
+	// report a "File could not be loaded" error.
+	return loaderPluginResult{loader: config.LoaderNone}, true
+}
+
+func logPluginMessages(
+	log logger.Log,
+	name string,
+	msgs []logger.Msg,
+	thrown error,
+	importSource *logger.Source,
+	importPathRange logger.Range,
+) bool {
+	didLogError := false
+
+	// Report errors and warnings generated by the plugin
+	for _, msg := range msgs {
+		if name != "" {
+			msg.Text = fmt.Sprintf("[%s] %s", name, msg.Text)
+		}
+		if msg.Kind == logger.Error {
+			didLogError = true
+		}
+
+		// Sometimes errors returned by plugins won't have a location. Use the
+		// location of the import path instead in that case.
+		if msg.Location == nil {
+			msg.Location = logger.LocationOrNil(importSource, importPathRange)
+		}
+
+		log.AddMsg(msg)
+	}
+
+	// Report errors thrown by the plugin itself
+	if thrown != nil {
+		didLogError = true
+		text := thrown.Error()
+		if name != "" {
+			text = fmt.Sprintf("[%s] %s", name, text)
+		}
+		log.AddRangeError(importSource, importPathRange, text)
+	}
+
+	return didLogError
+}
+
+// This is also used by the development server to set the "Content-Type" header
+func GuessMimeType(extension string, contents string) string {
 	mimeType := mime.TypeByExtension(extension)
 	if mimeType == "" {
 		mimeType = http.DetectContentType([]byte(contents))
@@ -440,7 +692,7 @@ func extractSourceMapFromComment(log logger.Log, fs fs.FS, res resolver.Resolver
 				decoded, err := base64.StdEncoding.DecodeString(encoded)
 				if err != nil {
 					r := logger.Range{Loc: logger.Loc{Start: comment.Range.Loc.Start + n}, Len: comment.Range.Len - n}
-					log.AddRangeWarning(source, r, "Invalid base64 data in source map")
+					log.AddRangeWarning(logger.MsgIDUnsupportedSourceMapComment, source, r, "Invalid base64 data in source map")
 					return logger.Path{}, nil
 				}
 				contents := string(decoded)
@@ -449,7 +701,7 @@ func extractSourceMapFromComment(log logger.Log, fs fs.FS, res resolver.Resolver
 		}
 
 		// Anything else is unsupported
-		log.AddRangeWarning(source, comment.Range, "Unsupported source map comment")
+		log.AddRangeWarning(logger.MsgIDUnsupportedSourceMapComment, source, comment.Range, "Unsupported source map comment")
 		return logger.Path{}, nil
 	}
 
@@ -470,7 +722,7 @@ func extractSourceMapFromComment(log logger.Log, fs fs.FS, res resolver.Resolver
 	}
 
 	// Anything else is unsupported
-	log.AddRangeWarning(source, comment.Range, "Unsupported source map comment")
+	log.AddRangeWarning(logger.MsgIDUnsupportedSourceMapComment, source, comment.Range, "Unsupported source map comment")
 	return logger.Path{}, nil
 }
 
@@ -503,8 +755,14 @@ func hashForFileName(bytes []byte) string {
 	return base32.StdEncoding.EncodeToString(hashBytes[:])[:8]
 }
 
-func ScanBundle(log logger.Log, fs fs.FS, res resolver.Resolver, entryPaths []string, options config.Options) Bundle {
-	results := []parseResult{}
+// The cache set is optional and may be nil. Passing the same cache set to
+// subsequent builds avoids re-parsing files that haven't changed.
+func ScanBundle(log logger.Log, fs fs.FS, res resolver.Resolver, caches *cache.CacheSet, entryPaths []string, options config.Options) Bundle {
+	if caches == nil {
+		caches = cache.MakeCacheSet()
+	}
+
+	results := make([]parseResult, runtime.SourceIndex+1)
 	visited := make(map[logger.Path]uint32)
 	resultChannel := make(chan parseResult)
 	remaining := 0
@@ -515,7 +773,6 @@ func ScanBundle(log logger.Log, fs fs.FS, res resolver.Resolver, entryPaths []st
 
 	// Always start by parsing the runtime file
 	{
-		results = append(results, parseResult{})
 		remaining++
 		go func() {
 			source, ast, ok := globalRuntimeCache.parseRuntime(&options)
@@ -546,9 +803,13 @@ func ScanBundle(log logger.Log, fs fs.FS, res resolver.Resolver, entryPaths []st
 		}
 		sourceIndex, ok := visited[visitedKey]
 		if !ok {
-			sourceIndex = uint32(len(results))
+			// Source indices must be stable across builds for cached ASTs to be
+			// reused, so files that aren't part of this build leave gaps behind
+			sourceIndex = caches.SourceIndexCache.Get(visitedKey)
 			visited[visitedKey] = sourceIndex
-			results = append(results, parseResult{})
+			for uint32(len(results)) <= sourceIndex {
+				results = append(results, parseResult{})
+			}
 			flags := parseFlags{
 				isEntryPoint:   kind == inputKindEntryPoint,
 				ignoreIfUnused: resolveResult.IgnoreIfUnused,
@@ -589,11 +850,13 @@ func ScanBundle(log logger.Log, fs fs.FS, res resolver.Resolver, entryPaths []st
 				fs:              fs,
 				log:             log,
 				res:             res,
+				caches:          caches,
 				keyPath:         path,
 				prettyPath:      prettyPath,
 				sourceIndex:     sourceIndex,
 				importSource:    importSource,
 				flags:           flags,
+				packageInfo:     resolveResult.Package,
 				importPathRange: importPathRange,
 				options:         optionsClone,
 				results:         resultChannel,
@@ -672,11 +935,13 @@ func ScanBundle(log logger.Log, fs fs.FS, res resolver.Resolver, entryPaths []st
 		entryPoints = append(entryPoints, sourceIndex)
 	}
 
-	// Continue scanning until all dependencies have been discovered
+	// Continue scanning until all dependencies have been discovered. If the
+	// build is cancelled, stop resolving imports so that no more files are
+	// parsed and just wait for the files that are already being parsed.
 	for remaining > 0 {
 		result := <-resultChannel
 		remaining--
-		if !result.ok {
+		if !result.ok || options.CancelFlag.DidCancel() {
 			continue
 		}
 
@@ -710,6 +975,10 @@ func ScanBundle(log logger.Log, fs fs.FS, res resolver.Resolver, entryPaths []st
 							}
 							record.Path.Text = relPath
 						}
+					} else {
+						// The resolver may have substituted a different path (e.g. an
+						// import map may map a package name to a URL)
+						record.Path.Text = path.Text
 					}
 				}
 			}
@@ -846,6 +1115,12 @@ func ScanBundle(log logger.Log, fs fs.FS, res resolver.Resolver, entryPaths []st
 		files[result.file.source.Index] = result.file
 	}
 
+	findFirstImporters(files, entryPoints)
+
+	if options.DetectCycles {
+		reportImportCycles(log, files)
+	}
+
 	return Bundle{
 		fs:          fs,
 		res:         res,
@@ -854,6 +1129,135 @@ func ScanBundle(log logger.Log, fs fs.FS, res resolver.Resolver, entryPaths []st
 	}
 }
 
+// This does a breadth-first traversal of the import graph so that following
+// the "firstImporter" links always results in the shortest import chain
+func findFirstImporters(files []file, entryPoints []uint32) {
+	visited := make([]bool, len(files))
+	queue := make([]uint32, 0, len(files))
+	for _, sourceIndex := range entryPoints {
+		if !visited[sourceIndex] {
+			visited[sourceIndex] = true
+			queue = append(queue, sourceIndex)
+		}
+	}
+
+	for len(queue) > 0 {
+		sourceIndex := queue[0]
+		queue = queue[1:]
+		repr := files[sourceIndex].repr
+		if repr == nil {
+			continue
+		}
+
+		// The JavaScript stub for a CSS file is automatically generated, so the
+		// CSS file is considered to be imported by whatever imported the stub
+		if repr, ok := repr.(*reprJS); ok && repr.cssSourceIndex != nil {
+			if other := *repr.cssSourceIndex; !visited[other] {
+				visited[other] = true
+				files[other].firstImporter = files[sourceIndex].firstImporter
+				queue = append(queue, other)
+			}
+			continue
+		}
+
+		records := repr.importRecords()
+		for importRecordIndex := range records {
+			if record := &records[importRecordIndex]; record.SourceIndex != nil {
+				if other := *record.SourceIndex; !visited[other] {
+					visited[other] = true
+					files[other].firstImporter = &importerRef{sourceIndex: sourceIndex, importRecordIndex: uint32(importRecordIndex)}
+					queue = append(queue, other)
+				}
+			}
+		}
+	}
+}
+
+type ImportChainLink struct {
+	ImporterPrettyPath string
+	ImportPath         string
+	Kind               ast.ImportKind
+}
+
+type ImportChain struct {
+	PrettyPath string
+	Links      []ImportChainLink // The first link is in an entry point
+}
+
+// This explains why a file is part of the bundle. The query is either a path
+// relative to the current directory, an absolute path, or the name of a
+// package (optionally with a subpath). If more than one file matches, the
+// one with the shortest import chain is used.
+func (b *Bundle) FindImportChain(query string) (ImportChain, bool) {
+	absPath := query
+	if !b.fs.IsAbs(absPath) {
+		absPath = b.fs.Join(b.fs.Cwd(), absPath)
+	}
+	absPath = lowerCaseAbsPathForWindows(absPath)
+	if chain, ok := b.findShortestImportChain(func(f *file) bool {
+		return f.source.KeyPath.Namespace == "file" && lowerCaseAbsPathForWindows(f.source.KeyPath.Text) == absPath
+	}); ok {
+		return chain, true
+	}
+
+	// Relative paths like "src/util.js" also look like package paths, so only
+	// try matching packages if no file has this path
+	if resolver.IsPackagePath(query) {
+		needle := "/node_modules/" + strings.TrimSuffix(query, "/") + "/"
+		return b.findShortestImportChain(func(f *file) bool {
+			return f.source.KeyPath.Namespace == "file" &&
+				strings.Contains(strings.ReplaceAll(f.source.KeyPath.Text, "\\", "/")+"/", needle)
+		})
+	}
+
+	return ImportChain{}, false
+}
+
+func (b *Bundle) findShortestImportChain(matches func(f *file) bool) (ImportChain, bool) {
+	isEntryPoint := make(map[uint32]bool)
+	for _, sourceIndex := range b.entryPoints {
+		isEntryPoint[sourceIndex] = true
+	}
+
+	var best ImportChain
+	found := false
+	for sourceIndex := range b.files {
+		f := &b.files[sourceIndex]
+		if uint32(sourceIndex) == runtime.SourceIndex || f.repr == nil || !matches(f) {
+			continue
+		}
+
+		// Ignore files that aren't reachable from an entry point
+		if f.firstImporter == nil && !isEntryPoint[uint32(sourceIndex)] {
+			continue
+		}
+
+		chain := ImportChain{PrettyPath: f.source.PrettyPath}
+		for importer := f.firstImporter; importer != nil; importer = b.files[importer.sourceIndex].firstImporter {
+			other := &b.files[importer.sourceIndex]
+			record := &other.repr.importRecords()[importer.importRecordIndex]
+			chain.Links = append(chain.Links, ImportChainLink{
+				ImporterPrettyPath: other.source.PrettyPath,
+				ImportPath:         record.Path.Text,
+				Kind:               record.Kind,
+			})
+		}
+
+		// Prefer shorter chains, then sort by path for determinism
+		if !found || len(chain.Links) < len(best.Links) ||
+			(len(chain.Links) == len(best.Links) && chain.PrettyPath < best.PrettyPath) {
+			best = chain
+			found = true
+		}
+	}
+
+	// The links were collected backward from the file to the entry point
+	for i, j := 0, len(best.Links)-1; i < j; i, j = i+1, j-1 {
+		best.Links[i], best.Links[j] = best.Links[j], best.Links[i]
+	}
+	return best, found
+}
+
 func DefaultExtensionToLoaderMap() map[string]config.Loader {
 	return map[string]config.Loader{
 		".js":   config.LoaderJS,
@@ -896,15 +1300,18 @@ func (b *Bundle) Compile(log logger.Log, options config.Options) []OutputFile {
 	type linkGroup struct {
 		outputFiles    []OutputFile
 		reachableFiles []uint32
+		filesInOutput  []uint32
 	}
 
 	var resultGroups []linkGroup
 	if options.CodeSplitting {
 		// If code splitting is enabled, link all entry points together
 		c := newLinkerContext(&options, log, b.fs, b.res, b.files, b.entryPoints, lcaAbsPath)
+		outputFiles := c.link()
 		resultGroups = []linkGroup{{
-			outputFiles:    c.link(),
+			outputFiles:    outputFiles,
 			reachableFiles: c.reachableFiles,
+			filesInOutput:  c.filesWithLiveParts(),
 		}}
 	} else {
 		// Otherwise, link each entry point with the runtime file separately
@@ -914,9 +1321,11 @@ func (b *Bundle) Compile(log logger.Log, options config.Options) []OutputFile {
 			waitGroup.Add(1)
 			go func(i int, entryPoint uint32) {
 				c := newLinkerContext(&options, log, b.fs, b.res, b.files, []uint32{entryPoint}, lcaAbsPath)
+				outputFiles := c.link()
 				resultGroups[i] = linkGroup{
-					outputFiles:    c.link(),
+					outputFiles:    outputFiles,
 					reachableFiles: c.reachableFiles,
+					filesInOutput:  c.filesWithLiveParts(),
 				}
 				waitGroup.Done()
 			}(i, entryPoint)
@@ -926,9 +1335,12 @@ func (b *Bundle) Compile(log logger.Log, options config.Options) []OutputFile {
 
 	// Join the results in entry point order for determinism
 	var outputFiles []OutputFile
-	for _, group := range resultGroups {
+	filesInOutput := make([][]uint32, len(resultGroups))
+	for i, group := range resultGroups {
 		outputFiles = append(outputFiles, group.outputFiles...)
+		filesInOutput[i] = group.filesInOutput
 	}
+	reportDuplicatePackages(log, b.res, b.files, filesInOutput)
 
 	// Also generate the metadata file if necessary
 	if options.AbsMetadataFile != "" {
@@ -938,7 +1350,7 @@ func (b *Bundle) Compile(log logger.Log, options config.Options) []OutputFile {
 		})
 	}
 
-	if !options.WriteToStdout {
+	if !options.WriteToStdout && !options.ServeFromMemory {
 		// Make sure an output file never overwrites an input file
 		sourceAbsPaths := make(map[string]uint32)
 		for _, group := range resultGroups {
@@ -1069,7 +1481,7 @@ func (b *Bundle) generateMetadataJSON(results []OutputFile, asciiOnly bool) []by
 	// Sort files by key path for determinism
 	sorted := make(indexAndPathArray, 0, len(b.files))
 	for sourceIndex, file := range b.files {
-		if uint32(sourceIndex) != runtime.SourceIndex {
+		if uint32(sourceIndex) != runtime.SourceIndex && file.repr != nil {
 			sorted = append(sorted, indexAndPath{uint32(sourceIndex), file.source.KeyPath})
 		}
 	}
@@ -1078,20 +1490,25 @@ func (b *Bundle) generateMetadataJSON(results []OutputFile, asciiOnly bool) []by
 	j := js_printer.Joiner{}
 	j.AddString("{\n  \"inputs\": {")
 
-	// Write inputs
-	for i, item := range sorted {
-		if i > 0 {
-			j.AddString(",\n    ")
-		} else {
-			j.AddString("\n    ")
+	// Write inputs. Some files such as the JavaScript stubs for imported CSS
+	// files are automatically generated and don't have any metadata.
+	isFirst := true
+	for _, item := range sorted {
+		if chunk := b.files[item.sourceIndex].jsonMetadataChunk; len(chunk) > 0 {
+			if isFirst {
+				isFirst = false
+				j.AddString("\n    ")
+			} else {
+				j.AddString(",\n    ")
+			}
+			j.AddBytes(chunk)
 		}
-		j.AddBytes(b.files[item.sourceIndex].jsonMetadataChunk)
 	}
 
 	j.AddString("\n  },\n  \"outputs\": {")
 
 	// Write outputs
-	isFirst := true
+	isFirst = true
 	for _, result := range results {
 		if len(result.jsonMetadataChunk) > 0 {
 			if isFirst {
@@ -0,0 +1,139 @@
+package bundler
+
+import (
+	"testing"
+
+	"github.com/evanw/esbuild/internal/config"
+)
+
+var cycles_suite = suite{
+	name: "cycles",
+}
+
+func TestCycleReadsBindingAtTopLevel(t *testing.T) {
+	cycles_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {a} from './a'
+				console.log(a)
+			`,
+			"/a.js": `
+				import {b} from './b'
+				export let a = 'a'
+				export function getA() { return a }
+			`,
+			"/b.js": `
+				import {getA} from './a'
+				export let b = getA()
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			DetectCycles:  true,
+		},
+		expectedScanLog: `/b.js: warning: Import cycle may read a binding before it has been initialized: "/b.js" -> "/a.js" -> "/b.js"
+/a.js: note: "/a.js" imports "/b.js" here
+/b.js: note: "getA" is read here during module evaluation, which will fail if "/a.js" hasn't been evaluated yet
+`,
+	})
+}
+
+func TestCycleHarmless(t *testing.T) {
+	cycles_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {a} from './a'
+				console.log(a())
+			`,
+			"/a.js": `
+				import {b} from './b'
+				export function a() { return b() }
+			`,
+			"/b.js": `
+				import * as ns from './c'
+				export const b = () => ns.c()
+			`,
+			"/c.js": `
+				import {a} from './a'
+				export class c { foo() { return a } }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			DetectCycles:  true,
+		},
+		expectedScanLog: `/a.js: warning: Import cycle: "/a.js" -> "/b.js" -> "/c.js" -> "/a.js"
+/b.js: note: "/b.js" imports "/c.js" here
+/c.js: note: "/c.js" imports "/a.js" here
+note: This cycle is likely harmless because no imported binding is read during module evaluation
+`,
+	})
+}
+
+func TestCycleNamespaceAndExtends(t *testing.T) {
+	cycles_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import './a'
+				import './c'
+			`,
+			"/a.js": `
+				import * as b from './b'
+				export class A extends b.B {}
+			`,
+			"/b.js": `
+				import './a'
+				export class B {}
+			`,
+			"/c.js": `
+				import {d} from './d'
+				export let c = 1
+			`,
+			"/d.js": `
+				import './c'
+				export let d = 2
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			DetectCycles:  true,
+		},
+		expectedScanLog: `/a.js: warning: Import cycle may read a binding before it has been initialized: "/a.js" -> "/b.js" -> "/a.js"
+/b.js: note: "/b.js" imports "/a.js" here
+/a.js: note: "b" is read here during module evaluation, which will fail if "/b.js" hasn't been evaluated yet
+/c.js: warning: Import cycle: "/c.js" -> "/d.js" -> "/c.js"
+/d.js: note: "/d.js" imports "/c.js" here
+note: This cycle is likely harmless because no imported binding is read during module evaluation
+`,
+	})
+}
+
+func TestCycleIgnoresRequireAndDynamicImport(t *testing.T) {
+	cycles_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {a} from './a'
+				console.log(a)
+			`,
+			"/a.js": `
+				export let a = require('./b')
+			`,
+			"/b.js": `
+				import('./a')
+				module.exports = 1
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			DetectCycles:  true,
+		},
+	})
+}
@@ -235,6 +235,222 @@ func TestExportFormsIIFE(t *testing.T) {
 	})
 }
 
+func TestExportFormsUMD(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export default 123
+				export var v = 234
+				export let l = 234
+				export const c = 234
+				export {Class as C}
+				export function Fn() {}
+				export class Class {}
+				export * from './a'
+				export * as b from './b'
+			`,
+			"/a.js": "export const abc = undefined",
+			"/b.js": "export const xyz = null",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			ModuleName:    []string{"moduleName"},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestUMDExternalsWithGlobals(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import React from 'react'
+				import { render } from 'react-dom'
+				import * as utils from '@company/utils'
+				render(React.createElement('div'), document.body)
+				export const version = utils.version
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			ModuleName:    []string{"company", "widgets", "my-widget"},
+			AbsOutputFile: "/out.js",
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"react":          true,
+					"react-dom":      true,
+					"@company/utils": true,
+				},
+			},
+			UMDGlobals: map[string][]string{
+				"react":          {"React"},
+				"react-dom":      {"ReactDOM"},
+				"@company/utils": {"company", "utils"},
+			},
+		},
+	})
+}
+
+func TestUMDMissingGlobalMinify(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import fs from 'fs'
+				import path from 'path'
+				console.log(fs, path)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			OutputFormat:     config.FormatUMD,
+			RemoveWhitespace: true,
+			AbsOutputFile:    "/out.js",
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"fs":   true,
+					"path": true,
+				},
+			},
+			UMDGlobals: map[string][]string{
+				"path": {"path"},
+			},
+		},
+		expectedCompileLog: `/entry.js: warning: No global variable name was configured for the external import "fs", so it will be undefined when there is no module system
+`,
+	})
+}
+
+func TestExportFormsSystem(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export default 123
+				export var v = 234
+				export let l = 234
+				export const c = 234
+				export {Class as C}
+				export function Fn() {}
+				export class Class {}
+				export * from './a'
+				export * as b from './b'
+			`,
+			"/a.js": "export const abc = undefined",
+			"/b.js": "export const xyz = null",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemExportLiveBindings(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export let x = 1
+				export {x as y}
+				x = 2
+				x += 3
+				x++
+				console.log(--x, x--, new (x = Foo)())
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemExportFunctionsBeforeExecute(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {g} from 'cycle'
+				export function f() { return [g(), count, i, Foo] }
+				export let count = 0, {a, b: [c]} = g()
+				export class Foo {}
+				for (var i = 0; i < 2; i++) if (i) { var j = i }
+				for (var k in g()) try { var m = k } catch { var n }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"cycle": true,
+				},
+			},
+		},
+	})
+}
+
+func TestSystemExternals(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import React, { render } from 'react'
+				import * as utils from 'utils'
+				import 'polyfill'
+				export * from 'reexported'
+				render(React, utils, import.meta.url)
+				import('lazy').then(m => m)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"react":      true,
+					"utils":      true,
+					"polyfill":   true,
+					"reexported": true,
+					"lazy":       true,
+				},
+			},
+		},
+	})
+}
+
+func TestSystemCommonJSEntryPointMinify(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import m from 'external'
+				module.exports = m
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			OutputFormat:     config.FormatSystem,
+			RemoveWhitespace: true,
+			AbsOutputFile:    "/out.js",
+			ExternalModules: config.ExternalModules{
+				NodeModules: map[string]bool{
+					"external": true,
+				},
+			},
+		},
+	})
+}
+
 func TestExportFormsWithMinifyIdentifiersAndNoBundle(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -1974,6 +2190,76 @@ func TestExternalModuleExclusionRelativePath(t *testing.T) {
 	})
 }
 
+func TestExternalWildcardPatterns(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/index.js": `
+				// These match the import path as written
+				import '@company/ui'
+				import '@company/utils/format'
+				import '@other/ui'
+
+				// These match the resolved path
+				import logo from './images/logo.png'
+				import icon from '../assets/icon.png'
+				import './styles/theme.css'
+				console.log(logo, icon)
+			`,
+			"/Users/user/project/src/images/logo.png":             `x`,
+			"/Users/user/project/assets/icon.png":                 `x`,
+			"/Users/user/project/src/styles/theme.css":            `a { color: red }`,
+			"/Users/user/project/node_modules/@other/ui/index.js": `export default 'other'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/index.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/Users/user/project/out",
+			ExternalModules: config.ExternalModules{
+				Patterns: []config.WildcardPattern{
+					{Prefix: "@company/"},
+					{Suffix: ".png"},
+					{Prefix: "/Users/user/project/src/styles/", Suffix: ".css"},
+				},
+			},
+		},
+	})
+}
+
+func TestExternalPackages(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/index.js": `
+				import 'pkg1'
+				import 'pkg2/foo'
+				import '@scope/pkg3'
+				import '#internal'
+				import './relative'
+				import '/Users/user/project/src/absolute.js'
+				import '@app/paths'
+				import 'browser-mapped'
+				require('fs')
+			`,
+			"/Users/user/project/src/relative.js": `console.log('relative')`,
+			"/Users/user/project/src/absolute.js": `console.log('absolute')`,
+			"/Users/user/project/src/internal.js": `console.log('internal')`,
+			"/Users/user/project/src/paths.js":    `console.log('paths')`,
+			"/Users/user/project/src/browser.js":  `console.log('browser')`,
+			"/Users/user/project/tsconfig.json":   `{ "compilerOptions": { "baseUrl": ".", "paths": { "@app/*": ["./src/*"] } } }`,
+			"/Users/user/project/package.json": `{
+				"imports": { "#internal": "./src/internal.js" },
+				"browser": { "browser-mapped": "./src/browser.js" }
+			}`,
+			"/Users/user/project/node_modules/pkg1/index.js": `console.log('pkg1')`,
+		},
+		entryPaths: []string{"/Users/user/project/src/index.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			AbsOutputFile:    "/Users/user/project/out.js",
+			ExternalPackages: true,
+		},
+	})
+}
+
 func TestAutoExternal(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -3266,3 +3552,122 @@ func TestProcessEnvNodeEnvWarningNoBundle(t *testing.T) {
 		},
 	})
 }
+
+func TestPackageAlias(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import "react"
+				import "react/jsx-runtime"
+				import "react-dom"
+				import "@scope/pkg/sub"
+				import "@scope/pkg-other"
+				import "local"
+			`,
+			"/node_modules/preact/compat/index.js":       `console.log('preact/compat')`,
+			"/node_modules/preact/compat/jsx-runtime.js": `console.log('preact/compat/jsx-runtime')`,
+			"/node_modules/react-dom/index.js":           `console.log('react-dom')`,
+			"/node_modules/@scope/replacement/sub.js":    `console.log('@scope/replacement/sub')`,
+			"/node_modules/@scope/pkg-other/index.js":    `console.log('@scope/pkg-other')`,
+			"/shims/local.js":                            `console.log('/shims/local')`,
+			"/node_modules/react/index.js":               `console.log('SHOULD NOT BE INCLUDED')`,
+			"/node_modules/react/jsx-runtime.js":         `console.log('SHOULD NOT BE INCLUDED')`,
+			"/node_modules/@scope/pkg/sub.js":            `console.log('SHOULD NOT BE INCLUDED')`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			PackageAliases: map[string]string{
+				"react":      "preact/compat",
+				"@scope/pkg": "@scope/replacement",
+				"local":      "/shims/local.js",
+			},
+		},
+	})
+}
+
+func TestImportMap(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/site/src/entry.js": `
+				import 'react'
+				import 'lodash/get'
+				import 'utils'
+				import './local.js'
+				import 'https://cdn.example.com/remote.js'
+				import 'scoped'
+			`,
+			"/site/src/local.js": `console.log('SHOULD NOT BE INCLUDED')`,
+			"/site/src/legacy.js": `
+				import 'scoped'
+				console.log('legacy')
+			`,
+			"/site/src/scoped-legacy.js": `console.log('scoped (legacy)')`,
+			"/site/vendor/lib.js": `
+				import 'scoped'
+				import 'utils'
+			`,
+			"/site/vendor/scoped-vendor.js": `console.log('scoped (vendor)')`,
+			"/site/scoped-top.js":           `console.log('scoped (top level)')`,
+			"/site/lib/utils/index.js":      `console.log('utils')`,
+			"/site/shims/remote.js":         `import '/site/vendor/lib.js'`,
+			"/site/importmap.json": `
+				{
+					"imports": {
+						"react": "https://esm.sh/react@17",
+						"lodash/": "https://esm.sh/lodash-es/",
+						"utils": "./lib/utils/index.js",
+						"./src/local.js": "/src/legacy.js",
+						"https://cdn.example.com/remote.js": "./shims/remote.js",
+						"scoped": "/scoped-top.js"
+					},
+					"scopes": {
+						"/vendor/": {
+							"scoped": "./vendor/scoped-vendor.js"
+						},
+						"/src/legacy.js": {
+							"scoped": "./src/scoped-legacy.js"
+						}
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/site/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			ImportMapPath: "/site/importmap.json",
+		},
+	})
+}
+
+func TestImportMapErrors(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/site/entry.js": `
+				import 'blocked'
+			`,
+			"/site/importmap.json": `
+				{
+					"imports": {
+						"blocked": null,
+						"bare": "not-a-url",
+						"prefix/": "./no-slash"
+					}
+				}
+			`,
+		},
+		entryPaths: []string{"/site/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			ImportMapPath: "/site/importmap.json",
+		},
+		expectedScanLog: `/site/entry.js: error: Could not resolve "blocked" (mark it as external to exclude it from the bundle)
+/site/importmap.json: note: The import map maps "blocked" to null
+/site/importmap.json: warning: Ignoring the address for "bare" because it must be a URL or a path starting with "/", "./", or "../"
+/site/importmap.json: warning: Ignoring the address for "prefix/" because it must end in "/" like the key does
+`,
+	})
+}
@@ -0,0 +1,85 @@
+package bundler
+
+import (
+	"testing"
+
+	"github.com/evanw/esbuild/internal/config"
+)
+
+var hmr_suite = suite{
+	name: "hmr",
+}
+
+func TestHMRWrapsESMAndCommonJS(t *testing.T) {
+	hmr_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {foo} from './foo'
+				import './style.css'
+				console.log(foo, require('./bar'))
+				if (import.meta.hot) import.meta.hot.accept()
+			`,
+			"/foo.js": `
+				export let foo = 123
+				import.meta.hot.dispose(data => { data.foo = foo })
+			`,
+			"/bar.js": `
+				module.exports = 'bar'
+			`,
+			"/style.css": `
+				a { color: red }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			OutputFormat:         config.FormatESModule,
+			AbsOutputDir:         "/out",
+			HotModuleReplacement: true,
+		},
+	})
+}
+
+func TestHMRIIFE(t *testing.T) {
+	hmr_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {foo} from './foo'
+				console.log(foo)
+			`,
+			"/foo.js": `
+				export function foo() {}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			OutputFormat:         config.FormatIIFE,
+			AbsOutputFile:        "/out.js",
+			HotModuleReplacement: true,
+		},
+	})
+}
+
+func TestHMREntryPointExports(t *testing.T) {
+	hmr_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {foo} from './foo'
+				export let bar = foo + 1
+				export default function() {}
+				export {foo}
+			`,
+			"/foo.js": `
+				export let foo = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			OutputFormat:         config.FormatESModule,
+			AbsOutputDir:         "/out",
+			HotModuleReplacement: true,
+		},
+	})
+}
@@ -0,0 +1,104 @@
+package bundler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/cache"
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/resolver"
+)
+
+type incrementalBuilder struct {
+	caches   *cache.CacheSet
+	dirCache *resolver.DirCache
+	options  config.Options
+}
+
+func (b *incrementalBuilder) build(t *testing.T, files map[string]string) string {
+	t.Helper()
+	fs := fs.MockFS(files)
+	log := logger.NewDeferLog()
+	resolver := resolver.NewResolver(fs, log, b.dirCache, b.options)
+	bundle := ScanBundle(log, fs, resolver, b.caches, []string{"/entry.js"}, b.options)
+	options := b.options
+	options.OmitRuntimeForTests = true
+	results := bundle.Compile(log, options)
+	assertLog(t, log.Done(), "")
+
+	generated := ""
+	for _, result := range results {
+		generated += fmt.Sprintf("---------- %s ----------\n%s", result.AbsPath, string(result.Contents))
+	}
+	return generated
+}
+
+func newIncrementalBuilder(options config.Options) *incrementalBuilder {
+	options.ExtensionOrder = []string{".tsx", ".ts", ".jsx", ".js", ".json"}
+	return &incrementalBuilder{
+		caches:   cache.MakeCacheSet(),
+		dirCache: resolver.NewDirCache(),
+		options:  options,
+	}
+}
+
+func TestIncrementalRebuild(t *testing.T) {
+	options := config.Options{
+		Mode:            config.ModeBundle,
+		AbsOutputFile:   "/out.js",
+		AbsOutputDir:    "/",
+		AbsMetadataFile: "/meta.json",
+	}
+	incremental := newIncrementalBuilder(options)
+	files := map[string]string{
+		"/entry.js": `
+			import {a} from './a'
+			import {b} from './b'
+			console.log(a, b)
+		`,
+		"/a.js":          `export let a = 'a'`,
+		"/b.js":          `export let b = 'b'`,
+		"/tsconfig.json": `{"compilerOptions": {"jsxFactory": "h"}}`,
+	}
+
+	// Each incremental build must match a build from scratch and must only
+	// parse the files that changed since the previous build
+	check := func(expectedParses uint32) {
+		t.Helper()
+		expected := newIncrementalBuilder(options).build(t, files)
+		before := incremental.caches.JSCache.ParseCount()
+		assertEqual(t, incremental.build(t, files), expected)
+		assertEqual(t, incremental.caches.JSCache.ParseCount()-before, expectedParses)
+	}
+
+	// Build twice without changing anything
+	check(3)
+	check(0)
+
+	// Change one file
+	files["/b.js"] = `export let b = 'changed'`
+	check(1)
+
+	// Stop importing a file
+	files["/entry.js"] = `
+		import {a} from './a'
+		console.log(a)
+	`
+	check(1)
+
+	// Start importing a file again and add a new file
+	files["/entry.js"] = `
+		import {b} from './b'
+		import './c.jsx'
+		console.log(b)
+	`
+	files["/c.jsx"] = `console.log(<div/>)`
+	check(2)
+
+	// Change a file that only affects the resolver. This changes the parse
+	// options for every file in the directory, so they are all parsed again.
+	files["/tsconfig.json"] = `{"compilerOptions": {"jsxFactory": "React.createElement"}}`
+	check(3)
+}
@@ -891,3 +891,295 @@ func TestPackageJsonMainFieldsB(t *testing.T) {
 		},
 	})
 }
+
+func TestPackageJsonDuplicatePackage(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from 'demo-pkg'
+				import b from 'other-pkg'
+				import c from 'tree-shaken-pkg'
+				console.log(a, b)
+			`,
+			"/Users/user/project/node_modules/demo-pkg/package.json": `
+				{ "name": "demo-pkg", "version": "2.0.0" }
+			`,
+			"/Users/user/project/node_modules/demo-pkg/index.js": `
+				export default 'demo-pkg v2'
+			`,
+			"/Users/user/project/node_modules/other-pkg/package.json": `
+				{ "name": "other-pkg", "version": "1.0.0" }
+			`,
+			"/Users/user/project/node_modules/other-pkg/index.js": `
+				import a from 'demo-pkg'
+				import b from 'demo-pkg/b'
+				export default a + b
+			`,
+			"/Users/user/project/node_modules/other-pkg/node_modules/demo-pkg/package.json": `
+				{ "name": "demo-pkg", "version": "1.0.0" }
+			`,
+			"/Users/user/project/node_modules/other-pkg/node_modules/demo-pkg/index.js": `
+				export default 'demo-pkg v1'
+			`,
+			"/Users/user/project/node_modules/other-pkg/node_modules/demo-pkg/b.js": `
+				export default 'b'
+			`,
+			"/Users/user/project/node_modules/tree-shaken-pkg/package.json": `
+				{ "name": "tree-shaken-pkg", "sideEffects": false }
+			`,
+			"/Users/user/project/node_modules/tree-shaken-pkg/index.js": `
+				export {default} from 'demo-pkg/extra'
+			`,
+			"/Users/user/project/node_modules/tree-shaken-pkg/node_modules/demo-pkg/package.json": `
+				{ "name": "demo-pkg", "version": "3.0.0", "sideEffects": false }
+			`,
+			"/Users/user/project/node_modules/tree-shaken-pkg/node_modules/demo-pkg/extra.js": `
+				export default 'unused'
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+		expectedCompileLog: `warning: The package "demo-pkg" is included in the bundle 2 times
+/Users/user/project/src/entry.js: note: The copy in "/Users/user/project/node_modules/demo-pkg" (version 2.0.0, 37b) is imported here
+/Users/user/project/node_modules/other-pkg/index.js: note: The copy in "/Users/user/project/node_modules/other-pkg/node_modules/demo-pkg" (version 1.0.0, 64b) is imported here
+/Users/user/project/node_modules/other-pkg/index.js: note: The copy in "/Users/user/project/node_modules/other-pkg/node_modules/demo-pkg" is also imported here
+`,
+	})
+}
+
+func TestPackageJsonDuplicatePackageSeparateEntryPoints(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/a.js": `
+				import a from 'demo-pkg'
+				console.log(a)
+			`,
+			"/Users/user/project/src/b.js": `
+				import b from 'other-pkg'
+				console.log(b)
+			`,
+			"/Users/user/project/src/c.js": `
+				import a from 'demo-pkg'
+				import b from 'other-pkg'
+				console.log(a, b)
+			`,
+			"/Users/user/project/src/d.js": `
+				import a from 'demo-pkg'
+				import b from 'other-pkg'
+				console.log(a, b)
+			`,
+			"/Users/user/project/node_modules/demo-pkg/package.json": `
+				{ "name": "demo-pkg", "version": "2.0.0" }
+			`,
+			"/Users/user/project/node_modules/demo-pkg/index.js": `
+				export default 'demo-pkg v2'
+			`,
+			"/Users/user/project/node_modules/other-pkg/package.json": `
+				{ "name": "other-pkg", "version": "1.0.0" }
+			`,
+			"/Users/user/project/node_modules/other-pkg/index.js": `
+				export {default} from 'demo-pkg'
+			`,
+			"/Users/user/project/node_modules/other-pkg/node_modules/demo-pkg/package.json": `
+				{ "name": "demo-pkg", "version": "1.0.0" }
+			`,
+			"/Users/user/project/node_modules/other-pkg/node_modules/demo-pkg/index.js": `
+				export default 'demo-pkg v1'
+			`,
+		},
+		entryPaths: []string{
+			"/Users/user/project/src/a.js",
+			"/Users/user/project/src/b.js",
+			"/Users/user/project/src/c.js",
+			"/Users/user/project/src/d.js",
+		},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/Users/user/project/out",
+		},
+		expectedCompileLog: `warning: The package "demo-pkg" is included in the bundle 2 times
+/Users/user/project/src/c.js: note: The copy in "/Users/user/project/node_modules/demo-pkg" (version 2.0.0, 37b) is imported here
+/Users/user/project/node_modules/other-pkg/index.js: note: The copy in "/Users/user/project/node_modules/other-pkg/node_modules/demo-pkg" (version 1.0.0, 37b) is imported here
+`,
+	})
+}
+
+func TestPackageJsonExportsConditions(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from 'pkg'
+				const b = require('pkg')
+				import c from 'pkg/feature'
+				console.log(a, b, c)
+			`,
+			"/Users/user/project/node_modules/pkg/package.json": `
+				{
+					"main": "./main.js",
+					"exports": {
+						".": {
+							"node": "./node.js",
+							"import": "./import.js",
+							"require": "./require.js",
+							"default": "./default.js"
+						},
+						"./feature": [{ "worker": "./worker.js" }, "./feature.js"]
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/pkg/main.js":    `module.exports = 'main'`,
+			"/Users/user/project/node_modules/pkg/node.js":    `module.exports = 'node'`,
+			"/Users/user/project/node_modules/pkg/import.js":  `export default 'import'`,
+			"/Users/user/project/node_modules/pkg/require.js": `module.exports = 'require'`,
+			"/Users/user/project/node_modules/pkg/feature.js": `export default 'feature'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+	})
+}
+
+func TestPackageJsonExportsPatterns(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from '@scope/pkg/features/a'
+				import b from '@scope/pkg/features/nested/b'
+				import c from '@scope/pkg/features/special/c'
+				console.log(a, b, c)
+			`,
+			"/Users/user/project/node_modules/@scope/pkg/package.json": `
+				{
+					"exports": {
+						"./features/*": "./src/features/*.js",
+						"./features/special/*": "./src/special/*.js"
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/@scope/pkg/src/features/a.js":        `export default 'a'`,
+			"/Users/user/project/node_modules/@scope/pkg/src/features/nested/b.js": `export default 'b'`,
+			"/Users/user/project/node_modules/@scope/pkg/src/special/c.js":         `export default 'c'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+	})
+}
+
+func TestPackageJsonExportsErrors(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import 'pkg/internal/secret'
+				import 'pkg/missing'
+				import 'pkg/main.js'
+				import 'pkg/lib/file'
+			`,
+			"/Users/user/project/node_modules/pkg/package.json": `
+				{
+					"exports": {
+						".": "./main.js",
+						"./internal/*": null,
+						"./lib/*": "./lib/*"
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/pkg/main.js":            `export default 'main'`,
+			"/Users/user/project/node_modules/pkg/internal/secret.js": `export default 'secret'`,
+			"/Users/user/project/node_modules/pkg/lib/file.js":        `export default 'file'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+		expectedScanLog: `/Users/user/project/src/entry.js: error: Could not resolve "pkg/internal/secret" (mark it as external to exclude it from the bundle)
+/Users/user/project/node_modules/pkg/package.json: note: The path "./internal/secret" cannot be imported from package "pkg" because it was disabled by the package author here
+/Users/user/project/src/entry.js: error: Could not resolve "pkg/missing" (mark it as external to exclude it from the bundle)
+/Users/user/project/node_modules/pkg/package.json: note: The path "./missing" is not exported by package "pkg"
+/Users/user/project/src/entry.js: error: Could not resolve "pkg/main.js" (mark it as external to exclude it from the bundle)
+/Users/user/project/node_modules/pkg/package.json: note: The path "./main.js" is not exported by package "pkg"
+/Users/user/project/src/entry.js: error: Could not resolve "pkg/lib/file" (mark it as external to exclude it from the bundle)
+/Users/user/project/node_modules/pkg/package.json: note: The "exports" field resolved "./lib/file" to "./lib/file", which is not a file
+`,
+	})
+}
+
+func TestPackageJsonImports(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from '#internal/a'
+				import b from '#dep'
+				import '#missing'
+			`,
+			"/Users/user/project/package.json": `
+				{
+					"imports": {
+						"#internal/*": "./src/internal/*.js",
+						"#dep": { "browser": "dep/browser", "default": "dep" }
+					}
+				}
+			`,
+			"/Users/user/project/src/internal/a.js":           `export default 'a'`,
+			"/Users/user/project/node_modules/dep/index.js":   `export default 'dep'`,
+			"/Users/user/project/node_modules/dep/browser.js": `export default 'dep (browser)'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+		expectedScanLog: `/Users/user/project/src/entry.js: error: Could not resolve "#missing" (mark it as external to exclude it from the bundle)
+/Users/user/project/package.json: note: The package import "#missing" is not defined in this "imports" map
+`,
+	})
+}
+
+func TestPackageJsonExportsCustomConditions(t *testing.T) {
+	packagejson_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import a from 'pkg'
+				const b = require('pkg')
+				import c from 'pkg/util'
+				console.log(a, b, c)
+			`,
+			"/Users/user/project/node_modules/pkg/package.json": `
+				{
+					"exports": {
+						".": {
+							"development": "./dev.js",
+							"production": {
+								"import": "./prod.mjs",
+								"require": "./prod.cjs"
+							},
+							"default": "./default.js"
+						},
+						"./util": {
+							"source": "./src/util.js",
+							"default": "./dist/util.js"
+						}
+					}
+				}
+			`,
+			"/Users/user/project/node_modules/pkg/prod.mjs":     `export default 'prod (import)'`,
+			"/Users/user/project/node_modules/pkg/prod.cjs":     `module.exports = 'prod (require)'`,
+			"/Users/user/project/node_modules/pkg/src/util.js":  `export default 'util (source)'`,
+			"/Users/user/project/node_modules/pkg/dist/util.js": `export default 'util (dist)'`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+			Conditions:    []string{"source", "production"},
+		},
+	})
+}
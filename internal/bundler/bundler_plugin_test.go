@@ -0,0 +1,204 @@
+package bundler
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+var plugin_suite = suite{
+	name: "plugin",
+}
+
+func TestPluginVirtualModule(t *testing.T) {
+	contents := "export let value = 123"
+	plugin_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {value} from 'virtual:config'
+				console.log(value)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Plugins: []config.Plugin{{
+				Name: "virtual",
+				OnResolve: []config.OnResolve{{
+					Filter: regexp.MustCompile(`^virtual:`),
+					Callback: func(args config.OnResolveArgs) config.OnResolveResult {
+						return config.OnResolveResult{Path: logger.Path{Text: args.Path[len("virtual:"):], Namespace: "virtual"}}
+					},
+				}},
+				OnLoad: []config.OnLoad{{
+					Filter:    regexp.MustCompile(`.*`),
+					Namespace: "virtual",
+					Callback: func(args config.OnLoadArgs) config.OnLoadResult {
+						return config.OnLoadResult{Contents: &contents}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestPluginVirtualModuleResolveDir(t *testing.T) {
+	contents := "export {value} from './util'"
+	plugin_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {value} from 'env'
+				console.log(value)
+			`,
+			"/lib/util.js": `
+				export let value = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Plugins: []config.Plugin{{
+				Name: "env",
+				OnResolve: []config.OnResolve{{
+					Filter: regexp.MustCompile(`^env$`),
+					Callback: func(args config.OnResolveArgs) config.OnResolveResult {
+						return config.OnResolveResult{Path: logger.Path{Text: "env", Namespace: "env-ns"}}
+					},
+				}},
+				OnLoad: []config.OnLoad{{
+					Filter:    regexp.MustCompile(`.*`),
+					Namespace: "env-ns",
+					Callback: func(args config.OnLoadArgs) config.OnLoadResult {
+						return config.OnLoadResult{Contents: &contents, AbsResolveDir: "/lib"}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestPluginExternal(t *testing.T) {
+	plugin_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import fn from 'remote/fn'
+				import './local'
+				fn()
+			`,
+			"/local.js": `
+				console.log('local')
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			OutputFormat:  config.FormatESModule,
+			Plugins: []config.Plugin{{
+				Name: "external",
+				OnResolve: []config.OnResolve{{
+					Filter: regexp.MustCompile(`^remote/`),
+					Callback: func(args config.OnResolveArgs) config.OnResolveResult {
+						return config.OnResolveResult{External: true}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestPluginLoadFileNamespace(t *testing.T) {
+	contents := "export default 'replaced'"
+	plugin_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import text from './data.custom'
+				console.log(text)
+			`,
+			"/data.custom": `this is not javascript`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Plugins: []config.Plugin{{
+				Name: "custom",
+				OnLoad: []config.OnLoad{{
+					Filter:    regexp.MustCompile(`\.custom$`),
+					Namespace: "file",
+					Callback: func(args config.OnLoadArgs) config.OnLoadResult {
+						return config.OnLoadResult{Contents: &contents, Loader: config.LoaderJS}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestPluginErrors(t *testing.T) {
+	plugin_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import 'thrown'
+				import 'reported'
+				import 'relative'
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Plugins: []config.Plugin{{
+				Name: "errors",
+				OnResolve: []config.OnResolve{{
+					Filter: regexp.MustCompile(`.*`),
+					Callback: func(args config.OnResolveArgs) config.OnResolveResult {
+						switch args.Path {
+						case "thrown":
+							return config.OnResolveResult{ThrownError: errors.New("Thrown error")}
+						case "reported":
+							return config.OnResolveResult{Msgs: []logger.Msg{{Kind: logger.Error, Text: "Reported error"}}}
+						default:
+							return config.OnResolveResult{Path: logger.Path{Text: "relative.js", Namespace: "file"}}
+						}
+					},
+				}},
+			}},
+		},
+		expectedScanLog: `/entry.js: error: [errors] Thrown error
+/entry.js: error: [errors] Reported error
+/entry.js: error: Plugin "errors" returned a non-absolute path: relative.js (set a namespace if this is not a file path)
+`,
+	})
+}
+
+func TestPluginNoLoaderForNamespace(t *testing.T) {
+	plugin_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import 'foo'
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Plugins: []config.Plugin{{
+				Name: "ns",
+				OnResolve: []config.OnResolve{{
+					Filter: regexp.MustCompile(`^foo$`),
+					Callback: func(args config.OnResolveArgs) config.OnResolveResult {
+						return config.OnResolveResult{Path: logger.Path{Text: "foo", Namespace: "ns"}}
+					},
+				}},
+			}},
+		},
+		expectedScanLog: `/entry.js: error: File could not be loaded: ns:foo
+`,
+	})
+}
@@ -484,3 +484,526 @@ func TestSplittingMinifyIdentifiersCrashIssue437(t *testing.T) {
 		},
 	})
 }
+
+func TestSplittingSharedES6IntoSystem(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {foo} from "./shared.js"
+				console.log(foo)
+			`,
+			"/b.js": `
+				import {foo, bar as baz} from "./shared.js"
+				export default foo + baz
+			`,
+			"/shared.js": `export let foo = 123; export let bar = 234`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingSharedES6IntoSystemLiveBindings(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				export * from "./shared.js"
+			`,
+			"/b.js": `
+				import {count, inc, reset} from "./shared.js"
+				inc()
+				reset()
+				console.log(count)
+			`,
+			"/shared.js": `
+				export let count
+				export let [first, ...rest] = []
+				export function inc() { count++; return count++ }
+				export function reset() { count = 0; [first, ...rest] = [count, count] }
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingDynamicES6IntoSystem(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import("./foo.js").then(({bar}) => console.log(bar))
+			`,
+			"/foo.js": `
+				export let bar = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingSharedES6IntoCommonJS(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {foo, setFoo} from "./shared.js"
+				setFoo(123)
+				console.log(foo)
+			`,
+			"/b.js": `
+				import {foo} from "./shared.js"
+				import "./side-effect.js"
+				export default {foo}
+			`,
+			"/shared.js": `
+				export let foo
+				export function setFoo(value) {
+					foo = value
+				}
+			`,
+			"/side-effect.js": `console.log('side effect')`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatCommonJS,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingDynamicES6IntoCommonJS(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import("./foo.js").then(({bar}) => console.log(bar))
+			`,
+			"/foo.js": `
+				export let bar = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatCommonJS,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingSharedES6IntoIIFE(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {foo, setFoo} from "./shared.js"
+				setFoo(123)
+				console.log(foo)
+			`,
+			"/b.js": `
+				import {foo} from "./shared.js"
+				console.log(foo)
+			`,
+			"/shared.js": `
+				export let foo
+				export function setFoo(value) {
+					foo = value
+				}
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatIIFE,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingDynamicES6IntoIIFEMinify(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import("./foo.js").then(({bar}) => console.log(bar))
+			`,
+			"/foo.js": `
+				export let bar = 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			CodeSplitting:     true,
+			RemoveWhitespace:  true,
+			MinifyIdentifiers: true,
+			OutputFormat:      config.FormatIIFE,
+			AbsOutputDir:      "/out",
+		},
+	})
+}
+
+func TestSplittingManualChunkPackages(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/a.js": `
+				import {inc, count} from "lib1"
+				import lib2 from "lib2"
+				inc()
+				console.log(count, lib2)
+			`,
+			"/src/b.js": `
+				import {helper} from "./util.js"
+				import("./lazy.js")
+				console.log(helper())
+			`,
+			"/src/lazy.js": `
+				import {inc} from "lib1"
+				export default inc
+			`,
+			"/src/util.js": `
+				export function helper() {}
+			`,
+			"/node_modules/lib1/package.json": `{ "name": "lib1" }`,
+			"/node_modules/lib1/index.js": `
+				import {helper} from "../../src/util.js"
+				export let count = 0
+				export function inc() { count++; helper() }
+			`,
+			"/node_modules/lib2/package.json": `{ "name": "lib2" }`,
+			"/node_modules/lib2/index.js": `
+				module.exports = 'lib2'
+			`,
+		},
+		entryPaths: []string{"/src/a.js", "/src/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{Name: "vendor", PackageNames: []string{"lib*"}},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunkPaths(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/a.js": `
+				import {foo} from "./vendor/foo.js"
+				import {bar} from "./vendor/nested/bar.js"
+				console.log(foo, bar)
+			`,
+			"/src/b.js": `
+				import {foo} from "./vendor/foo.js"
+				import {baz} from "./icons/baz.svg.js"
+				console.log(foo, baz)
+			`,
+			"/src/vendor/foo.js":        `export let foo = 1`,
+			"/src/vendor/nested/bar.js": `export let bar = 2`,
+			"/src/icons/baz.svg.js":     `export let baz = 3`,
+		},
+		entryPaths: []string{"/src/a.js", "/src/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatCommonJS,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{Name: "icons", AbsPaths: []string{"/src/*.svg.js"}},
+				{Name: "vendor", AbsPaths: []string{"/src/vendor"}},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunkCycle(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {a} from "./a.js"
+				console.log(a)
+			`,
+			"/a.js": `
+				import {b} from "./b.js"
+				export let a = () => b
+			`,
+			"/b.js": `
+				import {a} from "./a.js"
+				export let b = () => a
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{Name: "a", AbsPaths: []string{"/a.js"}},
+				{Name: "b", AbsPaths: []string{"/b.js"}},
+			},
+		},
+	})
+}
+
+func TestSplittingManualChunkCycleIIFE(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import {a} from "./a.js"
+				console.log(a)
+			`,
+			"/a.js": `
+				import {b} from "./b.js"
+				export let a = () => b
+			`,
+			"/b.js": `
+				import {a} from "./a.js"
+				export let b = () => a
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatIIFE,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{Name: "a", AbsPaths: []string{"/a.js"}},
+				{Name: "b", AbsPaths: []string{"/b.js"}},
+			},
+		},
+		expectedCompileLog: `error: Manual chunks cannot import each other in a cycle: "a" -> "b" -> "a"
+`,
+	})
+}
+
+func TestSplittingManualChunkSharedDependency(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import {render} from "react-dom"
+				import {createElement} from "react"
+				render(createElement("div"))
+			`,
+			"/node_modules/react/package.json": `{ "name": "react" }`,
+			"/node_modules/react/index.js": `
+				import assign from "object-assign"
+				import {helper} from "shared"
+				export function createElement(type) { return assign({type}, helper()) }
+			`,
+			"/node_modules/react-dom/package.json": `{ "name": "react-dom" }`,
+			"/node_modules/react-dom/index.js": `
+				import assign from "object-assign"
+				import {createElement} from "react"
+				import {helper} from "shared"
+				export function render(element) { return assign({root: createElement("root")}, element, helper()) }
+			`,
+			"/node_modules/object-assign/package.json": `{ "name": "object-assign" }`,
+			"/node_modules/object-assign/index.js": `
+				export default Object.assign
+			`,
+			"/node_modules/shared/package.json": `{ "name": "shared" }`,
+			"/node_modules/shared/index.js": `
+				export function helper() { return {} }
+			`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{Name: "react", PackageNames: []string{"react"}},
+				{Name: "vendor", PackageNames: []string{"react-dom", "object-assign"}},
+			},
+		},
+	})
+}
+
+func TestSplittingMinChunkSize(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {ab} from "./ab.js"
+				import {abc} from "./abc.js"
+				console.log(ab, abc)
+			`,
+			"/b.js": `
+				import {ab} from "./ab.js"
+				import {bc} from "./bc.js"
+				import {abc} from "./abc.js"
+				console.log(ab, bc, abc)
+			`,
+			"/c.js": `
+				import {bc} from "./bc.js"
+				import {abc} from "./abc.js"
+				import "./effect.js"
+				console.log(bc, abc)
+			`,
+			"/ab.js":     `export let ab = 1`,
+			"/bc.js":     `export let bc = 2`,
+			"/abc.js":    `export let abc = 3`,
+			"/effect.js": `console.log("effect")`,
+		},
+		entryPaths: []string{"/a.js", "/b.js", "/c.js"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			CodeSplitting:   true,
+			OutputFormat:    config.FormatESModule,
+			AbsOutputDir:    "/out",
+			AbsMetadataFile: "/out/meta.json",
+			MinChunkSize:    1000,
+		},
+	})
+}
+
+// The only other chunks are entry points and a manual chunk, so the shared
+// chunk can't be merged with anything. It's copied into the entry points
+// instead, and the copies import the state from the manual chunk.
+func TestSplittingMinChunkSizeDuplicateIntoEntryPoints(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {double, PI} from "./helpers.js"
+				import {state} from "./state.js"
+				console.log(double(PI), state)
+			`,
+			"/b.js": `
+				import {double} from "./helpers.js"
+				export {double}
+			`,
+			"/helpers.js": `
+				import {state} from "./state.js"
+				export const PI = 3.14
+				export function add(x, y) { return x + y + state.offset }
+				export function double(x) { return add(x, x) }
+			`,
+			"/state.js": `export let state = {offset: 0}`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			CodeSplitting:   true,
+			OutputFormat:    config.FormatESModule,
+			AbsOutputDir:    "/out",
+			AbsMetadataFile: "/out/meta.json",
+			MinChunkSize:    1000,
+			ManualChunks: []config.ManualChunk{
+				{Name: "state", AbsPaths: []string{"/state.js"}},
+			},
+		},
+	})
+}
+
+func TestSplittingMinChunkSizeDuplicateModuleState(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {count, inc} from "./counter.js"
+				inc()
+				console.log(count)
+			`,
+			"/b.js": `
+				import {count} from "./counter.js"
+				console.log(count)
+			`,
+			"/counter.js": `
+				export let count = 0
+				export function inc() { count++ }
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			CodeSplitting:   true,
+			OutputFormat:    config.FormatESModule,
+			AbsOutputDir:    "/out",
+			AbsMetadataFile: "/out/meta.json",
+			MinChunkSize:    1000,
+		},
+	})
+}
+
+func TestSplittingMinChunkSizeDuplicateUsedBySharedChunk(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {state} from "./x.js"
+				console.log(state)
+			`,
+			"/b.js": `
+				import {state} from "./x.js"
+				console.log(state)
+			`,
+			"/c.js": `
+				import {d} from "./util.js"
+				console.log(d())
+			`,
+			"/x.js": `
+				import {d} from "./util.js"
+				export let state = d()
+			`,
+			"/util.js": `
+				export function d() { return 123 }
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js", "/c.js"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			CodeSplitting:   true,
+			OutputFormat:    config.FormatESModule,
+			AbsOutputDir:    "/out",
+			AbsMetadataFile: "/out/meta.json",
+			MinChunkSize:    100000,
+		},
+	})
+}
+
+func TestSplittingMinChunkSizeSideEffects(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import "./ab.js"
+				import "./ac.js"
+			`,
+			"/b.js": `
+				import "./ab.js"
+			`,
+			"/c.js": `
+				import "./ac.js"
+			`,
+			"/ab.js": `console.log("ab")`,
+			"/ac.js": `console.log("ac")`,
+		},
+		entryPaths: []string{"/a.js", "/b.js", "/c.js"},
+		options: config.Options{
+			Mode:            config.ModeBundle,
+			CodeSplitting:   true,
+			OutputFormat:    config.FormatESModule,
+			AbsOutputDir:    "/out",
+			AbsMetadataFile: "/out/meta.json",
+			MinChunkSize:    1000,
+		},
+	})
+}
@@ -82,14 +82,14 @@ func (s *suite) expectBundled(t *testing.T, args bundled) {
 	testName := t.Name()
 	t.Run("", func(t *testing.T) {
 		t.Helper()
-		fs := fs.MockFS(args.files)
+		fs := fs.ZipFS(fs.MockFS(args.files), nil)
 		args.options.ExtensionOrder = []string{".tsx", ".ts", ".jsx", ".js", ".json"}
 		if args.options.AbsOutputFile != "" {
 			args.options.AbsOutputDir = path.Dir(args.options.AbsOutputFile)
 		}
 		log := logger.NewDeferLog()
-		resolver := resolver.NewResolver(fs, log, args.options)
-		bundle := ScanBundle(log, fs, resolver, args.entryPaths, args.options)
+		resolver := resolver.NewResolver(fs, log, nil, args.options)
+		bundle := ScanBundle(log, fs, resolver, nil, args.entryPaths, args.options)
 		msgs := log.Done()
 		assertLog(t, msgs, args.expectedScanLog)
 
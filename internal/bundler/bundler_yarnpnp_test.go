@@ -0,0 +1,173 @@
+package bundler
+
+import (
+	"testing"
+
+	"github.com/evanw/esbuild/internal/config"
+)
+
+var yarnpnp_suite = suite{
+	name: "yarnpnp",
+}
+
+func TestYarnPnPDataJSON(t *testing.T) {
+	yarnpnp_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import leftPad from 'left-pad'
+				import aliased from 'aliased/lib/util'
+				import fallback from 'fallback'
+				console.log(leftPad, aliased, fallback)
+			`,
+			"/Users/user/project/.pnp.data.json": `
+				{
+					"enableTopLevelFallback": true,
+					"fallbackPool": [
+						["fallback", "npm:1.0.0"]
+					],
+					"fallbackExclusionList": [],
+					"packageRegistryData": [
+						[null, [
+							[null, {
+								"packageLocation": "./",
+								"packageDependencies": [
+									["left-pad", "npm:1.3.0"],
+									["aliased", ["real-pkg", "npm:2.0.0"]]
+								]
+							}]
+						]],
+						["left-pad", [
+							["npm:1.3.0", {
+								"packageLocation": "./.yarn/unplugged/left-pad-npm-1.3.0/node_modules/left-pad/",
+								"packageDependencies": [
+									["left-pad", "npm:1.3.0"],
+									["peer-user", "virtual:abc#npm:1.0.0"]
+								]
+							}]
+						]],
+						["peer-user", [
+							["virtual:abc#npm:1.0.0", {
+								"packageLocation": "./.yarn/__virtual__/peer-user-virtual-abc/0/unplugged/peer-user-npm-1.0.0/node_modules/peer-user/",
+								"packageDependencies": [
+									["peer-user", "virtual:abc#npm:1.0.0"],
+									["left-pad", "npm:1.3.0"]
+								]
+							}]
+						]],
+						["real-pkg", [
+							["npm:2.0.0", {
+								"packageLocation": "./.yarn/unplugged/real-pkg-npm-2.0.0/node_modules/real-pkg/",
+								"packageDependencies": []
+							}]
+						]],
+						["fallback", [
+							["npm:1.0.0", {
+								"packageLocation": "./.yarn/unplugged/fallback-npm-1.0.0/node_modules/fallback/",
+								"packageDependencies": []
+							}]
+						]]
+					]
+				}
+			`,
+			"/Users/user/project/.yarn/unplugged/left-pad-npm-1.3.0/node_modules/left-pad/package.json": `
+				{ "main": "./main.js" }
+			`,
+			"/Users/user/project/.yarn/unplugged/left-pad-npm-1.3.0/node_modules/left-pad/main.js": `
+				import peerUser from 'peer-user'
+				export default 'left-pad' + peerUser
+			`,
+			"/Users/user/project/.yarn/unplugged/peer-user-npm-1.0.0/node_modules/peer-user/index.js": `
+				import 'left-pad'
+				export default 'peer-user'
+			`,
+			"/Users/user/project/.yarn/unplugged/real-pkg-npm-2.0.0/node_modules/real-pkg/lib/util.js": `
+				export default 'real-pkg'
+			`,
+			"/Users/user/project/.yarn/unplugged/fallback-npm-1.0.0/node_modules/fallback/index.js": `
+				export default 'fallback'
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+	})
+}
+
+func TestYarnPnPCJSManifestWithExports(t *testing.T) {
+	yarnpnp_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import pkg from 'pkg'
+				console.log(pkg)
+			`,
+			"/Users/user/project/.pnp.cjs": `
+				/* eslint-disable */
+				"use strict";
+
+				const RAW_RUNTIME_STATE =
+				'{\
+					"enableTopLevelFallback": false,\
+					"packageRegistryData": [\
+						[null, [[null, {"packageLocation": "./", "packageDependencies": [["pkg", "npm:1.0.0"]]}]]],\
+						["pkg", [["npm:1.0.0", {"packageLocation": "./.yarn/unplugged/pkg/node_modules/pkg/", "packageDependencies": []}]]]\
+					]\
+				}';
+
+				function $$SETUP_STATE(hydrateRuntimeState, basePath) {
+					return hydrateRuntimeState(JSON.parse(RAW_RUNTIME_STATE), {basePath: basePath || __dirname});
+				}
+			`,
+			"/Users/user/project/.yarn/unplugged/pkg/node_modules/pkg/package.json": `
+				{ "main": "./main.js", "exports": { "import": "./esm.js", "default": "./main.js" } }
+			`,
+			"/Users/user/project/.yarn/unplugged/pkg/node_modules/pkg/esm.js": `
+				export default 'esm'
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+	})
+}
+
+func TestYarnPnPErrors(t *testing.T) {
+	yarnpnp_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/Users/user/project/src/entry.js": `
+				import 'undeclared'
+				import 'pkg'
+			`,
+			"/Users/user/project/.pnp.data.json": `
+				{
+					"enableTopLevelFallback": false,
+					"packageRegistryData": [
+						[null, [[null, {"packageLocation": "./", "packageDependencies": [["pkg", "npm:1.0.0"]]}]]],
+						["pkg", [["npm:1.0.0", {"packageLocation": "./.yarn/unplugged/pkg/node_modules/pkg/", "packageDependencies": [["peer", null]]}]]]
+					]
+				}
+			`,
+			"/Users/user/project/node_modules/undeclared/index.js": `
+				export default 'this should not be found'
+			`,
+			"/Users/user/project/.yarn/unplugged/pkg/node_modules/pkg/index.js": `
+				import 'peer'
+			`,
+		},
+		entryPaths: []string{"/Users/user/project/src/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/Users/user/project/out.js",
+		},
+		expectedScanLog: `/Users/user/project/.yarn/unplugged/pkg/node_modules/pkg/index.js: error: Could not resolve "peer" (mark it as external to exclude it from the bundle)
+note: The package "peer" is a peer dependency of the package "pkg", but it was not provided by the package that depends on it
+note: This was resolved using the Yarn Plug'n'Play manifest "/Users/user/project/.pnp.data.json"
+/Users/user/project/src/entry.js: error: Could not resolve "undeclared" (mark it as external to exclude it from the bundle)
+note: The package "undeclared" is not listed as a dependency of your project
+note: This was resolved using the Yarn Plug'n'Play manifest "/Users/user/project/.pnp.data.json"
+`,
+	})
+}
@@ -0,0 +1,706 @@
+package bundler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/runtime"
+)
+
+// Code splitting creates a chunk for each set of entry points that share some
+// code. With many entry points, many of these chunks can be tiny and each one
+// is another request when the page loads. The "MinChunkSize" option merges
+// shared chunks that are smaller than that size with other shared chunks.
+//
+// Two chunks are merged by making the code in each chunk reachable from the
+// entry points of the other chunk. The code in both chunks then ends up in the
+// chunk for the combined set of entry points, and any chunk with code that
+// code depends on gains those entry points too. This means some entry points
+// load code they don't use, so merging is only allowed if none of the chunks
+// that gain entry points have code with side effects.
+//
+// These decisions are made on a graph of the chunks that code splitting
+// created. Each chunk in the graph knows its size, whether it has side effects,
+// and which other chunks have code that it depends on. The new entry points of
+// each chunk are only applied to the parts in it once at the end.
+//
+// A small chunk that can't be merged with another shared chunk can instead be
+// copied into the chunk for each of its entry points. Each copy declares its
+// own top-level symbols, so this is only done if the code has no side effects
+// and holds no module state. Otherwise entry points would each see a separate
+// copy of a variable that's supposed to be shared. It's also only done if no
+// other shared chunk uses the code, since that chunk wouldn't have a copy.
+
+// This is reported in the metafile to explain where the code in a chunk came
+// from. The chunk that was merged doesn't have a file name, so it's described
+// using the files that had code in it.
+type chunkMerge struct {
+	inputs         []string
+	estimatedBytes int
+	isDuplicated   bool
+}
+
+// This is a chunk created by code splitting before any chunks are merged. The
+// entry bits start off as the entry bits of the chunk and grow as chunks are
+// merged. Nodes with the same entry bits end up in the same chunk.
+type mergeNode struct {
+	originalBits   bitSet
+	entryBits      bitSet
+	parts          []partRef
+	cssFiles       []uint32
+	inputs         []string
+	estimatedBytes int
+
+	// These are the other nodes with code that this node's code depends on,
+	// either directly or indirectly. They must be reachable from every entry
+	// point that this node is reachable from.
+	dependencies []int
+
+	canMerge       bool
+	isEntryPoint   bool
+	hasManualBits  bool
+	hasSideEffects bool
+	isDuplicated   bool
+}
+
+func (c *linkerContext) mergeSmallChunks(chunks []chunkInfo) []chunkInfo {
+	if c.options.MinChunkSize <= 0 || len(chunks) < 2 {
+		return chunks
+	}
+	nodes := c.mergeGraphForChunks(chunks)
+	mergesByKey := make(map[string][]chunkMerge)
+	failuresByKey := make(map[string]string)
+
+	for {
+		// Group the nodes by the chunk they are currently in. The keys are sorted
+		// so this is deterministic.
+		groups := make(map[string][]int)
+		sizes := make(map[string]int)
+		for i := range nodes {
+			if node := &nodes[i]; !node.isDuplicated {
+				key := string(node.entryBits.entries)
+				groups[key] = append(groups[key], i)
+				sizes[key] += node.estimatedBytes
+			}
+		}
+		keys := make([]string, 0, len(groups))
+		for key := range groups {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		// Find the smallest chunk that's too small, ignoring ones that couldn't be
+		// merged already
+		smallKey := ""
+		for _, key := range keys {
+			if nodes[groups[key][0]].canMerge && sizes[key] < c.options.MinChunkSize &&
+				failuresByKey[key] == "" && (smallKey == "" || sizes[key] < sizes[smallKey]) {
+				smallKey = key
+			}
+		}
+		if smallKey == "" {
+			break
+		}
+		small := groups[smallKey]
+		smallBits := nodes[small[0]].entryBits
+
+		// Prefer merging with chunks that cause the least amount of extra code to
+		// be loaded by entry points that didn't need it before
+		type candidate struct {
+			key  string
+			cost int
+		}
+		var candidates []candidate
+		for _, key := range keys {
+			if key != smallKey && nodes[groups[key][0]].canMerge {
+				otherBits := nodes[groups[key][0]].entryBits
+				cost := sizes[smallKey]*c.countEntryPointsNotIn(otherBits, smallBits) +
+					sizes[key]*c.countEntryPointsNotIn(smallBits, otherBits)
+				candidates = append(candidates, candidate{key: key, cost: cost})
+			}
+		}
+		sort.SliceStable(candidates, func(i int, j int) bool {
+			return candidates[i].cost < candidates[j].cost
+		})
+
+		// Merge with the first chunk that works
+		failure := "There are no other shared chunks to merge this chunk with"
+		if len(candidates) > 0 {
+			failure = ""
+		}
+		for _, candidate := range candidates {
+			otherKey := candidate.key
+			combinedBits := newBitSet(c.entryBitCount())
+			combinedBits.copyFrom(smallBits)
+			combinedBits.bitwiseOrWith(nodes[groups[otherKey][0]].entryBits)
+
+			if reason := c.tryToMergeChunks(nodes, small, groups[otherKey]); reason != "" {
+				if failure == "" {
+					failure = reason
+				}
+				continue
+			}
+
+			// Remember what happened for the metafile
+			combinedKey := string(combinedBits.entries)
+			merges := append(mergesByKey[smallKey], chunkMerge{
+				inputs:         inputsForMergeNodes(nodes, small),
+				estimatedBytes: sizes[smallKey],
+			})
+			if otherKey != combinedKey {
+				merges = append(merges, mergesByKey[otherKey]...)
+				delete(mergesByKey, otherKey)
+			}
+			delete(mergesByKey, smallKey)
+			mergesByKey[combinedKey] = append(mergesByKey[combinedKey], merges...)
+			failure = ""
+			break
+		}
+
+		// Otherwise try copying the chunk into the chunk for each entry point
+		if failure != "" {
+			if reason := c.tryToDuplicateChunk(nodes, small); reason != "" {
+				if len(candidates) == 0 {
+					failure = reason
+				}
+				failuresByKey[smallKey] = failure
+				continue
+			}
+
+			// Remember what happened for the metafile
+			merges := append([]chunkMerge{{
+				inputs:         inputsForMergeNodes(nodes, small),
+				estimatedBytes: sizes[smallKey],
+				isDuplicated:   true,
+			}}, mergesByKey[smallKey]...)
+			delete(mergesByKey, smallKey)
+			for bit := range c.entryPoints {
+				if smallBits.hasBit(uint(bit)) {
+					entryBits := newBitSet(c.entryBitCount())
+					entryBits.setBit(uint(bit))
+					entryKey := string(entryBits.entries)
+					mergesByKey[entryKey] = append(mergesByKey[entryKey], merges...)
+				}
+			}
+		}
+	}
+
+	// Now that everything has been decided, move the code in each node to the
+	// chunk it ended up in
+	changed := false
+	for i := range nodes {
+		node := &nodes[i]
+		if !node.isDuplicated && node.entryBits.equals(node.originalBits) {
+			continue
+		}
+		changed = true
+		for _, part := range node.parts {
+			file := &c.files[part.sourceIndex]
+			partMeta := &file.repr.(*reprJS).meta.partMeta[part.partIndex]
+			if node.isDuplicated {
+				partMeta.isDuplicated = true
+			} else {
+				partMeta.entryBits.copyFrom(node.entryBits)
+				file.entryBits.bitwiseOrWith(node.entryBits)
+			}
+		}
+	}
+	if changed {
+		chunks = c.computeChunks()
+	}
+
+	for i := range chunks {
+		chunk := &chunks[i]
+		key := string(chunk.entryBits.entries)
+		chunk.mergedChunks = mergesByKey[key]
+		if c.canMergeChunk(chunk) {
+			chunk.notMergedReason = failuresByKey[key]
+		}
+	}
+	return chunks
+}
+
+// This builds the graph of chunks that merging works on. Code in one chunk
+// depends on code in another chunk if including the code in the first chunk
+// for an entry point would also include the code in the other chunk. This
+// follows the same dependencies as "includePart" and "includeFile", so files
+// are vertices in the graph too. The dependencies of each chunk are then all
+// chunks that can be reached from it in that graph.
+func (c *linkerContext) mergeGraphForChunks(chunks []chunkInfo) []mergeNode {
+	bitCount := c.entryBitCount()
+	entryPointCount := uint(len(c.entryPoints))
+	nodes := make([]mergeNode, len(chunks))
+	nodeForKey := make(map[string]int)
+	partSizes := make(map[uint32][]int)
+
+	for i := range chunks {
+		chunk := &chunks[i]
+		node := &nodes[i]
+		node.originalBits = newBitSet(bitCount)
+		node.originalBits.copyFrom(chunk.entryBits)
+		node.entryBits = newBitSet(bitCount)
+		node.entryBits.copyFrom(chunk.entryBits)
+		node.inputs = c.prettyPathsOfFilesInChunk(chunk)
+		node.estimatedBytes = c.estimateChunkSize(chunk, partSizes)
+		node.canMerge = c.canMergeChunk(chunk)
+		node.isEntryPoint = chunk.isEntryPoint
+		for bit := entryPointCount; bit < bitCount; bit++ {
+			if chunk.entryBits.hasBit(bit) {
+				node.hasManualBits = true
+			}
+		}
+		nodeForKey[string(chunk.entryBits.entries)] = i
+	}
+
+	// Each node and each reachable file is a vertex. Files come after the nodes.
+	edges := make([]map[int]bool, len(nodes))
+	for i := range edges {
+		edges[i] = make(map[int]bool)
+	}
+	vertexForFile := make(map[uint32]int)
+	for _, sourceIndex := range c.reachableFiles {
+		vertexForFile[sourceIndex] = len(edges)
+		edges = append(edges, make(map[int]bool))
+	}
+	nodeForBits := func(bits bitSet) (int, bool) {
+		i, ok := nodeForKey[string(bits.entries)]
+		return i, ok
+	}
+	addPartEdge := func(from int, sourceIndex uint32, partIndex uint32) {
+		if to, ok := nodeForBits(c.files[sourceIndex].repr.(*reprJS).meta.partMeta[partIndex].entryBits); ok && to != from {
+			edges[from][to] = true
+		}
+	}
+	addFileEdge := func(from int, sourceIndex uint32) {
+		if to, ok := vertexForFile[sourceIndex]; ok {
+			edges[from][to] = true
+		}
+	}
+	addRuntimeEdges := func(from int, name string) {
+		runtimeRepr := c.files[runtime.SourceIndex].repr.(*reprJS)
+		for _, partIndex := range runtimeRepr.ast.TopLevelSymbolToParts[runtimeRepr.ast.NamedExports[name]] {
+			addPartEdge(from, runtime.SourceIndex, partIndex)
+		}
+	}
+
+	for _, sourceIndex := range c.reachableFiles {
+		file := &c.files[sourceIndex]
+		fileVertex := vertexForFile[sourceIndex]
+
+		switch repr := file.repr.(type) {
+		case *reprJS:
+			// Including a file includes its parts with side effects and the files
+			// it imports for their side effects
+			if repr.cssSourceIndex != nil {
+				addFileEdge(fileVertex, *repr.cssSourceIndex)
+			}
+			for partIndex, part := range repr.ast.Parts {
+				if repr.meta.entryPointExportPartIndex != nil && uint32(partIndex) == *repr.meta.entryPointExportPartIndex {
+					continue
+				}
+				canBeRemovedIfUnused := part.CanBeRemovedIfUnused
+				for _, importRecordIndex := range part.ImportRecordIndices {
+					record := &repr.ast.ImportRecords[importRecordIndex]
+					if record.Kind != ast.ImportStmt {
+						continue
+					}
+					if record.SourceIndex != nil {
+						if c.files[*record.SourceIndex].ignoreIfUnused {
+							continue
+						}
+						addFileEdge(fileVertex, *record.SourceIndex)
+					}
+					canBeRemovedIfUnused = false
+				}
+				if !canBeRemovedIfUnused {
+					addPartEdge(fileVertex, sourceIndex, uint32(partIndex))
+				}
+			}
+			if file.isEntryPoint {
+				for _, alias := range repr.meta.sortedAndFilteredExportAliases {
+					export := repr.meta.resolvedExports[alias]
+					targetSourceIndex := export.sourceIndex
+					targetRef := export.ref
+					targetRepr := c.files[targetSourceIndex].repr.(*reprJS)
+					if importToBind, ok := targetRepr.meta.importsToBind[targetRef]; ok {
+						targetSourceIndex = importToBind.sourceIndex
+						targetRef = importToBind.ref
+						targetRepr = c.files[targetSourceIndex].repr.(*reprJS)
+					}
+					for _, partIndex := range targetRepr.ast.TopLevelSymbolToParts[targetRef] {
+						addPartEdge(fileVertex, targetSourceIndex, partIndex)
+					}
+				}
+			}
+
+			// Including a part includes its file and the parts it depends on
+			usesExportStar := false
+			for _, importRecordIndex := range repr.ast.ExportStarImportRecords {
+				if repr.ast.ImportRecords[importRecordIndex].CallsRunTimeExportStarFn {
+					usesExportStar = true
+				}
+			}
+			for partIndex := range repr.meta.partMeta {
+				partMeta := &repr.meta.partMeta[partIndex]
+				from, ok := nodeForBits(partMeta.entryBits)
+				if !ok {
+					continue
+				}
+				node := &nodes[from]
+				node.parts = append(node.parts, partRef{sourceIndex: sourceIndex, partIndex: uint32(partIndex)})
+				part := &repr.ast.Parts[partIndex]
+				if !part.CanBeRemovedIfUnused && !repr.meta.cjsWrap {
+					node.hasSideEffects = true
+				}
+				addFileEdge(from, sourceIndex)
+				for otherPartIndex := range part.LocalDependencies {
+					addPartEdge(from, sourceIndex, otherPartIndex)
+				}
+				for _, dependency := range partMeta.nonLocalDependencies {
+					addPartEdge(from, dependency.sourceIndex, dependency.partIndex)
+				}
+				for i := partMeta.nextSibling; i != uint32(partIndex); i = repr.meta.partMeta[i].nextSibling {
+					addPartEdge(from, sourceIndex, i)
+				}
+				for _, importRecordIndex := range part.ImportRecordIndices {
+					record := &repr.ast.ImportRecords[importRecordIndex]
+					if record.WrapWithToModule {
+						addRuntimeEdges(from, "__toModule")
+					}
+					if record.SourceIndex == nil || c.isExternalDynamicImport(record) ||
+						(record.Kind == ast.ImportStmt && !c.files[*record.SourceIndex].repr.(*reprJS).meta.cjsStyleExports) {
+						continue
+					}
+					addFileEdge(from, *record.SourceIndex)
+				}
+				if usesExportStar {
+					addRuntimeEdges(from, "__exportStar")
+				}
+			}
+
+		case *reprCSS:
+			if to, ok := nodeForBits(file.entryBits); ok {
+				edges[fileVertex][to] = true
+				nodes[to].cssFiles = append(nodes[to].cssFiles, sourceIndex)
+			}
+			for _, record := range repr.ast.ImportRecords {
+				if record.SourceIndex != nil {
+					addFileEdge(fileVertex, *record.SourceIndex)
+				}
+			}
+		}
+	}
+
+	// Find all nodes reachable from each node
+	for i := range nodes {
+		visited := map[int]bool{i: true}
+		stack := []int{i}
+		for len(stack) > 0 {
+			vertex := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for other := range edges[vertex] {
+				if !visited[other] {
+					visited[other] = true
+					stack = append(stack, other)
+					if other < len(nodes) {
+						nodes[i].dependencies = append(nodes[i].dependencies, other)
+					}
+				}
+			}
+		}
+		sort.Ints(nodes[i].dependencies)
+	}
+	return nodes
+}
+
+// This merges two groups of nodes if that doesn't cause any code with side
+// effects to be run by more entry points than before. Otherwise it leaves
+// everything as it was and returns the reason the chunks couldn't be merged.
+func (c *linkerContext) tryToMergeChunks(nodes []mergeNode, a []int, b []int) string {
+	// Every node in each group and every node they depend on gains the entry
+	// points of the other group
+	newBits := make(map[int]bitSet)
+	include := func(group []int, from bitSet) {
+		for _, i := range group {
+			for _, j := range append([]int{i}, nodes[i].dependencies...) {
+				bits, ok := newBits[j]
+				if !ok {
+					bits = newBitSet(c.entryBitCount())
+					bits.copyFrom(nodes[j].entryBits)
+					newBits[j] = bits
+				}
+				for bit := range c.entryPoints {
+					if from.hasBit(uint(bit)) {
+						bits.setBit(uint(bit))
+					}
+				}
+			}
+		}
+	}
+	include(a, nodes[b[0]].entryBits)
+	include(b, nodes[a[0]].entryBits)
+
+	// Don't change anything if more entry points would run code with side effects
+	for i := range nodes {
+		node := &nodes[i]
+		if bits, ok := newBits[i]; !ok || bits.equals(node.entryBits) {
+			continue
+		}
+		if node.hasManualBits {
+			return "Merging this chunk would move code into or out of a manual chunk"
+		}
+		if len(node.cssFiles) > 0 {
+			return "Merging this chunk would cause CSS to be included for more entry points"
+		}
+		if node.hasSideEffects || node.isEntryPoint {
+			return "Merging this chunk would cause code with side effects to run for more entry points"
+		}
+	}
+
+	for i, bits := range newBits {
+		nodes[i].entryBits = bits
+	}
+	return ""
+}
+
+// This marks a group of nodes as duplicated into the chunks for its entry
+// points if that's safe. Otherwise it leaves everything as it was and returns
+// the reason the chunk couldn't be duplicated.
+func (c *linkerContext) tryToDuplicateChunk(nodes []mergeNode, group []int) string {
+	inGroup := make(map[int]bool)
+	for _, i := range group {
+		inGroup[i] = true
+	}
+
+	for _, i := range group {
+		node := &nodes[i]
+		if len(node.cssFiles) > 0 {
+			return "Copying this chunk into entry points would cause CSS to be included more than once"
+		}
+		for _, part := range node.parts {
+			if reason := c.whyPartCantBeDuplicated(c.files[part.sourceIndex].repr.(*reprJS), part.partIndex); reason != "" {
+				return reason
+			}
+		}
+	}
+
+	// The copies only go in entry point chunks. Code in any other chunk that
+	// depends on this code would be left without a declaration of its symbols.
+	for i := range nodes {
+		node := &nodes[i]
+		if inGroup[i] || node.isEntryPoint || node.isDuplicated {
+			continue
+		}
+		for _, j := range node.dependencies {
+			if inGroup[j] {
+				return "Copying this chunk into entry points would leave code in another shared chunk without it"
+			}
+		}
+	}
+
+	for _, i := range group {
+		nodes[i].isDuplicated = true
+	}
+	return ""
+}
+
+func inputsForMergeNodes(nodes []mergeNode, group []int) []string {
+	var inputs []string
+	seen := make(map[string]bool)
+	for _, i := range group {
+		for _, input := range nodes[i].inputs {
+			if !seen[input] {
+				seen[input] = true
+				inputs = append(inputs, input)
+			}
+		}
+	}
+	sort.Strings(inputs)
+	return inputs
+}
+
+// Duplicated code is evaluated once for each entry point that loads it, and
+// each copy has its own top-level symbols. That's only invisible if the code
+// has no side effects and the symbols are never assigned to after they are
+// declared.
+func (c *linkerContext) whyPartCantBeDuplicated(repr *reprJS, partIndex uint32) string {
+	part := &repr.ast.Parts[partIndex]
+	partMeta := &repr.meta.partMeta[partIndex]
+
+	// Parts that don't generate any code can go anywhere
+	if len(part.Stmts) == 0 || !c.shouldIncludePart(repr, *part) {
+		return ""
+	}
+
+	if repr.meta.cjsWrap || partIndex == repr.meta.nsExportPartIndex {
+		return "Copying this chunk into entry points would duplicate module state"
+	}
+	if !part.CanBeRemovedIfUnused {
+		return "Copying this chunk into entry points would cause code with side effects to run more than once"
+	}
+	if len(part.ImportRecordIndices) > 0 {
+		return "Copying this chunk into entry points would duplicate an import"
+	}
+
+	// Each symbol must only be declared here and never assigned to
+	if partMeta.nextSibling != partIndex {
+		return "Copying this chunk into entry points would duplicate module state"
+	}
+	for _, use := range part.SymbolUses {
+		if use.IsAssigned {
+			return "Copying this chunk into entry points would duplicate module state"
+		}
+	}
+	for _, declared := range part.DeclaredSymbols {
+		if declared.IsTopLevel && len(repr.ast.TopLevelSymbolToParts[declared.Ref]) > 1 {
+			return "Copying this chunk into entry points would duplicate module state"
+		}
+	}
+
+	// Only allow functions and constants with primitive values. Other values
+	// such as objects and classes are mutable, so each copy would have its
+	// own state.
+	for _, stmt := range part.Stmts {
+		switch s := stmt.Data.(type) {
+		case *js_ast.SFunction, *js_ast.SExportClause:
+			continue
+
+		case *js_ast.SLocal:
+			if s.Kind == js_ast.LocalConst && isPrimitiveConstDecls(s.Decls) {
+				continue
+			}
+		}
+		return "Copying this chunk into entry points would duplicate module state"
+	}
+	return ""
+}
+
+func isPrimitiveConstDecls(decls []js_ast.Decl) bool {
+	for _, decl := range decls {
+		if _, ok := decl.Binding.Data.(*js_ast.BIdentifier); !ok || decl.Value == nil {
+			return false
+		}
+		switch decl.Value.Data.(type) {
+		case *js_ast.ENull, *js_ast.EUndefined, *js_ast.EBoolean, *js_ast.ENumber, *js_ast.EBigInt, *js_ast.EString:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Entry point chunks and manual chunks must keep their identity
+func (c *linkerContext) canMergeChunk(chunk *chunkInfo) bool {
+	_, isJS := chunk.repr.(*chunkReprJS)
+	return isJS && !chunk.isEntryPoint && chunk.manualChunkName == ""
+}
+
+// This returns the number of entry points in "a" that aren't in "b"
+func (c *linkerContext) countEntryPointsNotIn(a bitSet, b bitSet) int {
+	count := 0
+	for bit := range c.entryPoints {
+		if a.hasBit(uint(bit)) && !b.hasBit(uint(bit)) {
+			count++
+		}
+	}
+	return count
+}
+
+func (c *linkerContext) estimateChunkSize(chunk *chunkInfo, partSizes map[uint32][]int) int {
+	size := 0
+	for sourceIndex := range chunk.filesWithPartsInChunk {
+		file := &c.files[sourceIndex]
+		switch repr := file.repr.(type) {
+		case *reprJS:
+			sizes, ok := partSizes[sourceIndex]
+			if !ok {
+				sizes = estimatePartSizes(file.source, repr.ast.Parts)
+				partSizes[sourceIndex] = sizes
+			}
+			for partIndex := range repr.meta.partMeta {
+				if chunk.hasPart(&repr.meta.partMeta[partIndex]) {
+					size += sizes[partIndex]
+				}
+			}
+
+		case *reprCSS:
+			size += len(file.source.Contents)
+		}
+	}
+	return size
+}
+
+// The size of each part is estimated using the distance in the source code
+// between the start of that part and the start of the next part. Generated
+// parts don't have a size.
+func estimatePartSizes(source logger.Source, parts []js_ast.Part) []int {
+	type partStart struct {
+		partIndex int
+		start     int32
+	}
+	var starts []partStart
+	for partIndex, part := range parts {
+		if len(part.Stmts) > 0 && !part.IsNamespaceExport {
+			starts = append(starts, partStart{partIndex: partIndex, start: part.Stmts[0].Loc.Start})
+		}
+	}
+	sort.SliceStable(starts, func(i int, j int) bool {
+		return starts[i].start < starts[j].start
+	})
+
+	sizes := make([]int, len(parts))
+	for i, item := range starts {
+		end := int32(len(source.Contents))
+		if i+1 < len(starts) {
+			end = starts[i+1].start
+		}
+		sizes[item.partIndex] = int(end - item.start)
+	}
+	return sizes
+}
+
+func (c *linkerContext) prettyPathsOfFilesInChunk(chunk *chunkInfo) []string {
+	var paths []string
+	for sourceIndex := range chunk.filesWithPartsInChunk {
+		if sourceIndex != runtime.SourceIndex {
+			paths = append(paths, c.files[sourceIndex].source.PrettyPath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (c *linkerContext) generateChunkMergeMetadata(jMeta *js_printer.Joiner, chunk *chunkInfo) {
+	if len(chunk.mergedChunks) > 0 {
+		jMeta.AddString("\"mergedChunks\": [")
+		for i, merge := range chunk.mergedChunks {
+			if i > 0 {
+				jMeta.AddString(",")
+			}
+			jMeta.AddString("\n        {\n          \"inputs\": [")
+			for j, input := range merge.inputs {
+				if j > 0 {
+					jMeta.AddString(",")
+				}
+				jMeta.AddString(fmt.Sprintf("\n            %s", js_printer.QuoteForJSON(input, c.options.ASCIIOnly)))
+			}
+			if len(merge.inputs) > 0 {
+				jMeta.AddString("\n          ")
+			}
+			jMeta.AddString(fmt.Sprintf("],\n          \"estimatedBytes\": %d", merge.estimatedBytes))
+			if merge.isDuplicated {
+				jMeta.AddString(",\n          \"duplicated\": true")
+			}
+			jMeta.AddString("\n        }")
+		}
+		jMeta.AddString("\n      ],\n      ")
+	}
+	if chunk.notMergedReason != "" {
+		jMeta.AddString(fmt.Sprintf("\"notMergedReason\": %s,\n      ",
+			js_printer.QuoteForJSON(chunk.notMergedReason, c.options.ASCIIOnly)))
+	}
+}
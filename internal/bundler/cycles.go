@@ -0,0 +1,222 @@
+package bundler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// Import cycles between ECMAScript modules are allowed, but they mean that
+// some module in the cycle runs before a module it imports has been evaluated.
+// If that module reads an imported binding while it's being evaluated, the
+// read will either throw (for "let", "const", and "class") or return
+// "undefined". These errors only show up at run-time, often only after the
+// code has been bundled and the evaluation order has changed. This generates
+// a warning for each cycle so these problems can be found at build time.
+//
+// Only import statements are considered. Calls to "require()" and "import()"
+// are evaluated when the call happens, not when the module is loaded.
+type cycleDetector struct {
+	files      []file
+	order      []uint32 // Zero means not yet visited
+	lowLink    []uint32
+	isOnStack  []bool
+	stack      []uint32
+	nextOrder  uint32
+	components [][]uint32
+}
+
+type importCycle struct {
+	edges []importerRef
+
+	// This is true if the first edge reads an imported binding during module
+	// evaluation, which means the cycle is likely to break at run-time
+	readsBinding bool
+}
+
+func reportImportCycles(log logger.Log, files []file) {
+	cd := cycleDetector{
+		files:     files,
+		order:     make([]uint32, len(files)),
+		lowLink:   make([]uint32, len(files)),
+		isOnStack: make([]bool, len(files)),
+	}
+	for sourceIndex := range files {
+		if cd.order[sourceIndex] == 0 {
+			cd.visit(uint32(sourceIndex))
+		}
+	}
+
+	cycles := make([]importCycle, 0, len(cd.components))
+	for _, component := range cd.components {
+		cycles = append(cycles, cd.representativeCycle(component))
+	}
+
+	// Sort the cycles for determinism since source indices aren't deterministic
+	sort.Slice(cycles, func(i int, j int) bool {
+		return files[cycles[i].edges[0].sourceIndex].source.PrettyPath < files[cycles[j].edges[0].sourceIndex].source.PrettyPath
+	})
+
+	for _, cycle := range cycles {
+		first := cycle.edges[0]
+		firstSource := &files[first.sourceIndex].source
+		firstRecord := &files[first.sourceIndex].repr.importRecords()[first.importRecordIndex]
+
+		// Describe the whole cycle using the path of each file
+		chain := []string{fmt.Sprintf("%q", firstSource.PrettyPath)}
+		var notes []logger.MsgData
+		for i, edge := range cycle.edges {
+			source := &files[edge.sourceIndex].source
+			record := &files[edge.sourceIndex].repr.importRecords()[edge.importRecordIndex]
+			otherPath := files[*record.SourceIndex].source.PrettyPath
+			chain = append(chain, fmt.Sprintf("%q", otherPath))
+			if i > 0 {
+				notes = append(notes, logger.RangeData(source, record.Range,
+					fmt.Sprintf("%q imports %q here", source.PrettyPath, otherPath)))
+			}
+		}
+		text := strings.Join(chain, " -> ")
+
+		if cycle.readsBinding {
+			otherPath := files[*firstRecord.SourceIndex].source.PrettyPath
+			readRange := *firstRecord.TopLevelReadRange
+			notes = append(notes, logger.RangeData(firstSource, readRange,
+				fmt.Sprintf("%q is read here during module evaluation, which will fail if %q hasn't been evaluated yet",
+					firstSource.TextForRange(readRange), otherPath)))
+			log.AddRangeWarningWithNotes(logger.MsgIDImportCycle, firstSource, firstRecord.Range,
+				"Import cycle may read a binding before it has been initialized: "+text, notes)
+		} else {
+			notes = append(notes, logger.MsgData{
+				Text: "This cycle is likely harmless because no imported binding is read during module evaluation"})
+			log.AddRangeWarningWithNotes(logger.MsgIDImportCycleHarmless, firstSource, firstRecord.Range,
+				"Import cycle: "+text, notes)
+		}
+	}
+}
+
+// This calls the callback for each import record in this file that can take
+// part in an import cycle, along with the file it points to
+func (cd *cycleDetector) forEachEdge(sourceIndex uint32, callback func(importRecordIndex uint32, other uint32)) {
+	repr, ok := cd.files[sourceIndex].repr.(*reprJS)
+	if !ok || repr.cssSourceIndex != nil {
+		return
+	}
+	for importRecordIndex, record := range repr.ast.ImportRecords {
+		if record.Kind != ast.ImportStmt || record.IsUnused || record.SourceIndex == nil || *record.SourceIndex == sourceIndex {
+			continue
+		}
+		if _, ok := cd.files[*record.SourceIndex].repr.(*reprJS); ok {
+			callback(uint32(importRecordIndex), *record.SourceIndex)
+		}
+	}
+}
+
+// This is Tarjan's strongly connected components algorithm. Every component
+// with more than one file contains at least one cycle.
+func (cd *cycleDetector) visit(sourceIndex uint32) {
+	cd.nextOrder++
+	cd.order[sourceIndex] = cd.nextOrder
+	cd.lowLink[sourceIndex] = cd.nextOrder
+	cd.stack = append(cd.stack, sourceIndex)
+	cd.isOnStack[sourceIndex] = true
+
+	cd.forEachEdge(sourceIndex, func(importRecordIndex uint32, other uint32) {
+		if cd.order[other] == 0 {
+			cd.visit(other)
+			if cd.lowLink[other] < cd.lowLink[sourceIndex] {
+				cd.lowLink[sourceIndex] = cd.lowLink[other]
+			}
+		} else if cd.isOnStack[other] && cd.order[other] < cd.lowLink[sourceIndex] {
+			cd.lowLink[sourceIndex] = cd.order[other]
+		}
+	})
+
+	if cd.lowLink[sourceIndex] == cd.order[sourceIndex] {
+		var component []uint32
+		for {
+			last := cd.stack[len(cd.stack)-1]
+			cd.stack = cd.stack[:len(cd.stack)-1]
+			cd.isOnStack[last] = false
+			component = append(component, last)
+			if last == sourceIndex {
+				break
+			}
+		}
+		if len(component) > 1 {
+			cd.components = append(cd.components, component)
+		}
+	}
+}
+
+// A component may contain many overlapping cycles. Only one is reported. If
+// any import in the component reads an imported binding during module
+// evaluation, the reported cycle goes through that import.
+func (cd *cycleDetector) representativeCycle(component []uint32) importCycle {
+	sort.Slice(component, func(i int, j int) bool {
+		return cd.files[component[i]].source.PrettyPath < cd.files[component[j]].source.PrettyPath
+	})
+	isInComponent := make(map[uint32]bool, len(component))
+	for _, sourceIndex := range component {
+		isInComponent[sourceIndex] = true
+	}
+
+	for _, sourceIndex := range component {
+		records := cd.files[sourceIndex].repr.importRecords()
+		var found *importCycle
+		cd.forEachEdge(sourceIndex, func(importRecordIndex uint32, other uint32) {
+			if found == nil && isInComponent[other] && records[importRecordIndex].TopLevelReadRange != nil {
+				edges := []importerRef{{sourceIndex: sourceIndex, importRecordIndex: importRecordIndex}}
+				edges = append(edges, cd.shortestPath(isInComponent, other, sourceIndex)...)
+				found = &importCycle{edges: edges, readsBinding: true}
+			}
+		})
+		if found != nil {
+			return *found
+		}
+	}
+
+	start := component[0]
+	return importCycle{edges: cd.shortestPath(isInComponent, start, start)}
+}
+
+// This does a breadth-first search within a component. If "from" and "to" are
+// the same file, this returns the shortest cycle through that file.
+func (cd *cycleDetector) shortestPath(isInComponent map[uint32]bool, from uint32, to uint32) []importerRef {
+	parents := make(map[uint32]importerRef)
+	queue := []uint32{from}
+	found := false
+
+	for len(queue) > 0 && !found {
+		sourceIndex := queue[0]
+		queue = queue[1:]
+		cd.forEachEdge(sourceIndex, func(importRecordIndex uint32, other uint32) {
+			if _, ok := parents[other]; found || ok || !isInComponent[other] || (other == from && from != to) {
+				return
+			}
+			parents[other] = importerRef{sourceIndex: sourceIndex, importRecordIndex: importRecordIndex}
+			if other == to {
+				found = true
+			} else {
+				queue = append(queue, other)
+			}
+		})
+	}
+
+	// The edges were collected backward from the end to the start
+	var path []importerRef
+	for sourceIndex := to; ; {
+		edge := parents[sourceIndex]
+		path = append(path, edge)
+		sourceIndex = edge.sourceIndex
+		if sourceIndex == from {
+			break
+		}
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,182 @@
+package bundler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/resolver"
+)
+
+// This returns the reachable files that have at least one part in an output
+// file. Files that were completely removed by tree shaking aren't included.
+func (c *linkerContext) filesWithLiveParts() []uint32 {
+	neverReached := newBitSet(c.entryBitCount())
+	var result []uint32
+
+	for _, sourceIndex := range c.reachableFiles {
+		file := &c.files[sourceIndex]
+		switch repr := file.repr.(type) {
+		case *reprJS:
+			for _, partMeta := range repr.meta.partMeta {
+				if !partMeta.entryBits.equals(neverReached) {
+					result = append(result, sourceIndex)
+					break
+				}
+			}
+
+		case *reprCSS:
+			if !file.entryBits.equals(neverReached) {
+				result = append(result, sourceIndex)
+			}
+		}
+	}
+
+	return result
+}
+
+type packageCopy struct {
+	info      *resolver.PackageInfo
+	prettyDir string
+	bytes     int
+	importers []importerRef
+}
+
+// Package managers sometimes install more than one copy of the same package,
+// usually different versions in nested "node_modules" directories. If more
+// than one copy ends up in the output, the code is duplicated and each copy
+// has its own module-level state. This generates a warning for each package
+// with more than one copy, along with the imports that caused each copy to
+// be included.
+//
+// Each group of files is checked separately since entry points that are
+// linked separately don't share any code with each other. The same warning
+// is only reported once even if it happens in more than one group.
+func reportDuplicatePackages(log logger.Log, res resolver.Resolver, files []file, filesInOutput [][]uint32) {
+	reported := make(map[string]bool)
+	for _, group := range filesInOutput {
+		reportDuplicatePackagesInGroup(log, res, files, group, reported)
+	}
+}
+
+func reportDuplicatePackagesInGroup(log logger.Log, res resolver.Resolver, files []file, filesInOutput []uint32, reported map[string]bool) {
+	copiesByName := make(map[string]map[string]*packageCopy)
+	isInOutput := make(map[uint32]bool)
+
+	for _, sourceIndex := range filesInOutput {
+		if isInOutput[sourceIndex] {
+			continue
+		}
+		isInOutput[sourceIndex] = true
+
+		// Group files by package name and then by package directory
+		file := &files[sourceIndex]
+		if info := file.packageInfo; info != nil {
+			copies, ok := copiesByName[info.Name]
+			if !ok {
+				copies = make(map[string]*packageCopy)
+				copiesByName[info.Name] = copies
+			}
+			pkgCopy, ok := copies[info.AbsDir]
+			if !ok {
+				pkgCopy = &packageCopy{
+					info:      info,
+					prettyDir: res.PrettyPath(logger.Path{Text: info.AbsDir, Namespace: "file"}),
+				}
+				copies[info.AbsDir] = pkgCopy
+			}
+			pkgCopy.bytes += len(file.source.Contents)
+		}
+	}
+
+	// Sort the names for determinism
+	var duplicateNames []string
+	for name, copies := range copiesByName {
+		if len(copies) > 1 {
+			duplicateNames = append(duplicateNames, name)
+		}
+	}
+	if len(duplicateNames) == 0 {
+		return
+	}
+	sort.Strings(duplicateNames)
+
+	// Find the imports from outside of each copy that point into it
+	for sourceIndex := range isInOutput {
+		importerInfo := files[sourceIndex].packageInfo
+		records := files[sourceIndex].repr.importRecords()
+		for importRecordIndex := range records {
+			record := &records[importRecordIndex]
+			if record.SourceIndex == nil || !isInOutput[*record.SourceIndex] {
+				continue
+			}
+			info := files[*record.SourceIndex].packageInfo
+			if info == nil || (importerInfo != nil && importerInfo.AbsDir == info.AbsDir) {
+				continue
+			}
+			if copies := copiesByName[info.Name]; len(copies) > 1 {
+				pkgCopy := copies[info.AbsDir]
+				pkgCopy.importers = append(pkgCopy.importers, importerRef{sourceIndex: sourceIndex, importRecordIndex: uint32(importRecordIndex)})
+			}
+		}
+	}
+
+	for _, name := range duplicateNames {
+		copies := make([]*packageCopy, 0, len(copiesByName[name]))
+		for _, pkgCopy := range copiesByName[name] {
+			copies = append(copies, pkgCopy)
+		}
+		sort.Slice(copies, func(i int, j int) bool {
+			return copies[i].prettyDir < copies[j].prettyDir
+		})
+
+		// Skip this if the same copies were already reported for another group
+		key := name
+		for _, pkgCopy := range copies {
+			key += "\x00" + pkgCopy.prettyDir
+		}
+		if reported[key] {
+			continue
+		}
+		reported[key] = true
+
+		var notes []logger.MsgData
+		for _, pkgCopy := range copies {
+			description := fmt.Sprintf("%q", pkgCopy.prettyDir)
+			if pkgCopy.info.Version != "" {
+				description += fmt.Sprintf(" (version %s, %s)", pkgCopy.info.Version, logger.PrettyPrintedBytes(pkgCopy.bytes))
+			} else {
+				description += fmt.Sprintf(" (%s)", logger.PrettyPrintedBytes(pkgCopy.bytes))
+			}
+
+			// This can happen if an entry point is inside the package
+			if len(pkgCopy.importers) == 0 {
+				notes = append(notes, logger.MsgData{Text: "The copy in " + description + " is in the bundle"})
+				continue
+			}
+
+			// Sort the importers for determinism since source indices aren't deterministic
+			sort.Slice(pkgCopy.importers, func(i int, j int) bool {
+				a, b := pkgCopy.importers[i], pkgCopy.importers[j]
+				pathA, pathB := files[a.sourceIndex].source.PrettyPath, files[b.sourceIndex].source.PrettyPath
+				return pathA < pathB || (pathA == pathB && a.importRecordIndex < b.importRecordIndex)
+			})
+			for i, importer := range pkgCopy.importers {
+				source := &files[importer.sourceIndex].source
+				record := &files[importer.sourceIndex].repr.importRecords()[importer.importRecordIndex]
+				text := "The copy in " + description + " is imported here"
+				if i > 0 {
+					text = fmt.Sprintf("The copy in %q is also imported here", pkgCopy.prettyDir)
+				}
+				notes = append(notes, logger.RangeData(source, record.Range, text))
+			}
+		}
+
+		log.AddMsg(logger.Msg{
+			ID:    logger.MsgIDDuplicatePackage,
+			Kind:  logger.Warning,
+			Text:  fmt.Sprintf("The package %q is included in the bundle %d times", name, len(copies)),
+			Notes: notes,
+		})
+	}
+}
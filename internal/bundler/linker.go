@@ -17,6 +17,7 @@ import (
 	"github.com/evanw/esbuild/internal/fs"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/js_parser"
 	"github.com/evanw/esbuild/internal/js_printer"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/renamer"
@@ -80,8 +81,19 @@ type linkerContext struct {
 	// containing a source index (such as "js_ast.Ref" symbol references).
 	stableSourceIndices []uint32
 
-	// We may need to refer to the CommonJS "module" symbol for exports
+	// We may need to refer to the CommonJS "module" symbol for exports. This
+	// is also the "module" argument to the callback for the SystemJS format.
 	unboundModuleRef js_ast.Ref
+
+	// This is the "exports" function passed to the SystemJS callback
+	unboundExportsRef js_ast.Ref
+
+	// This is the function that loads other chunks with the IIFE format when
+	// code splitting is enabled. It's passed to the callback for each chunk.
+	chunkLoaderRef js_ast.Ref
+
+	// The chunk loader is the same in every chunk, so it's only printed once
+	chunkLoaderJS []byte
 }
 
 // This contains linker-specific metadata corresponding to a "file" struct
@@ -228,6 +240,11 @@ type partMeta struct {
 
 	// These are dependencies that come from other files via import statements.
 	nonLocalDependencies []partRef
+
+	// If true, this part is in the chunk for each entry point in "entryBits"
+	// instead of in the chunk shared by those entry points. This is done for
+	// small parts without side effects or state when merging small chunks.
+	isDuplicated bool
 }
 
 type partRef struct {
@@ -262,6 +279,15 @@ type chunkInfo struct {
 	sourceIndex   uint32 // An index into "c.sources"
 	entryPointBit uint   // An index into "c.entryPoints"
 
+	// This is only set for chunks created for manual chunks. It's used in the
+	// file name instead of "chunk".
+	manualChunkName string
+
+	// These explain the decisions made when merging small chunks. They are
+	// only used for the metafile.
+	mergedChunks    []chunkMerge
+	notMergedReason string
+
 	// For code splitting
 	crossChunkImports []uint32
 
@@ -280,6 +306,12 @@ type chunkReprJS struct {
 	crossChunkSuffixStmts  []js_ast.Stmt
 	exportsToOtherChunks   map[js_ast.Ref]string
 	importsFromOtherChunks map[uint32]crossChunkImportItemArray
+
+	// Formats without import statements access imports from other chunks as
+	// properties on a namespace object for each imported chunk. These objects
+	// are in the same order as the chunk's cross-chunk import records.
+	crossChunkNamespaceRefs []js_ast.Ref
+	crossChunkImportAliases map[js_ast.Ref]js_ast.NamespaceAlias
 }
 
 func (*chunkReprJS) fileExt() string { return ".js" }
@@ -298,6 +330,24 @@ func (chunk *chunkInfo) relPath() string {
 	return path.Join(chunk.relDir, chunk.baseNameOrEmpty)
 }
 
+// Parts are normally in the chunk for their exact set of entry points, but
+// duplicated parts are in the chunk for each of their entry points instead
+func (chunk *chunkInfo) hasPart(partMeta *partMeta) bool {
+	if partMeta.isDuplicated {
+		return chunk.isEntryPoint && partMeta.entryBits.hasBit(chunk.entryPointBit)
+	}
+	return chunk.entryBits.equals(partMeta.entryBits)
+}
+
+// Chunks that aren't entry points are named using a hash of their contents
+func (chunk *chunkInfo) hashedBaseName(hash string, ext string) string {
+	name := "chunk"
+	if chunk.manualChunkName != "" {
+		name = chunk.manualChunkName
+	}
+	return name + "." + hash + ext
+}
+
 func newLinkerContext(
 	options *config.Options,
 	log logger.Log,
@@ -417,6 +467,19 @@ func newLinkerContext(
 		c.stableSourceIndices[sourceIndex] = uint32(stableIndex)
 	}
 
+	// With hot module replacement, every module is wrapped in a closure that is
+	// registered with the runtime so that it can be re-executed later. Imports
+	// then go through the exports object, which makes it possible to swap out
+	// the module that is being imported. JavaScript stubs for CSS files are
+	// left alone since the runtime swaps the stylesheet itself instead.
+	if options.HotModuleReplacement {
+		for _, sourceIndex := range c.reachableFiles {
+			if repr, ok := c.files[sourceIndex].repr.(*reprJS); ok && sourceIndex != runtime.SourceIndex && repr.cssSourceIndex == nil {
+				repr.meta.cjsStyleExports = true
+			}
+		}
+	}
+
 	// Mark all entry points so we don't add them again for import() expressions
 	for _, sourceIndex := range entryPoints {
 		file := &c.files[sourceIndex]
@@ -424,31 +487,62 @@ func newLinkerContext(
 
 		// Entry points must be CommonJS-style if the output format doesn't support
 		// ES6 export syntax
-		if !options.OutputFormat.KeepES6ImportExportSyntax() {
-			if repr, ok := file.repr.(*reprJS); ok && repr.ast.HasES6Exports {
-				repr.meta.cjsStyleExports = true
-			}
+		if repr, ok := file.repr.(*reprJS); ok && c.entryPointNeedsCommonJSExports(repr, false /* isDynamicImportTarget */) {
+			repr.meta.cjsStyleExports = true
 		}
 	}
 
 	// Allocate a new unbound symbol called "module" in case we need it later
-	if c.options.OutputFormat == config.FormatCommonJS {
-		runtimeSymbols := &c.symbols.Outer[runtime.SourceIndex]
-		runtimeScope := c.files[runtime.SourceIndex].repr.(*reprJS).ast.ModuleScope
-		c.unboundModuleRef = js_ast.Ref{OuterIndex: runtime.SourceIndex, InnerIndex: uint32(len(*runtimeSymbols))}
-		runtimeScope.Generated = append(runtimeScope.Generated, c.unboundModuleRef)
-		*runtimeSymbols = append(*runtimeSymbols, js_ast.Symbol{
-			Kind:         js_ast.SymbolUnbound,
-			OriginalName: "module",
-			Link:         js_ast.InvalidRef,
-		})
+	if c.options.OutputFormat == config.FormatCommonJS || c.options.OutputFormat == config.FormatSystem {
+		c.unboundModuleRef = c.generateUnboundRuntimeSymbol("module")
 	} else {
 		c.unboundModuleRef = js_ast.InvalidRef
 	}
 
+	// The SystemJS format also passes an "exports" function to the callback
+	if c.options.OutputFormat == config.FormatSystem {
+		c.unboundExportsRef = c.generateUnboundRuntimeSymbol("exports")
+	} else {
+		c.unboundExportsRef = js_ast.InvalidRef
+	}
+
+	// The IIFE format passes a chunk loader to the callback for each chunk
+	if c.options.OutputFormat == config.FormatIIFE && c.options.CodeSplitting {
+		c.chunkLoaderRef = c.generateUnboundRuntimeSymbol("__import")
+	} else {
+		c.chunkLoaderRef = js_ast.InvalidRef
+	}
+
 	return c
 }
 
+// With the IIFE format and code splitting, entry points that are loaded using
+// "import()" return their exports to the chunk loader even if they don't have
+// any. Other entry points without exports are left alone so they don't need
+// to be wrapped in a closure.
+func (c *linkerContext) entryPointNeedsCommonJSExports(repr *reprJS, isDynamicImportTarget bool) bool {
+	if c.options.OutputFormat.KeepES6ImportExportSyntax() {
+		return false
+	}
+	return repr.ast.HasES6Exports || (isDynamicImportTarget && c.options.OutputFormat == config.FormatIIFE && c.options.CodeSplitting)
+}
+
+// Unbound symbols are never renamed, and other symbols are renamed to avoid
+// them. They are stored in the runtime file's scope so they are reserved in
+// every chunk.
+func (c *linkerContext) generateUnboundRuntimeSymbol(name string) js_ast.Ref {
+	runtimeSymbols := &c.symbols.Outer[runtime.SourceIndex]
+	runtimeScope := c.files[runtime.SourceIndex].repr.(*reprJS).ast.ModuleScope
+	ref := js_ast.Ref{OuterIndex: runtime.SourceIndex, InnerIndex: uint32(len(*runtimeSymbols))}
+	runtimeScope.Generated = append(runtimeScope.Generated, ref)
+	*runtimeSymbols = append(*runtimeSymbols, js_ast.Symbol{
+		Kind:         js_ast.SymbolUnbound,
+		OriginalName: name,
+		Link:         js_ast.InvalidRef,
+	})
+	return ref
+}
+
 type indexAndPath struct {
 	sourceIndex uint32
 	path        logger.Path
@@ -519,7 +613,7 @@ func (c *linkerContext) addPartToFile(sourceIndex uint32, part js_ast.Part, part
 		part.SymbolUses = make(map[js_ast.Ref]js_ast.SymbolUse)
 	}
 	if partMeta.entryBits.entries == nil {
-		partMeta.entryBits = newBitSet(uint(len(c.entryPoints)))
+		partMeta.entryBits = newBitSet(c.entryBitCount())
 	}
 	repr := c.files[sourceIndex].repr.(*reprJS)
 	partIndex := uint32(len(repr.ast.Parts))
@@ -540,6 +634,7 @@ func (c *linkerContext) link() []OutputFile {
 
 	c.markPartsReachableFromEntryPoints()
 	c.handleCrossChunkAssignments()
+	c.assignManualChunks()
 
 	if c.options.Mode == config.ModePassThrough {
 		for _, entryPoint := range c.entryPoints {
@@ -547,13 +642,24 @@ func (c *linkerContext) link() []OutputFile {
 		}
 	}
 
-	chunks := c.computeChunks()
+	chunks := c.mergeSmallChunks(c.computeChunks())
 	c.computeCrossChunkDependencies(chunks)
 
+	// Manual chunks can end up importing each other, which isn't allowed with
+	// some formats
+	if c.checkForManualChunkCycles(chunks) {
+		return []OutputFile{}
+	}
+
 	// Make sure calls to "js_ast.FollowSymbols()" in parallel goroutines after this
 	// won't hit concurrent map mutation hazards
 	js_ast.FollowAllSymbols(c.symbols)
 
+	// Stop now if the build was cancelled
+	if c.options.CancelFlag.DidCancel() {
+		return []OutputFile{}
+	}
+
 	return c.generateChunksInParallel(chunks)
 }
 
@@ -587,6 +693,7 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo) []OutputFil
 						entryPointBit:         chunk.entryPointBit,
 						relDir:                chunk.relDir,
 						baseNameOrEmpty:       baseNameOrEmpty,
+						manualChunkName:       chunk.manualChunkName,
 						filesWithPartsInChunk: make(map[uint32]bool),
 						repr:                  &chunkReprCSS{},
 					})
@@ -607,30 +714,30 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo) []OutputFil
 		dependencies sync.WaitGroup
 		dependents   []uint32
 	}
+	//
+	// Chunks that import each other in a cycle can't wait for each other, so
+	// they are named ahead of time instead. Their names use a hash of the input
+	// files of every chunk they can reach since their contents aren't known yet.
+	// Then they only need to wait for the chunks they import outside the cycle.
+	cycles, cycleSizes := findChunkCycles(chunks)
 	chunkOrdering := make([]ordering, len(chunks))
-	for chunkIndex, chunk := range chunks {
-		chunkOrdering[chunkIndex].dependencies.Add(len(chunk.crossChunkImports))
+	for chunkIndex := range chunks {
+		chunk := &chunks[chunkIndex]
 		for _, otherChunkIndex := range chunk.crossChunkImports {
-			dependents := &chunkOrdering[otherChunkIndex].dependents
-			*dependents = append(*dependents, uint32(chunkIndex))
-		}
-	}
-
-	// Check for loops in the dependency graph since they cause a deadlock
-	var check func(int, []int)
-	check = func(chunkIndex int, path []int) {
-		for _, otherChunkIndex := range path {
-			if chunkIndex == otherChunkIndex {
-				panic("Internal error: Chunk import graph contains a cycle")
+			if cycles[otherChunkIndex] != cycles[chunkIndex] {
+				chunkOrdering[chunkIndex].dependencies.Add(1)
+				dependents := &chunkOrdering[otherChunkIndex].dependents
+				*dependents = append(*dependents, uint32(chunkIndex))
 			}
 		}
-		path = append(path, chunkIndex)
-		for _, otherChunkIndex := range chunks[chunkIndex].crossChunkImports {
-			check(int(otherChunkIndex), path)
+		if cycleSizes[cycles[chunkIndex]] > 1 && chunk.baseNameOrEmpty == "" {
+			hash := hashForFileName(c.inputsForChunkCycle(chunks, uint32(chunkIndex)))
+			chunk.baseNameOrEmpty = chunk.hashedBaseName(hash, c.options.OutputExtensionFor(chunk.repr.fileExt()))
 		}
 	}
-	for i := range chunks {
-		check(i, nil)
+
+	if c.chunkLoaderRef != js_ast.InvalidRef {
+		c.chunkLoaderJS = c.printChunkLoader()
 	}
 
 	results := make([][]OutputFile, len(chunks))
@@ -646,7 +753,10 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo) []OutputFil
 			// Start generating the chunk without dependencies, but stop when
 			// dependencies are needed. This returns a callback that is called
 			// later to resume generating the chunk once dependencies are known.
-			resume := chunk.repr.generate(c, chunk)
+			var resume func([]ast.ImportRecord) []OutputFile
+			if !c.options.CancelFlag.DidCancel() {
+				resume = chunk.repr.generate(c, chunk)
+			}
 
 			// Wait for all dependencies to be resolved first
 			order.dependencies.Wait()
@@ -660,8 +770,11 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo) []OutputFil
 				}
 			}
 
-			// Generate the chunk
-			results[i] = resume(crossChunkImportRecords)
+			// Generate the chunk unless the build was cancelled in the meantime.
+			// Dependents must still be woken up below to avoid a deadlock.
+			if resume != nil && !c.options.CancelFlag.DidCancel() {
+				results[i] = resume(crossChunkImportRecords)
+			}
 
 			// Wake up any dependents now that we're done
 			for _, chunkIndex := range order.dependents {
@@ -680,6 +793,102 @@ func (c *linkerContext) generateChunksInParallel(chunks []chunkInfo) []OutputFil
 	return outputFiles
 }
 
+// This groups chunks that import each other in a cycle using Tarjan's
+// strongly-connected components algorithm. It returns the group of each chunk
+// and the number of chunks in each group.
+func findChunkCycles(chunks []chunkInfo) (cycles []uint32, cycleSizes []uint32) {
+	cycles = make([]uint32, len(chunks))
+	order := make([]uint32, len(chunks))
+	lowLinks := make([]uint32, len(chunks))
+	onStack := make([]bool, len(chunks))
+	var stack []uint32
+	nextOrder := uint32(1)
+
+	var visit func(chunkIndex uint32)
+	visit = func(chunkIndex uint32) {
+		order[chunkIndex] = nextOrder
+		lowLinks[chunkIndex] = nextOrder
+		nextOrder++
+		stack = append(stack, chunkIndex)
+		onStack[chunkIndex] = true
+
+		for _, otherChunkIndex := range chunks[chunkIndex].crossChunkImports {
+			if order[otherChunkIndex] == 0 {
+				visit(otherChunkIndex)
+				if lowLinks[otherChunkIndex] < lowLinks[chunkIndex] {
+					lowLinks[chunkIndex] = lowLinks[otherChunkIndex]
+				}
+			} else if onStack[otherChunkIndex] && order[otherChunkIndex] < lowLinks[chunkIndex] {
+				lowLinks[chunkIndex] = order[otherChunkIndex]
+			}
+		}
+
+		// This chunk is the first one visited in its cycle, so everything above
+		// it on the stack is in the same cycle
+		if lowLinks[chunkIndex] == order[chunkIndex] {
+			cycle := uint32(len(cycleSizes))
+			size := uint32(0)
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				cycles[top] = cycle
+				size++
+				if top == chunkIndex {
+					break
+				}
+			}
+			cycleSizes = append(cycleSizes, size)
+		}
+	}
+
+	for chunkIndex := range chunks {
+		if order[chunkIndex] == 0 {
+			visit(uint32(chunkIndex))
+		}
+	}
+	return
+}
+
+// This returns the inputs that determine the contents of a chunk in a cycle
+// and of every chunk it imports directly or indirectly. That's the options that
+// change how code is printed and the input files of those chunks. The chunk's
+// own entry bits go first since every chunk in the cycle reaches the same
+// chunks.
+func (c *linkerContext) inputsForChunkCycle(chunks []chunkInfo, chunkIndex uint32) []byte {
+	inputs := append([]byte{}, chunks[chunkIndex].entryBits.entries...)
+	inputs = append(inputs, fmt.Sprintf("%d %d %d %t %t %t",
+		c.options.OutputFormat,
+		c.options.UnsupportedJSFeatures,
+		c.options.SourceMap,
+		c.options.RemoveWhitespace,
+		c.options.MinifyIdentifiers,
+		c.options.MangleSyntax,
+	)...)
+	visited := make(map[uint32]bool)
+
+	var visit func(chunkIndex uint32)
+	visit = func(chunkIndex uint32) {
+		if visited[chunkIndex] {
+			return
+		}
+		visited[chunkIndex] = true
+		chunk := &chunks[chunkIndex]
+		inputs = append(inputs, chunk.relDir...)
+		for _, sourceIndex := range chunk.filesInChunkInOrder {
+			source := &c.files[sourceIndex].source
+			inputs = append(inputs, source.KeyPath.Text...)
+			inputs = append(inputs, source.Contents...)
+		}
+		for _, otherChunkIndex := range chunk.crossChunkImports {
+			visit(otherChunkIndex)
+		}
+	}
+
+	visit(chunkIndex)
+	return inputs
+}
+
 func (c *linkerContext) relativePathBetweenChunks(fromRelDir string, toRelPath string) string {
 	relPath, ok := c.fs.Rel(fromRelDir, toRelPath)
 	if !ok {
@@ -719,7 +928,6 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 	waitGroup.Add(len(chunks))
 	for chunkIndex, chunk := range chunks {
 		go func(chunkIndex int, chunk chunkInfo) {
-			chunkKey := string(chunk.entryBits.entries)
 			imports := make(map[js_ast.Ref]bool)
 			chunkMetas[chunkIndex] = chunkMeta{imports: imports, exports: make(map[js_ast.Ref]bool)}
 
@@ -728,8 +936,9 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 				// Go over each part in this file that's marked for inclusion in this chunk
 				switch repr := c.files[sourceIndex].repr.(type) {
 				case *reprJS:
-					for partIndex, partMeta := range repr.meta.partMeta {
-						if string(partMeta.entryBits.entries) != chunkKey {
+					for partIndex := range repr.meta.partMeta {
+						partMeta := &repr.meta.partMeta[partIndex]
+						if !chunk.hasPart(partMeta) {
 							continue
 						}
 						part := &repr.ast.Parts[partIndex]
@@ -740,6 +949,7 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 							if record.SourceIndex != nil && c.isExternalDynamicImport(record) {
 								record.Path.Text = c.relativePathBetweenChunks(chunk.relDir, c.files[*record.SourceIndex].entryPointRelPath)
 								record.SourceIndex = nil
+								record.IsChunkImport = true
 							}
 						}
 
@@ -747,10 +957,13 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 						// with multiple declarations such as repeated "var" statements with
 						// the same name should already be marked as all being in a single
 						// chunk. In that case this will overwrite the same value below which
-						// is fine.
-						for _, declared := range part.DeclaredSymbols {
-							if declared.IsTopLevel {
-								c.symbols.Get(declared.Ref).ChunkIndex = ^uint32(chunkIndex)
+						// is fine. Symbols in duplicated parts are declared in every chunk
+						// that uses them, so they are never imported from another chunk.
+						if !partMeta.isDuplicated {
+							for _, declared := range part.DeclaredSymbols {
+								if declared.IsTopLevel {
+									c.symbols.Get(declared.Ref).ChunkIndex = ^uint32(chunkIndex)
+								}
 							}
 						}
 
@@ -844,25 +1057,43 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 		}
 
 		repr.exportsToOtherChunks = make(map[js_ast.Ref]string)
-		switch c.options.OutputFormat {
-		case config.FormatESModule:
-			r := renamer.ExportRenamer{}
-			var items []js_ast.ClauseItem
-			for _, export := range c.sortedCrossChunkExportItems(chunkMetas[chunkIndex].exports) {
-				var alias string
-				if c.options.MinifyIdentifiers {
-					alias = r.NextMinifiedName()
-				} else {
-					alias = r.NextRenamedName(c.symbols.Get(export.ref).OriginalName)
-				}
-				items = append(items, js_ast.ClauseItem{Name: js_ast.LocRef{Ref: export.ref}, Alias: alias})
-				repr.exportsToOtherChunks[export.ref] = alias
-			}
-			if len(items) > 0 {
-				repr.crossChunkSuffixStmts = []js_ast.Stmt{{Data: &js_ast.SExportClause{
-					Items: items,
-				}}}
+		r := renamer.ExportRenamer{}
+		var items []js_ast.ClauseItem
+		for _, export := range c.sortedCrossChunkExportItems(chunkMetas[chunkIndex].exports) {
+			var alias string
+			if c.options.MinifyIdentifiers {
+				alias = r.NextMinifiedName()
+			} else {
+				alias = r.NextRenamedName(c.symbols.Get(export.ref).OriginalName)
 			}
+			items = append(items, js_ast.ClauseItem{Name: js_ast.LocRef{Ref: export.ref}, Alias: alias})
+			repr.exportsToOtherChunks[export.ref] = alias
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		switch c.options.OutputFormat {
+		case config.FormatESModule, config.FormatSystem:
+			// "export {a, b}"
+			repr.crossChunkSuffixStmts = []js_ast.Stmt{{Data: &js_ast.SExportClause{
+				Items: items,
+			}}}
+
+		case config.FormatCommonJS:
+			// "module.exports = {get a() { return a; }}"
+			repr.crossChunkSuffixStmts = []js_ast.Stmt{js_ast.AssignStmt(
+				js_ast.Expr{Data: &js_ast.EDot{
+					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.unboundModuleRef}},
+					Name:   "exports",
+				}},
+				crossChunkExportsObject(items),
+			)}
+
+		case config.FormatIIFE:
+			// "return {get a() { return a; }}"
+			value := crossChunkExportsObject(items)
+			repr.crossChunkSuffixStmts = []js_ast.Stmt{{Data: &js_ast.SReturn{Value: &value}}}
 
 		default:
 			panic("Internal error")
@@ -881,10 +1112,12 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 
 		var crossChunkImports []uint32
 		var crossChunkPrefixStmts []js_ast.Stmt
+		var crossChunkNamespaceRefs []js_ast.Ref
+		var crossChunkImportAliases map[js_ast.Ref]js_ast.NamespaceAlias
 
 		for _, crossChunkImport := range c.sortedCrossChunkImports(chunks, repr.importsFromOtherChunks) {
 			switch c.options.OutputFormat {
-			case config.FormatESModule:
+			case config.FormatESModule, config.FormatSystem:
 				var items []js_ast.ClauseItem
 				for _, item := range crossChunkImport.sortedImportItems {
 					items = append(items, js_ast.ClauseItem{Name: js_ast.LocRef{Ref: item.ref}, Alias: item.exportAlias})
@@ -904,6 +1137,42 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 					}})
 				}
 
+			case config.FormatCommonJS, config.FormatIIFE:
+				// Each imported symbol becomes a property access on the exports of
+				// the other chunk so that it's a live binding
+				namespaceRef := c.generateCrossChunkNamespaceSymbol()
+				for _, item := range crossChunkImport.sortedImportItems {
+					if crossChunkImportAliases == nil {
+						crossChunkImportAliases = make(map[js_ast.Ref]js_ast.NamespaceAlias)
+					}
+					crossChunkImportAliases[js_ast.FollowSymbols(c.symbols, item.ref)] = js_ast.NamespaceAlias{
+						NamespaceRef: namespaceRef,
+						Alias:        item.exportAlias,
+					}
+				}
+				importRecordIndex := uint32(len(crossChunkImports))
+				crossChunkImports = append(crossChunkImports, crossChunkImport.chunkIndex)
+				crossChunkNamespaceRefs = append(crossChunkNamespaceRefs, namespaceRef)
+
+				// The IIFE format passes the exports of other chunks to the callback
+				// for this chunk instead, so there are no statements to generate
+				if c.options.OutputFormat == config.FormatCommonJS {
+					requireExpr := js_ast.Expr{Data: &js_ast.ERequire{ImportRecordIndex: importRecordIndex}}
+					if len(crossChunkImport.sortedImportItems) > 0 {
+						// "var chunk = require('./chunk.js')"
+						crossChunkPrefixStmts = append(crossChunkPrefixStmts, js_ast.Stmt{Data: &js_ast.SLocal{
+							Kind: js_ast.LocalVar,
+							Decls: []js_ast.Decl{{
+								Binding: js_ast.Binding{Data: &js_ast.BIdentifier{Ref: namespaceRef}},
+								Value:   &requireExpr,
+							}},
+						}})
+					} else {
+						// "require('./chunk.js')"
+						crossChunkPrefixStmts = append(crossChunkPrefixStmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: requireExpr}})
+					}
+				}
+
 			default:
 				panic("Internal error")
 			}
@@ -911,7 +1180,41 @@ func (c *linkerContext) computeCrossChunkDependencies(chunks []chunkInfo) {
 
 		chunk.crossChunkImports = crossChunkImports
 		repr.crossChunkPrefixStmts = crossChunkPrefixStmts
+		repr.crossChunkNamespaceRefs = crossChunkNamespaceRefs
+		repr.crossChunkImportAliases = crossChunkImportAliases
+	}
+}
+
+// This generates an object with a getter for each export so that imports of
+// these exports from other chunks are live bindings
+func crossChunkExportsObject(items []js_ast.ClauseItem) js_ast.Expr {
+	properties := make([]js_ast.Property, 0, len(items))
+	for _, item := range items {
+		body := js_ast.FnBody{Stmts: []js_ast.Stmt{{Data: &js_ast.SReturn{
+			Value: &js_ast.Expr{Data: &js_ast.EIdentifier{Ref: item.Name.Ref}},
+		}}}}
+		properties = append(properties, js_ast.Property{
+			Kind:     js_ast.PropertyGet,
+			IsMethod: true,
+			Key:      js_ast.Expr{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(item.Alias)}},
+			Value:    &js_ast.Expr{Data: &js_ast.EFunction{Fn: js_ast.Fn{Body: body}}},
+		})
 	}
+	return js_ast.Expr{Data: &js_ast.EObject{Properties: properties}}
+}
+
+// The namespace object for an imported chunk is only used by the importing
+// chunk. It isn't declared in any scope, and is renamed along with the other
+// top-level symbols in the importing chunk.
+func (c *linkerContext) generateCrossChunkNamespaceSymbol() js_ast.Ref {
+	runtimeSymbols := &c.symbols.Outer[runtime.SourceIndex]
+	ref := js_ast.Ref{OuterIndex: runtime.SourceIndex, InnerIndex: uint32(len(*runtimeSymbols))}
+	*runtimeSymbols = append(*runtimeSymbols, js_ast.Symbol{
+		Kind:         js_ast.SymbolOther,
+		OriginalName: "chunk",
+		Link:         js_ast.InvalidRef,
+	})
+	return ref
 }
 
 type crossChunkImport struct {
@@ -1076,6 +1379,9 @@ func (c *linkerContext) scanImportsAndExports() {
 							c.entryPoints = append(c.entryPoints, *record.SourceIndex)
 							otherFile.isEntryPoint = true
 						}
+						if c.entryPointNeedsCommonJSExports(otherRepr, true /* isDynamicImportTarget */) {
+							otherRepr.meta.cjsStyleExports = true
+						}
 					} else {
 						// If we're not splitting, then import() is just a require() that
 						// returns a promise, so the imported file must be a CommonJS module
@@ -1119,7 +1425,9 @@ func (c *linkerContext) scanImportsAndExports() {
 		// resulting wrapper won't be invoked by other files.
 		if repr.meta.cjsStyleExports &&
 			(c.options.OutputFormat == config.FormatIIFE ||
-				c.options.OutputFormat == config.FormatESModule) {
+				c.options.OutputFormat == config.FormatUMD ||
+				c.options.OutputFormat == config.FormatESModule ||
+				c.options.OutputFormat == config.FormatSystem) {
 			repr.meta.cjsWrap = true
 		}
 
@@ -1128,9 +1436,11 @@ func (c *linkerContext) scanImportsAndExports() {
 		// cause that file to need to be wrapped. This is because the import
 		// method, whatever it is, will need to invoke the wrapper. Note that
 		// this can include entry points (e.g. an entry point that imports a file
-		// that imports that entry point).
-		for _, record := range repr.ast.ImportRecords {
-			if record.SourceIndex != nil {
+		// that imports that entry point). Code splitting turns "import()" of
+		// another entry point into an import of that entry point's chunk, which
+		// doesn't call the wrapper.
+		for i := range repr.ast.ImportRecords {
+			if record := &repr.ast.ImportRecords[i]; record.SourceIndex != nil && !(c.options.CodeSplitting && c.isExternalDynamicImport(record)) {
 				otherRepr := c.files[*record.SourceIndex].repr.(*reprJS)
 				if otherRepr.meta.cjsStyleExports {
 					otherRepr.meta.cjsWrap = true
@@ -1404,9 +1714,11 @@ func (c *linkerContext) createExportsForFile(sourceIndex uint32) {
 	// ES6 export statement containing all exports. Except don't do that if this
 	// entry point is a CommonJS-style module, since that would generate an ES6
 	// export statement that's not top-level. Instead, we will export the CommonJS
-	// exports as a default export later on.
+	// exports as a default export later on. The SystemJS format converts this
+	// export statement into a call to its "exports" function later on.
 	needsEntryPointES6ExportPart := file.isEntryPoint && !repr.meta.cjsWrap &&
-		c.options.OutputFormat == config.FormatESModule && len(repr.meta.sortedAndFilteredExportAliases) > 0
+		(c.options.OutputFormat == config.FormatESModule || c.options.OutputFormat == config.FormatSystem) &&
+		len(repr.meta.sortedAndFilteredExportAliases) > 0
 
 	// Generate a getter per export
 	properties := []js_ast.Property{}
@@ -1652,8 +1964,8 @@ func (c *linkerContext) createExportsForFile(sourceIndex uint32) {
 				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.ast.WrapperRef}},
 			}}}}
 
-		case config.FormatIIFE:
-			if len(c.options.ModuleName) > 0 {
+		case config.FormatIIFE, config.FormatUMD:
+			if len(c.options.ModuleName) > 0 || c.options.OutputFormat == config.FormatUMD || c.options.CodeSplitting {
 				// "return require_foo();"
 				cjsWrapStmt = js_ast.Stmt{Data: &js_ast.SReturn{Value: &js_ast.Expr{Data: &js_ast.ECall{
 					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.ast.WrapperRef}},
@@ -1678,10 +1990,36 @@ func (c *linkerContext) createExportsForFile(sourceIndex uint32) {
 			)
 
 		case config.FormatESModule:
+			if c.options.HotModuleReplacement && repr.ast.HasES6Exports {
+				// ES6 entry points are only wrapped for hot module replacement, so
+				// their exports are re-exported by name instead of as a default export.
+				// Like exports of CommonJS modules above, these are "dead" bindings.
+				//
+				//   const entry_exports = require_foo();
+				//   const export_foo = entry_exports.foo;
+				//   export {
+				//     export_foo as foo
+				//   };
+				//
+				entryPointExportStmts = append(entryPointExportStmts,
+					c.hmrEntryPointExportStmts(sourceIndex, entryPointExportSymbolUses)...)
+				break
+			}
+
 			// "export default require_foo();"
 			cjsWrapStmt = js_ast.Stmt{Data: &js_ast.SExportDefault{Value: js_ast.ExprOrStmt{Expr: &js_ast.Expr{Data: &js_ast.ECall{
 				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.ast.WrapperRef}},
 			}}}}}
+
+		case config.FormatSystem:
+			// "exports('default', require_foo());"
+			cjsWrapStmt = js_ast.Stmt{Data: &js_ast.SExpr{Value: js_ast.Expr{Data: &js_ast.ECall{
+				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.unboundExportsRef}},
+				Args: []js_ast.Expr{
+					{Data: &js_ast.EString{Value: js_lexer.StringToUTF16("default")}},
+					{Data: &js_ast.ECall{Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.ast.WrapperRef}}}},
+				},
+			}}}}
 		}
 	}
 
@@ -1703,6 +2041,66 @@ func (c *linkerContext) createExportsForFile(sourceIndex uint32) {
 	}
 }
 
+// This calls the wrapper for an ES6 entry point that was wrapped for hot
+// module replacement and re-exports each of its exports
+func (c *linkerContext) hmrEntryPointExportStmts(sourceIndex uint32, symbolUses map[js_ast.Ref]js_ast.SymbolUse) []js_ast.Stmt {
+	repr := c.files[sourceIndex].repr.(*reprJS)
+	kind := js_ast.LocalConst
+	if c.options.AvoidTDZ || c.options.UnsupportedJSFeatures.Has(compat.Const) {
+		kind = js_ast.LocalVar
+	}
+
+	// Temporary variables are stuck on the module scope so they get renamed
+	newTempRef := func(name string) js_ast.Ref {
+		inner := &c.symbols.Outer[sourceIndex]
+		ref := js_ast.Ref{OuterIndex: sourceIndex, InnerIndex: uint32(len(*inner))}
+		*inner = append(*inner, js_ast.Symbol{
+			Kind:         js_ast.SymbolOther,
+			OriginalName: name,
+			Link:         js_ast.InvalidRef,
+		})
+		repr.ast.ModuleScope.Generated = append(repr.ast.ModuleScope.Generated, ref)
+		symbolUses[ref] = js_ast.SymbolUse{CountEstimate: 2}
+		return ref
+	}
+
+	// "const entry_exports = require_foo();"
+	call := js_ast.Expr{Data: &js_ast.ECall{Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.ast.WrapperRef}}}}
+	symbolUses[repr.ast.WrapperRef] = js_ast.SymbolUse{CountEstimate: 1}
+	if len(repr.meta.sortedAndFilteredExportAliases) == 0 {
+		return []js_ast.Stmt{{Data: &js_ast.SExpr{Value: call}}}
+	}
+	exportsRef := newTempRef(c.files[sourceIndex].source.IdentifierName + "_exports")
+	stmts := []js_ast.Stmt{{Data: &js_ast.SLocal{Kind: kind, Decls: []js_ast.Decl{{
+		Binding: js_ast.Binding{Data: &js_ast.BIdentifier{Ref: exportsRef}},
+		Value:   &call,
+	}}}}}
+
+	// "const export_foo = entry_exports.foo;"
+	var items []js_ast.ClauseItem
+	for _, alias := range repr.meta.sortedAndFilteredExportAliases {
+		tempRef := newTempRef("export_" + alias)
+		value := js_ast.Expr{Data: &js_ast.EDot{
+			Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: exportsRef}},
+			Name:   alias,
+		}}
+		if !js_lexer.IsIdentifier(alias) {
+			value = js_ast.Expr{Data: &js_ast.EIndex{
+				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: exportsRef}},
+				Index:  js_ast.Expr{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(alias)}},
+			}}
+		}
+		stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SLocal{Kind: kind, Decls: []js_ast.Decl{{
+			Binding: js_ast.Binding{Data: &js_ast.BIdentifier{Ref: tempRef}},
+			Value:   &value,
+		}}}})
+		items = append(items, js_ast.ClauseItem{Name: js_ast.LocRef{Ref: tempRef}, Alias: alias})
+	}
+
+	// "export { export_foo as foo };"
+	return append(stmts, js_ast.Stmt{Data: &js_ast.SExportClause{Items: items}})
+}
+
 func (c *linkerContext) matchImportsWithExportsForFile(sourceIndex uint32) {
 	file := &c.files[sourceIndex]
 	repr := file.repr.(*reprJS)
@@ -1769,7 +2167,7 @@ func (c *linkerContext) matchImportsWithExportsForFile(sourceIndex uint32) {
 					source := c.files[tracker.sourceIndex].source
 					symbol := c.symbols.Get(tracker.importRef)
 					symbol.ImportItemStatus = js_ast.ImportItemMissing
-					c.log.AddRangeWarning(&source, js_lexer.RangeOfIdentifier(source, namedImport.AliasLoc),
+					c.log.AddRangeWarning(logger.MsgIDImportIsUndefined, &source, js_lexer.RangeOfIdentifier(source, namedImport.AliasLoc),
 						fmt.Sprintf("Import %q will always be undefined", namedImport.Alias))
 				}
 
@@ -1789,7 +2187,7 @@ func (c *linkerContext) matchImportsWithExportsForFile(sourceIndex uint32) {
 					// time, so we emit a warning and rewrite the value to the literal
 					// "undefined" instead of emitting an error.
 					symbol.ImportItemStatus = js_ast.ImportItemMissing
-					c.log.AddRangeWarning(&source, r, fmt.Sprintf("No matching export for import %q", namedImport.Alias))
+					c.log.AddRangeWarning(logger.MsgIDImportIsUndefined, &source, r, fmt.Sprintf("No matching export for import %q", namedImport.Alias))
 				} else {
 					c.addRangeError(source, r, fmt.Sprintf("No matching export for import %q", namedImport.Alias))
 				}
@@ -2019,7 +2417,7 @@ func (c *linkerContext) advanceImportTracker(tracker importTracker) (importTrack
 
 func (c *linkerContext) markPartsReachableFromEntryPoints() {
 	// Allocate bit sets
-	bitCount := uint(len(c.entryPoints))
+	bitCount := c.entryBitCount()
 	for _, sourceIndex := range c.reachableFiles {
 		file := &c.files[sourceIndex]
 		file.entryBits = newBitSet(bitCount)
@@ -2049,7 +2447,7 @@ func (c *linkerContext) markPartsReachableFromEntryPoints() {
 			// of it.
 			if repr.meta.cjsWrap {
 				runtimeRepr := c.files[runtime.SourceIndex].repr.(*reprJS)
-				commonJSRef := runtimeRepr.ast.NamedExports["__commonJS"]
+				commonJSRef := runtimeRepr.ast.NamedExports[c.wrapperRuntimeName()]
 				commonJSParts := runtimeRepr.ast.TopLevelSymbolToParts[commonJSRef]
 
 				// Generate the dummy part
@@ -2091,11 +2489,11 @@ func (c *linkerContext) markPartsReachableFromEntryPoints() {
 // an assignment to an import, which will fail. Make sure these parts end up
 // in the same chunk in these cases.
 func (c *linkerContext) handleCrossChunkAssignments() {
-	if len(c.entryPoints) < 2 {
+	if len(c.entryPoints) < 2 && len(c.options.ManualChunks) == 0 {
 		// No need to do this if there cannot be cross-chunk assignments
 		return
 	}
-	neverReachedEntryBits := newBitSet(uint(len(c.entryPoints)))
+	neverReachedEntryBits := newBitSet(c.entryBitCount())
 
 	for _, sourceIndex := range c.reachableFiles {
 		file := &c.files[sourceIndex]
@@ -2170,7 +2568,7 @@ func (c *linkerContext) includeFile(sourceIndex uint32, entryPointBit uint, dist
 
 			// Don't include the entry point part if we're not the entry point
 			if repr.meta.entryPointExportPartIndex != nil && uint32(partIndex) == *repr.meta.entryPointExportPartIndex &&
-				sourceIndex != c.entryPoints[entryPointBit] {
+				(entryPointBit >= uint(len(c.entryPoints)) || sourceIndex != c.entryPoints[entryPointBit]) {
 				continue
 			}
 
@@ -2366,7 +2764,7 @@ func (c *linkerContext) includePart(sourceIndex uint32, partIndex uint32, entryP
 
 func (c *linkerContext) computeChunks() []chunkInfo {
 	chunks := make(map[string]chunkInfo)
-	neverReachedKey := string(newBitSet(uint(len(c.entryPoints))).entries)
+	neverReachedKey := string(newBitSet(c.entryBitCount()).entries)
 
 	// Compute entry point names
 	for i, entryPoint := range c.entryPoints {
@@ -2406,7 +2804,7 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 
 		// Create a chunk for the entry point here to ensure that the chunk is
 		// always generated even if the resulting file is empty
-		entryBits := newBitSet(uint(len(c.entryPoints)))
+		entryBits := newBitSet(c.entryBitCount())
 		entryBits.setBit(uint(i))
 		chunks[string(entryBits.entries)] = chunkInfo{
 			entryBits:             entryBits,
@@ -2431,9 +2829,23 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 					// Ignore this part if it was never reached
 					continue
 				}
+
+				// Duplicated parts go in the chunk for each of their entry points
+				if partMeta.isDuplicated {
+					for i := range c.entryPoints {
+						if partMeta.entryBits.hasBit(uint(i)) {
+							entryBits := newBitSet(c.entryBitCount())
+							entryBits.setBit(uint(i))
+							chunks[string(entryBits.entries)].filesWithPartsInChunk[uint32(sourceIndex)] = true
+						}
+					}
+					continue
+				}
+
 				chunk, ok := chunks[key]
 				if !ok {
 					chunk.entryBits = partMeta.entryBits
+					chunk.manualChunkName = c.manualChunkName(partMeta.entryBits)
 					chunk.filesWithPartsInChunk = make(map[uint32]bool)
 					chunk.repr = &chunkReprJS{}
 					chunks[key] = chunk
@@ -2450,6 +2862,7 @@ func (c *linkerContext) computeChunks() []chunkInfo {
 			chunk, ok := chunks[key]
 			if !ok {
 				chunk.entryBits = file.entryBits
+				chunk.manualChunkName = c.manualChunkName(file.entryBits)
 				chunk.filesWithPartsInChunk = make(map[uint32]bool)
 				chunk.repr = &chunkReprJS{}
 				chunks[key] = chunk
@@ -2559,12 +2972,12 @@ func (c *linkerContext) chunkFileOrder(chunk *chunkInfo) (js []uint32, jsParts [
 
 			// Make sure the generated call to "__export(exports, ...)" comes first
 			// before anything else in this file
-			if canFileBeSplit && chunk.entryBits.equals(repr.meta.partMeta[repr.meta.nsExportPartIndex].entryBits) {
+			if canFileBeSplit && chunk.hasPart(&repr.meta.partMeta[repr.meta.nsExportPartIndex]) {
 				jsParts = appendOrExtendPartRange(jsParts, sourceIndex, repr.meta.nsExportPartIndex)
 			}
 
 			for partIndex, part := range repr.ast.Parts {
-				isPartInThisChunk := chunk.entryBits.equals(repr.meta.partMeta[partIndex].entryBits)
+				isPartInThisChunk := chunk.hasPart(&repr.meta.partMeta[partIndex])
 
 				// Also traverse any files imported by this part
 				for _, importRecordIndex := range part.ImportRecordIndices {
@@ -2958,6 +3371,17 @@ type compileResultJS struct {
 
 	sourceIndex uint32
 
+	// For the SystemJS format, these are the imports that were removed from
+	// this file's code. They become dependencies of the chunk.
+	systemImports []systemImport
+
+	// For the SystemJS format, these are the top-level function declarations
+	// and variables that were moved out of the "execute" function. See the
+	// comment for "hoistStmtsForSystem".
+	systemFunctions    *js_printer.PrintResult
+	systemFunctionRefs []js_ast.Ref
+	systemVarRefs      []js_ast.Ref
+
 	// This is used during minification to merge local declarations across files
 	startsWithLocal         *js_ast.LocalKind
 	endsWithLocal           *js_ast.LocalKind
@@ -2973,10 +3397,12 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	r renamer.Renamer,
 	waitGroup *sync.WaitGroup,
 	partRange partRange,
-	entryBits bitSet,
+	chunk *chunkInfo,
 	chunkAbsDir string,
 	commonJSRef js_ast.Ref,
 	toModuleRef js_ast.Ref,
+	crossChunkImportAliases map[js_ast.Ref]js_ast.NamespaceAlias,
+	systemExports map[js_ast.Ref][]string,
 	result *compileResultJS,
 ) {
 	file := &c.files[partRange.sourceIndex]
@@ -2988,7 +3414,7 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	// Make sure the generated call to "__export(exports, ...)" comes first
 	// before anything else.
 	if nsExportPartIndex >= partRange.partIndexBegin && nsExportPartIndex < partRange.partIndexEnd &&
-		entryBits.equals(repr.meta.partMeta[nsExportPartIndex].entryBits) {
+		chunk.hasPart(&repr.meta.partMeta[nsExportPartIndex]) {
 		c.convertStmtsForChunk(partRange.sourceIndex, &stmtList, repr.ast.Parts[nsExportPartIndex].Stmts)
 
 		// Move everything to the prefix list
@@ -2999,7 +3425,7 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	// Add all other parts in this chunk
 	for partIndex := partRange.partIndexBegin; partIndex < partRange.partIndexEnd; partIndex++ {
 		part := repr.ast.Parts[partIndex]
-		if !entryBits.equals(repr.meta.partMeta[partIndex].entryBits) {
+		if !chunk.hasPart(&repr.meta.partMeta[partIndex]) {
 			// Skip the part if it's not in this chunk
 			continue
 		}
@@ -3050,19 +3476,31 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		}
 
 		// "__commonJS((exports, module) => { ... })"
-		var value js_ast.Expr
+		var closure js_ast.Expr
 		if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
-			value = js_ast.Expr{Data: &js_ast.ECall{
-				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: commonJSRef}},
-				Args:   []js_ast.Expr{{Data: &js_ast.EFunction{Fn: js_ast.Fn{Args: args, Body: js_ast.FnBody{Stmts: stmts}}}}},
-			}}
+			closure = js_ast.Expr{Data: &js_ast.EFunction{Fn: js_ast.Fn{Args: args, Body: js_ast.FnBody{Stmts: stmts}}}}
 		} else {
-			value = js_ast.Expr{Data: &js_ast.ECall{
-				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: commonJSRef}},
-				Args:   []js_ast.Expr{{Data: &js_ast.EArrow{Args: args, Body: js_ast.FnBody{Stmts: stmts}}}},
-			}}
+			closure = js_ast.Expr{Data: &js_ast.EArrow{Args: args, Body: js_ast.FnBody{Stmts: stmts}}}
+		}
+		callArgs := []js_ast.Expr{closure}
+
+		// "__hmrModule('foo.js', 'HASH', (exports, module) => { ... })"
+		//
+		// The module is identified by its path, which is stable across builds.
+		// The hash is used by the runtime to detect when the code has changed.
+		if c.options.HotModuleReplacement {
+			callArgs = []js_ast.Expr{
+				{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(file.source.PrettyPath)}},
+				{Data: &js_ast.EString{Value: js_lexer.StringToUTF16(hashForFileName([]byte(file.source.Contents)))}},
+				closure,
+			}
 		}
 
+		value := js_ast.Expr{Data: &js_ast.ECall{
+			Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: commonJSRef}},
+			Args:   callArgs,
+		}}
+
 		// "var require_foo = __commonJS((exports, module) => { ... });"
 		stmts = append(stmtList.es6StmtsForCJSWrap, js_ast.Stmt{Data: &js_ast.SLocal{
 			Decls: []js_ast.Decl{{
@@ -3072,6 +3510,16 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		}})
 	}
 
+	// The SystemJS format doesn't have import and export statements
+	var systemImports []systemImport
+	var systemFunctions []js_ast.Stmt
+	var systemVarRefs []js_ast.Ref
+	if c.options.OutputFormat == config.FormatSystem {
+		stmts, systemImports = c.convertStmtsForSystem(repr.ast.ImportRecords, stmts)
+		stmts, systemFunctions, systemVarRefs = hoistStmtsForSystem(stmts)
+		stmtList.entryPointTail, _ = c.convertStmtsForSystem(repr.ast.ImportRecords, stmtList.entryPointTail)
+	}
+
 	// Only generate a source map if needed
 	var sourceForSourceMap *logger.Source
 	var inputSourceMap *sourcemap.SourceMap
@@ -3111,10 +3559,12 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		}
 	}
 
-	// Indent the file if everything is wrapped in an IIFE
+	// Indent the file if everything is wrapped in an IIFE or a UMD factory
 	indent := 0
-	if c.options.OutputFormat == config.FormatIIFE {
+	if c.options.OutputFormat == config.FormatIIFE || c.options.OutputFormat == config.FormatUMD {
 		indent++
+	} else if c.options.OutputFormat == config.FormatSystem {
+		indent += 3
 	}
 
 	// Convert the AST to JavaScript code
@@ -3132,12 +3582,19 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		WrapperRefForSource: func(sourceIndex uint32) js_ast.Ref {
 			return c.files[sourceIndex].repr.(*reprJS).ast.WrapperRef
 		},
+		SystemModuleRef:   c.unboundModuleRef,
+		SystemExports:     systemExports,
+		SystemExportsRef:  c.unboundExportsRef,
+		ChunkLoaderRef:    c.chunkLoaderRef,
+		CrossChunkImports: crossChunkImportAliases,
 	}
 	tree := repr.ast
 	tree.Parts = []js_ast.Part{{Stmts: stmts}}
 	*result = compileResultJS{
-		PrintResult: js_printer.Print(tree, c.symbols, r, printOptions),
-		sourceIndex: partRange.sourceIndex,
+		PrintResult:   js_printer.Print(tree, c.symbols, r, printOptions),
+		sourceIndex:   partRange.sourceIndex,
+		systemImports: systemImports,
+		systemVarRefs: systemVarRefs,
 	}
 	if len(stmts) > 0 {
 		if local, ok := stmts[0].Data.(*js_ast.SLocal); ok {
@@ -3158,14 +3615,36 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		result.entryPointTail = &entryPointTail
 	}
 
+	// Functions moved out of the "execute" function are only indented once
+	if len(systemFunctions) > 0 {
+		tree := repr.ast
+		tree.Parts = []js_ast.Part{{Stmts: systemFunctions}}
+		printOptions.Indent = 1
+		functions := js_printer.Print(tree, c.symbols, r, printOptions)
+		result.systemFunctions = &functions
+		for _, stmt := range systemFunctions {
+			result.systemFunctionRefs = append(result.systemFunctionRefs, stmt.Data.(*js_ast.SFunction).Fn.Name.Ref)
+		}
+	}
+
 	waitGroup.Done()
 }
 
 func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []uint32) renamer.Renamer {
 	// Determine the reserved names (e.g. can't generate the name "if")
-	moduleScopes := make([]*js_ast.Scope, len(filesInOrder))
-	for i, sourceIndex := range filesInOrder {
-		moduleScopes[i] = c.files[sourceIndex].repr.(*reprJS).ast.ModuleScope
+	moduleScopes := make([]*js_ast.Scope, 0, len(filesInOrder)+1)
+	hasRuntime := false
+	for _, sourceIndex := range filesInOrder {
+		moduleScopes = append(moduleScopes, c.files[sourceIndex].repr.(*reprJS).ast.ModuleScope)
+		if sourceIndex == runtime.SourceIndex {
+			hasRuntime = true
+		}
+	}
+
+	// The runtime scope contains generated unbound symbols such as "module",
+	// which must be reserved even if this chunk contains no runtime code
+	if !hasRuntime {
+		moduleScopes = append(moduleScopes, c.files[runtime.SourceIndex].repr.(*reprJS).ast.ModuleScope)
 	}
 	reservedNames := renamer.ComputeReservedNames(moduleScopes, c.symbols)
 
@@ -3199,7 +3678,7 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 			}
 
 			for partIndex, part := range repr.ast.Parts {
-				if !chunk.entryBits.equals(repr.meta.partMeta[partIndex].entryBits) {
+				if !chunk.hasPart(&repr.meta.partMeta[partIndex]) {
 					// Skip the part if it's not in this chunk
 					continue
 				}
@@ -3220,6 +3699,14 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 		// over assigning minified names in order (i.e. "a b c ..."). Even though
 		// it's a very small win, we still do it because it's simple to do and very
 		// cheap to compute.
+		// Also count the namespace objects for other chunks
+		for _, ref := range chunk.repr.(*chunkReprJS).crossChunkNamespaceRefs {
+			r.AccumulateSymbolCount(ref, 1)
+		}
+		for _, alias := range chunk.repr.(*chunkReprJS).crossChunkImportAliases {
+			r.AccumulateSymbolCount(alias.NamespaceRef, 1)
+		}
+
 		minifier := freq.Compile()
 		r.AssignNamesByFrequency(&minifier)
 		return r
@@ -3244,6 +3731,11 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 		r.AddTopLevelSymbol(stable.Ref)
 	}
 
+	// Also rename the namespace objects for other chunks
+	for _, ref := range chunk.repr.(*chunkReprJS).crossChunkNamespaceRefs {
+		r.AddTopLevelSymbol(ref)
+	}
+
 	for _, sourceIndex := range filesInOrder {
 		repr := c.files[sourceIndex].repr.(*reprJS)
 		var scopes []*js_ast.Scope
@@ -3270,7 +3762,7 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 
 		// Rename each top-level symbol declaration in this chunk
 		for partIndex, part := range repr.ast.Parts {
-			if chunk.entryBits.equals(repr.meta.partMeta[partIndex].entryBits) {
+			if chunk.hasPart(&repr.meta.partMeta[partIndex]) {
 				for _, declared := range part.DeclaredSymbols {
 					if declared.IsTopLevel {
 						r.AddTopLevelSymbol(declared.Ref)
@@ -3290,15 +3782,30 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 	return r
 }
 
+// This is the runtime function that wraps the closure for a CommonJS-style
+// module and returns the "require" function for that module
+func (c *linkerContext) wrapperRuntimeName() string {
+	if c.options.HotModuleReplacement {
+		return "__hmrModule"
+	}
+	return "__commonJS"
+}
+
 func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast.ImportRecord) []OutputFile {
 	var results []OutputFile
 	compileResults := make([]compileResultJS, 0, len(chunk.partsInChunkInOrder))
 	runtimeMembers := c.files[runtime.SourceIndex].repr.(*reprJS).ast.ModuleScope.Members
-	commonJSRef := js_ast.FollowSymbols(c.symbols, runtimeMembers["__commonJS"].Ref)
+	commonJSRef := js_ast.FollowSymbols(c.symbols, runtimeMembers[c.wrapperRuntimeName()].Ref)
 	toModuleRef := js_ast.FollowSymbols(c.symbols, runtimeMembers["__toModule"].Ref)
 	r := c.renameSymbolsInChunk(chunk, chunk.filesInChunkInOrder)
 	chunkAbsDir := c.fs.Join(c.options.AbsOutputDir, chunk.relDir)
 
+	// Assignments to exported symbols must update the exports with SystemJS
+	var systemExports map[js_ast.Ref][]string
+	if c.options.OutputFormat == config.FormatSystem {
+		systemExports = c.systemExportsForChunk(chunk)
+	}
+
 	// Generate JavaScript for each file in parallel
 	waitGroup := sync.WaitGroup{}
 	for _, partRange := range chunk.partsInChunkInOrder {
@@ -3315,10 +3822,12 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 			r,
 			&waitGroup,
 			partRange,
-			chunk.entryBits,
+			chunk,
 			chunkAbsDir,
 			commonJSRef,
 			toModuleRef,
+			repr.crossChunkImportAliases,
+			systemExports,
 			compileResult,
 		)
 	}
@@ -3334,11 +3843,14 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 		// Also generate the cross-chunk binding code
 		var crossChunkPrefix []byte
 		var crossChunkSuffix []byte
+		var crossChunkSystemImports []systemImport
 		{
-			// Indent the file if everything is wrapped in an IIFE
+			// Indent the file if everything is wrapped in an IIFE or a UMD factory
 			indent := 0
-			if c.options.OutputFormat == config.FormatIIFE {
+			if c.options.OutputFormat == config.FormatIIFE || c.options.OutputFormat == config.FormatUMD {
 				indent++
+			} else if c.options.OutputFormat == config.FormatSystem {
+				indent += 3
 			}
 			printOptions := js_printer.PrintOptions{
 				Indent:           indent,
@@ -3346,12 +3858,18 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 				RemoveWhitespace: c.options.RemoveWhitespace,
 				MangleSyntax:     c.options.MangleSyntax,
 			}
+			prefixStmts := repr.crossChunkPrefixStmts
+			suffixStmts := repr.crossChunkSuffixStmts
+			if c.options.OutputFormat == config.FormatSystem {
+				prefixStmts, crossChunkSystemImports = c.convertStmtsForSystem(crossChunkImportRecords, prefixStmts)
+				suffixStmts, _ = c.convertStmtsForSystem(nil, suffixStmts)
+			}
 			crossChunkPrefix = js_printer.Print(js_ast.AST{
 				ImportRecords: crossChunkImportRecords,
-				Parts:         []js_ast.Part{{Stmts: repr.crossChunkPrefixStmts}},
+				Parts:         []js_ast.Part{{Stmts: prefixStmts}},
 			}, c.symbols, r, printOptions).JS
 			crossChunkSuffix = js_printer.Print(js_ast.AST{
-				Parts: []js_ast.Part{{Stmts: repr.crossChunkSuffixStmts}},
+				Parts: []js_ast.Part{{Stmts: suffixStmts}},
 			}, c.symbols, r, printOptions).JS
 		}
 
@@ -3392,8 +3910,14 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 			}
 		}
 
-		// Optionally wrap with an IIFE
-		if c.options.OutputFormat == config.FormatIIFE {
+		// Optionally wrap with a call to the chunk loader
+		if c.options.OutputFormat == config.FormatIIFE && c.options.CodeSplitting {
+			indent = "  "
+			text := c.generateChunkLoaderPrefix(r, chunk, repr.crossChunkNamespaceRefs, crossChunkImportRecords)
+			prevOffset.advanceString(text)
+			j.AddString(text)
+			newlineBeforeComment = false
+		} else if c.options.OutputFormat == config.FormatIIFE {
 			var text string
 			indent = "  "
 			if len(c.options.ModuleName) > 0 {
@@ -3409,6 +3933,62 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 			newlineBeforeComment = false
 		}
 
+		// Optionally wrap with a UMD factory
+		if c.options.OutputFormat == config.FormatUMD {
+			indent = "  "
+			text := c.generateUMDPrefix(chunk)
+			prevOffset.advanceString(text)
+			j.AddString(text)
+			newlineBeforeComment = false
+		}
+
+		// Optionally wrap with a call to "System.register()"
+		var compileResultsForSourceMap []compileResultJS
+		if c.options.OutputFormat == config.FormatSystem {
+			indent = "      "
+			systemImports := crossChunkSystemImports
+			var functionRefs []js_ast.Ref
+			var varRefs []js_ast.Ref
+			for _, compileResult := range compileResults {
+				systemImports = append(systemImports, compileResult.systemImports...)
+				functionRefs = append(functionRefs, compileResult.systemFunctionRefs...)
+				varRefs = append(varRefs, compileResult.systemVarRefs...)
+			}
+			text, textAfterFunctions := c.generateSystemPrefix(r, systemImports, functionRefs, varRefs, systemExports)
+			prevOffset.advanceString(text)
+			j.AddString(text)
+
+			// Functions moved out of the "execute" function go in between
+			for _, compileResult := range compileResults {
+				functions := compileResult.systemFunctions
+				if functions == nil {
+					continue
+				}
+				if c.options.Mode == config.ModeBundle && !c.options.RemoveWhitespace {
+					text := fmt.Sprintf("  // %s\n", c.files[compileResult.sourceIndex].source.PrettyPath)
+					prevOffset.advanceString(text)
+					j.AddString(text)
+				}
+				if compileResult.sourceIndex == runtime.SourceIndex || functions.SourceMapChunk.ShouldIgnore {
+					prevOffset.advanceBytes(functions.JS)
+				} else {
+					if c.options.SourceMap != config.SourceMapNone {
+						compileResultsForSourceMap = append(compileResultsForSourceMap, compileResultJS{
+							PrintResult:     *functions,
+							sourceIndex:     compileResult.sourceIndex,
+							generatedOffset: prevOffset,
+						})
+					}
+					prevOffset = lineColumnOffset{}
+				}
+				j.AddBytes(functions.JS)
+			}
+
+			prevOffset.advanceString(textAfterFunctions)
+			j.AddString(textAfterFunctions)
+			newlineBeforeComment = false
+		}
+
 		// Put the cross-chunk prefix inside the IIFE
 		if len(crossChunkPrefix) > 0 {
 			newlineBeforeComment = true
@@ -3434,12 +4014,13 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 			if !isFirstMeta {
 				jMeta.AddString("\n      ")
 			}
-			jMeta.AddString("],\n      \"inputs\": {")
+			jMeta.AddString("],\n      ")
+			c.generateChunkMergeMetadata(&jMeta, chunk)
+			jMeta.AddString("\"inputs\": {")
 		}
 		isFirstMeta := true
 
 		// Concatenate the generated JavaScript chunks together
-		var compileResultsForSourceMap []compileResultJS
 		var entryPointTail *js_printer.PrintResult
 		var commentList []string
 		commentSet := make(map[string]bool)
@@ -3535,9 +4116,13 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 					} else {
 						jMeta.AddString(",")
 					}
+					bytesInOutput := len(js)
+					if compileResult.systemFunctions != nil {
+						bytesInOutput += len(compileResult.systemFunctions.JS)
+					}
 					jMeta.AddString(fmt.Sprintf("\n        %s: {\n          \"bytesInOutput\": %d\n        }",
 						js_printer.QuoteForJSON(c.files[compileResult.sourceIndex].source.PrettyPath, c.options.ASCIIOnly),
-						len(js)))
+						bytesInOutput))
 				}
 			}
 
@@ -3564,7 +4149,25 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 
 		// Optionally wrap with an IIFE
 		if c.options.OutputFormat == config.FormatIIFE {
-			j.AddString("})();" + newline)
+			if c.options.CodeSplitting {
+				j.AddString("});" + newline)
+			} else {
+				j.AddString("})();" + newline)
+			}
+		}
+
+		// Optionally wrap with a UMD factory
+		if c.options.OutputFormat == config.FormatUMD {
+			j.AddString("});" + newline)
+		}
+
+		// Optionally wrap with a call to "System.register()"
+		if c.options.OutputFormat == config.FormatSystem {
+			if c.options.RemoveWhitespace {
+				j.AddString("}}});")
+			} else {
+				j.AddString("    }\n  };\n});\n")
+			}
 		}
 
 		// Make sure the file ends with a newline
@@ -3604,7 +4207,7 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 				var sourceMapBaseName string
 				if chunk.baseNameOrEmpty == "" {
 					hash := hashForFileName(sourceMap)
-					sourceMapBaseName = "chunk." + hash + c.options.OutputExtensionFor(".js") + ".map"
+					sourceMapBaseName = chunk.hashedBaseName(hash, c.options.OutputExtensionFor(".js")) + ".map"
 				} else {
 					sourceMapBaseName = chunk.baseNameOrEmpty + ".map"
 				}
@@ -3630,7 +4233,7 @@ func (repr *chunkReprJS) generate(c *linkerContext, chunk *chunkInfo) func([]ast
 		// Figure out the base name for this chunk now that the content hash is known
 		if chunk.baseNameOrEmpty == "" {
 			hash := hashForFileName(jsContents)
-			chunk.baseNameOrEmpty = "chunk." + hash + c.options.OutputExtensionFor(".js")
+			chunk.baseNameOrEmpty = chunk.hashedBaseName(hash, c.options.OutputExtensionFor(".js"))
 		}
 
 		// End the metadata
@@ -3690,6 +4293,645 @@ func generateModuleNamePrefix(options *config.Options) string {
 	return text
 }
 
+type umdExternal struct {
+	path   string
+	source *logger.Source
+	r      logger.Range
+}
+
+// This returns the external import paths used by the code in this chunk in
+// the order they are first used. These are the dependencies of the UMD factory.
+func (c *linkerContext) umdExternalsInChunk(chunk *chunkInfo) []umdExternal {
+	var externals []umdExternal
+	visited := make(map[string]bool)
+
+	for _, partRange := range chunk.partsInChunkInOrder {
+		file := &c.files[partRange.sourceIndex]
+		repr := file.repr.(*reprJS)
+		for partIndex := partRange.partIndexBegin; partIndex < partRange.partIndexEnd; partIndex++ {
+			for _, importRecordIndex := range repr.ast.Parts[partIndex].ImportRecordIndices {
+				record := &repr.ast.ImportRecords[importRecordIndex]
+				if record.SourceIndex != nil || record.IsUnused || visited[record.Path.Text] {
+					continue
+				}
+				visited[record.Path.Text] = true
+				externals = append(externals, umdExternal{path: record.Path.Text, source: &file.source, r: record.Range})
+			}
+		}
+	}
+
+	return externals
+}
+
+// This generates the code that comes before the bundled code in the UMD
+// format. It calls the factory function with a "require" function that is
+// appropriate for the environment. See the comment for "FormatUMD".
+func (c *linkerContext) generateUMDPrefix(chunk *chunkInfo) string {
+	options := c.options
+	space := " "
+	newline := "\n"
+	indent := "  "
+	if options.RemoveWhitespace {
+		space = ""
+		newline = ""
+		indent = ""
+	}
+	fn := func(args string) string {
+		if options.UnsupportedJSFeatures.Has(compat.Arrow) {
+			return "function(" + args + ")" + space
+		}
+		return "(" + args + ")" + space + "=>" + space
+	}
+	externals := c.umdExternalsInChunk(chunk)
+
+	// Use the AMD loader if there is one. AMD's local "require" function can
+	// synchronously return any module listed as a dependency.
+	text := "(" + fn("root,"+space+"factory") + "{" + newline
+	text += indent + "if" + space + "(typeof define" + space + "===" + space + "\"function\"" + space + "&&" + space + "define.amd)" + space + "{" + newline
+	text += indent + indent + "define([\"require\""
+	for _, external := range externals {
+		text += "," + space + string(js_printer.QuoteForJSON(external.path, options.ASCIIOnly))
+	}
+	text += "]," + space + "factory);" + newline
+
+	// Otherwise use CommonJS if it's available
+	text += indent + "}" + space + "else if" + space + "(typeof module" + space + "===" + space + "\"object\"" + space + "&&" + space + "module.exports)" + space + "{" + newline
+	text += indent + indent + "module.exports" + space + "=" + space + "factory(require);" + newline
+
+	// Otherwise fall back to global variables
+	text += indent + "}" + space + "else" + space + "{" + newline
+	call := "factory()"
+	if len(externals) > 0 {
+		globals := "{"
+		for i, external := range externals {
+			if i > 0 {
+				globals += "," + space
+			}
+			globals += string(js_printer.QuoteForJSON(external.path, options.ASCIIOnly)) + ":" + space
+			if global, ok := options.UMDGlobals[external.path]; ok {
+				globals += umdGlobalAccess("root", global, options)
+			} else {
+				globals += "void 0"
+				c.log.AddRangeWarning(logger.MsgIDMissingGlobalName, external.source, external.r, fmt.Sprintf(
+					"No global variable name was configured for the external import %q, so it will be undefined when there is no module system",
+					external.path))
+			}
+		}
+		globals += "}"
+		if options.UnsupportedJSFeatures.Has(compat.Arrow) {
+			call = "factory(function(id)" + space + "{" + space + "return " + globals + "[id];" + space + "})"
+		} else {
+			call = "factory((id)" + space + "=>" + space + "(" + globals + ")[id])"
+		}
+	}
+	if len(options.ModuleName) > 0 {
+		for i := 1; i < len(options.ModuleName); i++ {
+			prefix := umdGlobalAccess("root", options.ModuleName[:i], options)
+			text += indent + indent + prefix + space + "=" + space + prefix + space + "||" + space + "{};" + newline
+		}
+		call = umdGlobalAccess("root", options.ModuleName, options) + space + "=" + space + call
+	}
+	text += indent + indent + call + ";" + newline
+	text += indent + "}" + newline
+
+	// The global object is "self" in browsers and web workers and "this" in
+	// older environments without "self"
+	text += "})(typeof self" + space + "!==" + space + "\"undefined\"" + space + "?" + space + "self" + space + ":" + space + "this," +
+		space + fn("require") + "{" + newline
+	return text
+}
+
+// This generates "root.a.b" for the names "a" and "b", using bracket syntax
+// for names that aren't valid identifiers
+func umdGlobalAccess(root string, names []string, options *config.Options) string {
+	text := root
+	for _, name := range names {
+		if js_printer.CanQuoteIdentifier(name, options) {
+			if options.ASCIIOnly {
+				name = string(js_printer.QuoteIdentifier(nil, name, options.UnsupportedJSFeatures))
+			}
+			text += "." + name
+		} else {
+			text += "[" + string(js_printer.QuoteForJSON(name, options.ASCIIOnly)) + "]"
+		}
+	}
+	return text
+}
+
+// This generates the code that comes before the bundled code in each chunk
+// with the IIFE format when code splitting is enabled. The bundled code goes
+// inside a callback that is registered with the chunk loader from the runtime.
+// Entry point chunks define the chunk loader first since they are loaded
+// before any other chunks. Other chunks only contain the call to "register":
+//
+//	self.esbuildChunks.register(["./chunk.js"], function(__import, chunk) {
+//	  ...
+//	});
+func (c *linkerContext) generateChunkLoaderPrefix(r renamer.Renamer, chunk *chunkInfo, namespaceRefs []js_ast.Ref, importRecords []ast.ImportRecord) string {
+	space := " "
+	newline := "\n"
+	if c.options.RemoveWhitespace {
+		space = ""
+		newline = ""
+	}
+	text := ""
+	if chunk.isEntryPoint {
+		text = string(c.chunkLoaderJS)
+	}
+	text += "self.esbuildChunks.register(["
+
+	// The paths of the other chunks, resolved relative to this chunk
+	for i, record := range importRecords {
+		if i > 0 {
+			text += "," + space
+		}
+		text += string(js_printer.QuoteForJSON(record.Path.Text, c.options.ASCIIOnly))
+	}
+
+	// The callback is passed the chunk loader and the exports of each chunk
+	text += "]," + space + "function(" + r.NameForSymbol(c.chunkLoaderRef)
+	for _, ref := range namespaceRefs {
+		text += "," + space + r.NameForSymbol(ref)
+	}
+	return text + ")" + space + "{" + newline
+}
+
+// This prints the chunk loader from the runtime. It's repeated in every entry
+// point chunk, so it's worth minifying the names in it too.
+func (c *linkerContext) printChunkLoader() []byte {
+	tree, ok := js_parser.Parse(logger.NewDeferLog(), runtime.ChunkLoaderSource, config.Options{
+		MangleSyntax:      c.options.MangleSyntax,
+		MinifyIdentifiers: c.options.MinifyIdentifiers,
+	})
+	if !ok {
+		panic("Internal error")
+	}
+	symbols := js_ast.NewSymbolMap(1)
+	symbols.Outer[0] = tree.Symbols
+	var loaderRenamer renamer.Renamer = renamer.NewNoOpRenamer(symbols)
+	if c.options.MinifyIdentifiers {
+		reservedNames := renamer.ComputeReservedNames([]*js_ast.Scope{tree.ModuleScope}, symbols)
+		minifyRenamer := renamer.NewMinifyRenamer(symbols, tree.NestedScopeSlotCounts, reservedNames)
+		for _, part := range tree.Parts {
+			minifyRenamer.AccumulateSymbolUseCounts(part.SymbolUses, []uint32{0})
+			for _, declared := range part.DeclaredSymbols {
+				minifyRenamer.AccumulateSymbolCount(declared.Ref, 1)
+			}
+		}
+		freq := js_ast.CharFreq{}
+		minifier := freq.Compile()
+		minifyRenamer.AssignNamesByFrequency(&minifier)
+		loaderRenamer = minifyRenamer
+	}
+	return js_printer.Print(tree, symbols, loaderRenamer, js_printer.PrintOptions{
+		RemoveWhitespace: c.options.RemoveWhitespace,
+		MangleSyntax:     c.options.MangleSyntax,
+	}).JS
+}
+
+// An import that becomes a dependency of a SystemJS module. The imported
+// bindings are assigned by the setter function for that dependency.
+type systemImport struct {
+	path       string
+	bindings   []systemBinding
+	exportStar bool
+}
+
+type systemBinding struct {
+	ref   js_ast.Ref
+	alias string // This is empty for the namespace object
+}
+
+// The SystemJS format doesn't have import and export statements. This removes
+// import statements and "export * from" statements, which are returned as
+// dependencies instead, and turns export clauses into calls to "exports()".
+// That only sets the initial values of the exports. Later assignments to
+// exported symbols call "exports()" again when they are printed, which keeps
+// the exports live bindings like they are with ES6 modules.
+func (c *linkerContext) convertStmtsForSystem(importRecords []ast.ImportRecord, stmts []js_ast.Stmt) ([]js_ast.Stmt, []systemImport) {
+	var result []js_ast.Stmt
+	var imports []systemImport
+
+	for _, stmt := range stmts {
+		switch s := stmt.Data.(type) {
+		case *js_ast.SImport:
+			// "import a, {b as c} from 'path'" => "a = m.default; c = m.b;"
+			// "import * as ns from 'path'" => "ns = m;"
+			imp := systemImport{path: importRecords[s.ImportRecordIndex].Path.Text}
+			if s.DefaultName != nil {
+				imp.bindings = append(imp.bindings, systemBinding{ref: s.DefaultName.Ref, alias: "default"})
+			}
+			if s.StarNameLoc != nil {
+				imp.bindings = append(imp.bindings, systemBinding{ref: s.NamespaceRef})
+			}
+			if s.Items != nil {
+				for _, item := range *s.Items {
+					imp.bindings = append(imp.bindings, systemBinding{ref: item.Name.Ref, alias: item.Alias})
+				}
+			}
+			imports = append(imports, imp)
+			continue
+
+		case *js_ast.SExportStar:
+			// "export * from 'path'"
+			if s.Alias == nil {
+				imports = append(imports, systemImport{path: importRecords[s.ImportRecordIndex].Path.Text, exportStar: true})
+				continue
+			}
+
+		case *js_ast.SExportClause:
+			// "export {a as b}" => "exports({b: a});"
+			properties := make([]js_ast.Property, 0, len(s.Items))
+			for _, item := range s.Items {
+				properties = append(properties, js_ast.Property{
+					Key:   js_ast.Expr{Loc: item.AliasLoc, Data: &js_ast.EString{Value: js_lexer.StringToUTF16(item.Alias)}},
+					Value: &js_ast.Expr{Loc: item.Name.Loc, Data: &js_ast.EIdentifier{Ref: item.Name.Ref}},
+				})
+			}
+			stmt = js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.ECall{
+				Target: js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EIdentifier{Ref: c.unboundExportsRef}},
+				Args:   []js_ast.Expr{{Loc: stmt.Loc, Data: &js_ast.EObject{Properties: properties}}},
+			}}}}
+		}
+
+		result = append(result, stmt)
+	}
+
+	return result, imports
+}
+
+// With the SystemJS format, modules in an import cycle may call each other's
+// functions before their "execute" functions have run, just like with ES6
+// modules. To allow this, top-level function declarations are moved out of
+// "execute" and into the body of the "System.register" callback where they
+// can be exported right away. Every other top-level variable is declared
+// there too using "var" so that the moved functions can still reference it,
+// and its declaration inside "execute" becomes an assignment instead.
+func hoistStmtsForSystem(stmts []js_ast.Stmt) (execute []js_ast.Stmt, functions []js_ast.Stmt, vars []js_ast.Ref) {
+	for _, stmt := range stmts {
+		switch s := stmt.Data.(type) {
+		case *js_ast.SFunction:
+			functions = append(functions, stmt)
+			continue
+
+		case *js_ast.SClass:
+			// "class Foo {}" => "Foo = class Foo {}"
+			vars = append(vars, s.Class.Name.Ref)
+			stmt = js_ast.AssignStmt(
+				js_ast.Expr{Loc: s.Class.Name.Loc, Data: &js_ast.EIdentifier{Ref: s.Class.Name.Ref}},
+				js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EClass{Class: s.Class}},
+			)
+
+		case *js_ast.SLocal:
+			// "let a = 1, {b} = c, d;" => "a = 1, {b} = c;"
+			value, ok := convertLocalToAssignForSystem(s, &vars)
+			if !ok {
+				continue
+			}
+			stmt = js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: value}}
+
+		default:
+			stmt = hoistNestedVarsForSystem(stmt, &vars)
+		}
+
+		execute = append(execute, stmt)
+	}
+
+	return
+}
+
+// This returns false if none of the declarations have a value
+func convertLocalToAssignForSystem(s *js_ast.SLocal, vars *[]js_ast.Ref) (js_ast.Expr, bool) {
+	var value js_ast.Expr
+	hasValue := false
+	for _, decl := range s.Decls {
+		collectBindingRefsForSystem(decl.Binding, vars)
+		if decl.Value != nil {
+			assign := js_ast.Assign(js_ast.ConvertBindingToExpr(decl.Binding, nil), *decl.Value)
+			if hasValue {
+				value = js_ast.JoinWithComma(value, assign)
+			} else {
+				value = assign
+				hasValue = true
+			}
+		}
+	}
+	return value, hasValue
+}
+
+func collectBindingRefsForSystem(binding js_ast.Binding, vars *[]js_ast.Ref) {
+	switch b := binding.Data.(type) {
+	case *js_ast.BIdentifier:
+		*vars = append(*vars, b.Ref)
+
+	case *js_ast.BArray:
+		for _, item := range b.Items {
+			collectBindingRefsForSystem(item.Binding, vars)
+		}
+
+	case *js_ast.BObject:
+		for _, property := range b.Properties {
+			collectBindingRefsForSystem(property.Value, vars)
+		}
+	}
+}
+
+// A "var" declaration inside a nested block declares a variable in the whole
+// "execute" function, so it must also be turned into an assignment. The AST
+// is shared with other builds, so statements are copied instead of modified.
+func hoistNestedVarsForSystem(stmt js_ast.Stmt, vars *[]js_ast.Ref) js_ast.Stmt {
+	hoistStmts := func(stmts []js_ast.Stmt) []js_ast.Stmt {
+		result := make([]js_ast.Stmt, 0, len(stmts))
+		for _, stmt := range stmts {
+			stmt = hoistNestedVarsForSystem(stmt, vars)
+			if _, ok := stmt.Data.(*js_ast.SEmpty); !ok {
+				result = append(result, stmt)
+			}
+		}
+		return result
+	}
+
+	// "for (var x of y)" => "for (x of y)"
+	hoistLoopInit := func(init js_ast.Stmt) js_ast.Stmt {
+		if local, ok := init.Data.(*js_ast.SLocal); ok && local.Kind == js_ast.LocalVar && len(local.Decls) == 1 && local.Decls[0].Value == nil {
+			collectBindingRefsForSystem(local.Decls[0].Binding, vars)
+			return js_ast.Stmt{Loc: init.Loc, Data: &js_ast.SExpr{Value: js_ast.ConvertBindingToExpr(local.Decls[0].Binding, nil)}}
+		}
+		return hoistNestedVarsForSystem(init, vars)
+	}
+
+	switch s := stmt.Data.(type) {
+	case *js_ast.SLocal:
+		if s.Kind == js_ast.LocalVar {
+			if value, ok := convertLocalToAssignForSystem(s, vars); ok {
+				return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: value}}
+			}
+			return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SEmpty{}}
+		}
+
+	case *js_ast.SBlock:
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SBlock{Stmts: hoistStmts(s.Stmts)}}
+
+	case *js_ast.SIf:
+		clone := *s
+		clone.Yes = hoistNestedVarsForSystem(s.Yes, vars)
+		if s.No != nil {
+			no := hoistNestedVarsForSystem(*s.No, vars)
+			clone.No = &no
+		}
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+
+	case *js_ast.SFor:
+		clone := *s
+		if s.Init != nil {
+			if local, ok := s.Init.Data.(*js_ast.SLocal); ok && local.Kind == js_ast.LocalVar {
+				// "for (var i = 0; ...)" => "for (i = 0; ...)"
+				if value, ok := convertLocalToAssignForSystem(local, vars); ok {
+					clone.Init = &js_ast.Stmt{Loc: s.Init.Loc, Data: &js_ast.SExpr{Value: value}}
+				} else {
+					clone.Init = nil
+				}
+			}
+		}
+		clone.Body = hoistNestedVarsForSystem(s.Body, vars)
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+
+	case *js_ast.SForIn:
+		clone := *s
+		clone.Init = hoistLoopInit(s.Init)
+		clone.Body = hoistNestedVarsForSystem(s.Body, vars)
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+
+	case *js_ast.SForOf:
+		clone := *s
+		clone.Init = hoistLoopInit(s.Init)
+		clone.Body = hoistNestedVarsForSystem(s.Body, vars)
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+
+	case *js_ast.SWhile:
+		clone := *s
+		clone.Body = hoistNestedVarsForSystem(s.Body, vars)
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+
+	case *js_ast.SDoWhile:
+		clone := *s
+		clone.Body = hoistNestedVarsForSystem(s.Body, vars)
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+
+	case *js_ast.SWith:
+		clone := *s
+		clone.Body = hoistNestedVarsForSystem(s.Body, vars)
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+
+	case *js_ast.SLabel:
+		clone := *s
+		clone.Stmt = hoistNestedVarsForSystem(s.Stmt, vars)
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+
+	case *js_ast.STry:
+		clone := *s
+		clone.Body = hoistStmts(s.Body)
+		if s.Catch != nil {
+			catch := *s.Catch
+			catch.Body = hoistStmts(s.Catch.Body)
+			clone.Catch = &catch
+		}
+		if s.Finally != nil {
+			finally := *s.Finally
+			finally.Stmts = hoistStmts(s.Finally.Stmts)
+			clone.Finally = &finally
+		}
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+
+	case *js_ast.SSwitch:
+		clone := *s
+		clone.Cases = make([]js_ast.Case, len(s.Cases))
+		for i, c := range s.Cases {
+			clone.Cases[i] = js_ast.Case{Value: c.Value, Body: hoistStmts(c.Body)}
+		}
+		return js_ast.Stmt{Loc: stmt.Loc, Data: &clone}
+	}
+
+	return stmt
+}
+
+// This returns the names that each symbol in this chunk is exported as with
+// the SystemJS format, both from the entry point and to other chunks
+func (c *linkerContext) systemExportsForChunk(chunk *chunkInfo) map[js_ast.Ref][]string {
+	exports := make(map[js_ast.Ref][]string)
+	add := func(items []js_ast.ClauseItem) {
+		for _, item := range items {
+			ref := js_ast.FollowSymbols(c.symbols, item.Name.Ref)
+			exports[ref] = append(exports[ref], item.Alias)
+		}
+	}
+
+	repr := chunk.repr.(*chunkReprJS)
+	for _, stmt := range repr.crossChunkSuffixStmts {
+		if s, ok := stmt.Data.(*js_ast.SExportClause); ok {
+			add(s.Items)
+		}
+	}
+
+	if chunk.isEntryPoint {
+		fileRepr := c.files[chunk.sourceIndex].repr.(*reprJS)
+		if partIndex := fileRepr.meta.entryPointExportPartIndex; partIndex != nil {
+			for _, stmt := range fileRepr.ast.Parts[*partIndex].Stmts {
+				if s, ok := stmt.Data.(*js_ast.SExportClause); ok {
+					add(s.Items)
+				}
+			}
+		}
+	}
+
+	return exports
+}
+
+// This generates the code that comes before the bundled code in the SystemJS
+// format. Imports of the same path are merged into a single dependency. Imported
+// symbols that this chunk exports again are passed on to "exports()" whenever
+// their setter is called. The function declarations that were moved out of the
+// "execute" function go in between the two returned strings, and the second
+// string starts by exporting them.
+func (c *linkerContext) generateSystemPrefix(
+	r renamer.Renamer,
+	imports []systemImport,
+	functionRefs []js_ast.Ref,
+	varRefs []js_ast.Ref,
+	exports map[js_ast.Ref][]string,
+) (string, string) {
+	space := " "
+	newline := "\n"
+	indent := "  "
+	if c.options.RemoveWhitespace {
+		space = ""
+		newline = ""
+		indent = ""
+	}
+	exportsName := r.NameForSymbol(c.unboundExportsRef)
+	moduleName := r.NameForSymbol(c.unboundModuleRef)
+
+	// Merge imports with the same path
+	var merged []systemImport
+	pathToIndex := make(map[string]int)
+	for _, imp := range imports {
+		if i, ok := pathToIndex[imp.path]; ok {
+			merged[i].bindings = append(merged[i].bindings, imp.bindings...)
+			merged[i].exportStar = merged[i].exportStar || imp.exportStar
+		} else {
+			pathToIndex[imp.path] = len(merged)
+			merged = append(merged, imp)
+		}
+	}
+
+	// "System.register(['./chunk.js'], function(exports, module) {"
+	text := "System.register(["
+	for i, imp := range merged {
+		if i > 0 {
+			text += "," + space
+		}
+		text += string(js_printer.QuoteForJSON(imp.path, c.options.ASCIIOnly))
+	}
+	text += "]," + space + "function(" + exportsName + "," + space + moduleName + ")" + space + "{" + newline
+
+	// Declare a variable for each imported binding and each top-level variable
+	// that was moved out of the "execute" function
+	var names []string
+	declared := make(map[string]bool)
+	declare := func(ref js_ast.Ref) {
+		if name := r.NameForSymbol(ref); !declared[name] {
+			declared[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, imp := range merged {
+		for _, binding := range imp.bindings {
+			declare(binding.ref)
+		}
+	}
+	for _, ref := range varRefs {
+		declare(ref)
+	}
+	if len(names) > 0 {
+		text += indent + "var " + strings.Join(names, ","+space) + ";" + newline
+	}
+
+	// Functions are exported before anything runs, since other modules in an
+	// import cycle may call them before this module's "execute" function runs.
+	// "exports('f', f);"
+	after := ""
+	for _, ref := range functionRefs {
+		for _, alias := range exports[js_ast.FollowSymbols(c.symbols, ref)] {
+			after += indent + exportsName + "(" + string(js_printer.QuoteForJSON(alias, c.options.ASCIIOnly)) +
+				"," + space + r.NameForSymbol(ref) + ");" + newline
+		}
+	}
+
+	// Each dependency has a setter that is called with its exports
+	after += indent + "return" + space + "{" + newline
+	after += indent + indent + "setters:" + space + "["
+	for i, imp := range merged {
+		if i > 0 {
+			after += "," + space
+		}
+
+		// Avoid collisions with the names used inside the setter
+		used := map[string]bool{exportsName: true}
+		for name := range declared {
+			used[name] = true
+		}
+		for _, binding := range imp.bindings {
+			used[r.NameForSymbol(binding.ref)] = true
+		}
+		m := systemUnusedName("m", used)
+		used[m] = true
+
+		if len(imp.bindings) == 0 && !imp.exportStar {
+			after += "function()" + space + "{}"
+			continue
+		}
+		after += "function(" + m + ")" + space + "{" + newline
+		for _, binding := range imp.bindings {
+			after += indent + indent + indent + r.NameForSymbol(binding.ref) + space + "=" + space + m
+			if binding.alias != "" {
+				if js_lexer.IsIdentifier(binding.alias) {
+					after += "." + binding.alias
+				} else {
+					after += "[" + string(js_printer.QuoteForJSON(binding.alias, c.options.ASCIIOnly)) + "]"
+				}
+			}
+			after += ";" + newline
+
+			// "exports('b', b);"
+			for _, alias := range exports[js_ast.FollowSymbols(c.symbols, binding.ref)] {
+				after += indent + indent + indent + exportsName + "(" + string(js_printer.QuoteForJSON(alias, c.options.ASCIIOnly)) +
+					"," + space + r.NameForSymbol(binding.ref) + ");" + newline
+			}
+		}
+		if imp.exportStar {
+			// "for (var k in m) if (k !== 'default') exports(k, m[k]);"
+			k := systemUnusedName("k", used)
+			after += indent + indent + indent + "for" + space + "(var " + k + " in " + m + ")" + space +
+				"if" + space + "(" + k + space + "!==" + space + "\"default\")" + space +
+				exportsName + "(" + k + "," + space + m + "[" + k + "]);" + newline
+		}
+		after += indent + indent + "}"
+	}
+	after += "]," + newline
+
+	// The bundled code goes inside the "execute" function
+	after += indent + indent + "execute:" + space + "function()" + space + "{" + newline
+	return text, after
+}
+
+func systemUnusedName(name string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	return candidate
+}
+
 type compileResultCSS struct {
 	printedCSS            string
 	sourceIndex           uint32
@@ -3839,7 +5081,7 @@ func (repr *chunkReprCSS) generate(c *linkerContext, chunk *chunkInfo) func([]as
 		// Figure out the base name for this chunk now that the content hash is known
 		if chunk.baseNameOrEmpty == "" {
 			hash := hashForFileName(cssContents)
-			chunk.baseNameOrEmpty = "chunk." + hash + c.options.OutputExtensionFor(".css")
+			chunk.baseNameOrEmpty = chunk.hashedBaseName(hash, c.options.OutputExtensionFor(".css"))
 		}
 
 		// End the metadata
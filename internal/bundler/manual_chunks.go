@@ -0,0 +1,233 @@
+package bundler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/runtime"
+)
+
+// Manual chunks let people decide which chunk a file goes in instead of code
+// splitting deciding based on which entry points reach that file. This is
+// useful for keeping slowly-changing code such as third-party packages in a
+// chunk with a stable set of files.
+//
+// Each manual chunk has its own bit in the entry bit sets after the bits for
+// the entry points. Files that match a manual chunk are marked with that bit
+// along with everything they depend on. Dependencies that only one manual
+// chunk reaches go in that chunk, and dependencies that several manual chunks
+// reach go in a chunk that those manual chunks share. Then the bit sets of all parts in a manual
+// chunk are replaced with a bit set that's shared by the whole chunk, which is
+// what the rest of the linker uses to group parts into chunks.
+
+// Each entry point has a bit in the entry bit sets, followed by one bit for
+// each manual chunk
+func (c *linkerContext) entryBitCount() uint {
+	return uint(len(c.entryPoints) + len(c.options.ManualChunks))
+}
+
+func (c *linkerContext) assignManualChunks() {
+	if len(c.options.ManualChunks) == 0 {
+		return
+	}
+	bitCount := c.entryBitCount()
+	entryPointCount := uint(len(c.entryPoints))
+	neverReached := newBitSet(bitCount)
+
+	// Mark all files that match each manual chunk along with their dependencies.
+	// Entry points are left alone since they always have their own chunk.
+	matchedBits := make(map[uint32]uint)
+	for i, manualChunk := range c.options.ManualChunks {
+		bit := entryPointCount + uint(i)
+		for _, sourceIndex := range c.reachableFiles {
+			file := &c.files[sourceIndex]
+			repr, ok := file.repr.(*reprJS)
+			if !ok || file.isEntryPoint || sourceIndex == runtime.SourceIndex {
+				continue
+			}
+			if _, ok := matchedBits[sourceIndex]; ok {
+				// Files that match more than one manual chunk go in the first one
+				continue
+			}
+			var packageName string
+			if file.packageInfo != nil {
+				packageName = file.packageInfo.Name
+			}
+			if file.source.KeyPath.Namespace != "file" || !manualChunk.Matches(file.source.KeyPath.Text, packageName) {
+				continue
+			}
+			matchedBits[sourceIndex] = bit
+			for partIndex := range repr.meta.partMeta {
+				if !repr.meta.partMeta[partIndex].entryBits.equals(neverReached) {
+					c.includePart(sourceIndex, uint32(partIndex), bit, file.distanceFromEntryPoint)
+				}
+			}
+		}
+	}
+
+	// Figure out which manual chunk each file belongs to. A file that matches a
+	// manual chunk goes in that chunk. Otherwise a file that is a dependency of
+	// exactly one manual chunk goes in that chunk. Files that are dependencies
+	// of more than one manual chunk aren't put in any of them, since that would
+	// make the other manual chunks import that one even if none of their files
+	// import each other. Instead they go in a chunk that's shared by all of the
+	// manual chunks that depend on them.
+	fileBits := make(map[uint32]uint)
+	for _, sourceIndex := range c.reachableFiles {
+		if bit, ok := matchedBits[sourceIndex]; ok {
+			fileBits[sourceIndex] = bit
+			continue
+		}
+		file := &c.files[sourceIndex]
+		manualBit, count := uint(0), 0
+		for bit := entryPointCount; bit < bitCount; bit++ {
+			if file.entryBits.hasBit(bit) {
+				manualBit = bit
+				count++
+			}
+		}
+		switch {
+		case count == 1:
+			fileBits[sourceIndex] = manualBit
+
+		case count > 1:
+			// Keep the parts of the file together so they aren't spread out over a
+			// chunk for each combination of entry points and manual chunks
+			if repr, ok := file.repr.(*reprJS); ok {
+				for partIndex := range repr.meta.partMeta {
+					partMeta := &repr.meta.partMeta[partIndex]
+					if !partMeta.entryBits.equals(neverReached) {
+						partMeta.entryBits.copyFrom(file.entryBits)
+					}
+				}
+			}
+		}
+	}
+
+	// Each entry point that reaches any part of a manual chunk must import that
+	// chunk, so the bit set for each manual chunk includes those entry points
+	chunkBits := make([]bitSet, len(c.options.ManualChunks))
+	for i := range chunkBits {
+		chunkBits[i] = newBitSet(bitCount)
+		chunkBits[i].setBit(entryPointCount + uint(i))
+	}
+	c.forEachManualChunkBitSet(fileBits, func(bits *bitSet, manualBit uint) {
+		for bit := uint(0); bit < entryPointCount; bit++ {
+			if bits.hasBit(bit) {
+				chunkBits[manualBit-entryPointCount].setBit(bit)
+			}
+		}
+	})
+
+	// Move everything into the manual chunks
+	c.forEachManualChunkBitSet(fileBits, func(bits *bitSet, manualBit uint) {
+		*bits = newBitSet(bitCount)
+		bits.copyFrom(chunkBits[manualBit-entryPointCount])
+	})
+}
+
+// This calls the callback for the entry bit set of each part and file that
+// must be moved into a manual chunk
+func (c *linkerContext) forEachManualChunkBitSet(fileBits map[uint32]uint, callback func(bits *bitSet, manualBit uint)) {
+	entryPointCount := uint(len(c.entryPoints))
+	bitCount := c.entryBitCount()
+	hasManualBit := func(bits bitSet) bool {
+		for bit := entryPointCount; bit < bitCount; bit++ {
+			if bits.hasBit(bit) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, sourceIndex := range c.reachableFiles {
+		manualBit, ok := fileBits[sourceIndex]
+		if !ok {
+			continue
+		}
+		file := &c.files[sourceIndex]
+
+		switch repr := file.repr.(type) {
+		case *reprJS:
+			// CommonJS files can't be split, so all of their parts go with the file
+			neverReached := newBitSet(bitCount)
+			for partIndex := range repr.meta.partMeta {
+				partMeta := &repr.meta.partMeta[partIndex]
+				if hasManualBit(partMeta.entryBits) || (repr.meta.cjsWrap && !partMeta.entryBits.equals(neverReached)) {
+					callback(&partMeta.entryBits, manualBit)
+				}
+			}
+			if repr.meta.cjsWrap {
+				callback(&file.entryBits, manualBit)
+			}
+
+		case *reprCSS:
+			callback(&file.entryBits, manualBit)
+		}
+	}
+}
+
+// This returns the name of the manual chunk for a chunk's entry bit set, or
+// the empty string if it's not a manual chunk. Chunks for code that's shared
+// by several manual chunks aren't manual chunks themselves.
+func (c *linkerContext) manualChunkName(entryBits bitSet) string {
+	entryPointCount := uint(len(c.entryPoints))
+	name := ""
+	for i, manualChunk := range c.options.ManualChunks {
+		if entryBits.hasBit(entryPointCount + uint(i)) {
+			if name != "" {
+				return ""
+			}
+			name = manualChunk.Name
+		}
+	}
+	return name
+}
+
+// Two manual chunks can import each other if files in both of them import
+// each other. That's fine with "esm" and "system" since those module systems
+// handle import cycles. But the other formats load a chunk by running it after
+// the chunks it imports have run, which can't work for a cycle, so it's an
+// error with those formats.
+func (c *linkerContext) checkForManualChunkCycles(chunks []chunkInfo) bool {
+	if len(c.options.ManualChunks) == 0 || c.options.OutputFormat == config.FormatESModule || c.options.OutputFormat == config.FormatSystem {
+		return false
+	}
+
+	visited := make(map[uint32]bool)
+	var visit func(chunkIndex uint32, path []uint32) bool
+	visit = func(chunkIndex uint32, path []uint32) bool {
+		for i, otherChunkIndex := range path {
+			if otherChunkIndex == chunkIndex {
+				var names []string
+				for _, cycleChunkIndex := range append(path[i:], chunkIndex) {
+					names = append(names, fmt.Sprintf("%q", chunks[cycleChunkIndex].manualChunkName))
+				}
+				c.log.AddError(nil, logger.Loc{}, fmt.Sprintf(
+					"Manual chunks cannot import each other in a cycle: %s", strings.Join(names, " -> ")))
+				c.hasErrors = true
+				return true
+			}
+		}
+		if visited[chunkIndex] {
+			return false
+		}
+		path = append(path, chunkIndex)
+		for _, otherChunkIndex := range chunks[chunkIndex].crossChunkImports {
+			if chunks[otherChunkIndex].manualChunkName != "" && visit(otherChunkIndex, path) {
+				return true
+			}
+		}
+		visited[chunkIndex] = true
+		return false
+	}
+
+	for chunkIndex, chunk := range chunks {
+		if chunk.manualChunkName != "" && visit(uint32(chunkIndex), nil) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_parser"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/runtime"
+)
+
+// This is a cache of the parsed contents of a set of files. The idea is to be
+// able to reuse the results of parsing between builds and make subsequent
+// builds faster by avoiding redundant parsing work. This only works if:
+//
+// * The AST information in the cache must be considered immutable. There is
+//   no way to enforce this in Go, but please be disciplined about this. The
+//   ASTs are shared in between builds. Any information that must be mutated
+//   in the AST during a build must be done on a shallow clone of the data if
+//   the mutation happens after parsing (i.e. a clone that clones everything
+//   that will be mutated and shares only the parts that won't be mutated).
+//
+// * The information in the cache must not depend at all on the contents of
+//   any file other than the file being cached. Invalidating an entry in the
+//   cache does not also invalidate any entries that depend on that file, so
+//   caching information that depends on other files can result in incorrect
+//   results due to reusing stale data. For example, do not "bake in" some
+//   value imported from another file.
+//
+// * Cached ASTs must only be reused if the parsing options are identical
+//   between builds. For example, it would be bad if the AST parser depended
+//   on options inherited from a nearby "package.json" file but those options
+//   were not part of the cache key. Then the cached AST could incorrectly be
+//   reused even if the contents of that "package.json" file have changed.
+//
+type CacheSet struct {
+	SourceIndexCache SourceIndexCache
+	CSSCache         CSSCache
+	JSCache          JSCache
+}
+
+func MakeCacheSet() *CacheSet {
+	return &CacheSet{
+		SourceIndexCache: SourceIndexCache{
+			entries:         make(map[logger.Path]uint32),
+			nextSourceIndex: runtime.SourceIndex + 1,
+		},
+		CSSCache: CSSCache{
+			entries: make(map[logger.Path]*cssCacheEntry),
+		},
+		JSCache: JSCache{
+			entries: make(map[logger.Path]*jsCacheEntry),
+		},
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Source index cache
+
+// Parsed ASTs embed their source index in all symbol references. Each path
+// must therefore be assigned the same source index in every build, otherwise
+// a cached AST could not be reused.
+type SourceIndexCache struct {
+	mutex           sync.Mutex
+	entries         map[logger.Path]uint32
+	nextSourceIndex uint32
+}
+
+func (c *SourceIndexCache) Get(path logger.Path) uint32 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if sourceIndex, ok := c.entries[path]; ok {
+		return sourceIndex
+	}
+	sourceIndex := c.nextSourceIndex
+	c.nextSourceIndex++
+	c.entries[path] = sourceIndex
+	return sourceIndex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CSS
+
+type CSSCache struct {
+	mutex   sync.Mutex
+	entries map[logger.Path]*cssCacheEntry
+
+	// This is the number of cache misses so far. It's used by tests to check
+	// that unchanged files aren't parsed again.
+	parseCount uint32
+}
+
+func (c *CSSCache) ParseCount() uint32 {
+	return atomic.LoadUint32(&c.parseCount)
+}
+
+type cssCacheEntry struct {
+	source  logger.Source
+	options config.Options
+	ast     css_ast.AST
+	msgs    []logger.Msg
+}
+
+func (c *CSSCache) Parse(log logger.Log, source logger.Source, options config.Options) css_ast.AST {
+	// Check the cache
+	entry := func() *cssCacheEntry {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return c.entries[source.KeyPath]
+	}()
+
+	// Cache hit
+	if entry != nil && entry.source == source && parseOptionsEqual(&entry.options, &options) {
+		for _, msg := range entry.msgs {
+			log.AddMsg(msg)
+		}
+		return entry.ast
+	}
+
+	// Cache miss
+	atomic.AddUint32(&c.parseCount, 1)
+	tempLog := logger.NewDeferLog()
+	ast := css_parser.Parse(tempLog, source, options)
+	msgs := tempLog.Done()
+	for _, msg := range msgs {
+		log.AddMsg(msg)
+	}
+
+	// Create the cache entry
+	entry = &cssCacheEntry{
+		source:  source,
+		options: options,
+		ast:     ast,
+		msgs:    msgs,
+	}
+
+	// Save for next time
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[source.KeyPath] = entry
+	return ast
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// JS
+
+type JSCache struct {
+	mutex   sync.Mutex
+	entries map[logger.Path]*jsCacheEntry
+
+	// This is the number of cache misses so far. It's used by tests to check
+	// that unchanged files aren't parsed again.
+	parseCount uint32
+}
+
+func (c *JSCache) ParseCount() uint32 {
+	return atomic.LoadUint32(&c.parseCount)
+}
+
+type jsCacheEntry struct {
+	source  logger.Source
+	options config.Options
+	ast     js_ast.AST
+	ok      bool
+	msgs    []logger.Msg
+}
+
+func (c *JSCache) Parse(log logger.Log, source logger.Source, options config.Options) (js_ast.AST, bool) {
+	// Check the cache
+	entry := func() *jsCacheEntry {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return c.entries[source.KeyPath]
+	}()
+
+	// Cache hit
+	if entry != nil && entry.source == source && parseOptionsEqual(&entry.options, &options) {
+		for _, msg := range entry.msgs {
+			log.AddMsg(msg)
+		}
+		return entry.ast, entry.ok
+	}
+
+	// Cache miss
+	atomic.AddUint32(&c.parseCount, 1)
+	tempLog := logger.NewDeferLog()
+	ast, ok := js_parser.Parse(tempLog, source, options)
+	msgs := tempLog.Done()
+	for _, msg := range msgs {
+		log.AddMsg(msg)
+	}
+
+	// Create the cache entry
+	entry = &jsCacheEntry{
+		source:  source,
+		options: options,
+		ast:     ast,
+		ok:      ok,
+		msgs:    msgs,
+	}
+
+	// Save for next time
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[source.KeyPath] = entry
+	return ast, ok
+}
+
+// This compares all options that the JavaScript and CSS parsers read. Make
+// sure to update this when a parser starts reading another option, otherwise
+// stale ASTs may be reused after that option changes.
+func parseOptionsEqual(a *config.Options, b *config.Options) bool {
+	if a.Mode != b.Mode ||
+		a.MangleSyntax != b.MangleSyntax ||
+		a.MinifyIdentifiers != b.MinifyIdentifiers ||
+		a.ASCIIOnly != b.ASCIIOnly ||
+		a.SuppressWarningsAboutWeirdCode != b.SuppressWarningsAboutWeirdCode ||
+		a.OmitRuntimeForTests != b.OmitRuntimeForTests ||
+		a.PreserveUnusedImportsTS != b.PreserveUnusedImportsTS ||
+		a.UseDefineForClassFields != b.UseDefineForClassFields ||
+		a.HotModuleReplacement != b.HotModuleReplacement ||
+		a.Defines != b.Defines ||
+		a.TS != b.TS ||
+		a.JSX.Parse != b.JSX.Parse ||
+		!stringArraysEqual(a.JSX.Factory, b.JSX.Factory) ||
+		!stringArraysEqual(a.JSX.Fragment, b.JSX.Fragment) ||
+		a.Platform != b.Platform ||
+		a.UnsupportedJSFeatures != b.UnsupportedJSFeatures ||
+		a.UnsupportedCSSFeatures != b.UnsupportedCSSFeatures ||
+		a.OutputFormat != b.OutputFormat ||
+		a.SourceMap != b.SourceMap ||
+		len(a.InjectedFiles) != len(b.InjectedFiles) {
+		return false
+	}
+
+	for i, x := range a.InjectedFiles {
+		y := b.InjectedFiles[i]
+		if x.Path != y.Path || x.SourceIndex != y.SourceIndex || !stringArraysEqual(x.Exports, y.Exports) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func stringArraysEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, x := range a {
+		if b[i] != x {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,12 @@
 package config
 
 import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+
 	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/logger"
 )
 
 type LanguageTarget int8
@@ -105,10 +110,51 @@ const (
 	//   export {...};
 	//
 	FormatESModule
+
+	// UMD stands for universal module definition. The bundled code is wrapped
+	// in a factory function that is passed to an AMD loader if there is one,
+	// assigned to "module.exports" for CommonJS, and otherwise assigned to the
+	// optional ModuleName global:
+	//
+	//   ((root, factory) => {
+	//     if (typeof define === "function" && define.amd) {
+	//       define(["require", ...externals], factory);
+	//     } else if (typeof module === "object" && module.exports) {
+	//       module.exports = factory(require);
+	//     } else {
+	//       root.moduleName = factory((id) => ({...globals})[id]);
+	//     }
+	//   })(typeof self !== "undefined" ? self : this, (require) => {
+	//     ... bundled code ...
+	//     return exports;
+	//   });
+	//
+	// External imports use the "require" function passed to the factory, which
+	// is AMD's local "require" function, node's "require" function, or a lookup
+	// into the global variables from UMDGlobals depending on the environment.
+	FormatUMD
+
+	// The SystemJS format looks like this:
+	//
+	//   System.register([...dependencies], function(exports, module) {
+	//     var ...imports;
+	//     return {
+	//       setters: [function(m) { ...imports = m... }],
+	//       execute: function() {
+	//         ... bundled code ...
+	//         exports({...});
+	//       }
+	//     };
+	//   });
+	//
+	// This is linked like the ES module format. The ES6 import and export
+	// statements are converted to dependencies, setters, and calls to "exports"
+	// when the code is generated.
+	FormatSystem
 )
 
 func (f Format) KeepES6ImportExportSyntax() bool {
-	return f == FormatPreserve || f == FormatESModule
+	return f == FormatPreserve || f == FormatESModule || f == FormatSystem
 }
 
 func (f Format) String() string {
@@ -119,6 +165,10 @@ func (f Format) String() string {
 		return "cjs"
 	case FormatESModule:
 		return "esm"
+	case FormatUMD:
+		return "umd"
+	case FormatSystem:
+		return "system"
 	}
 	return ""
 }
@@ -133,6 +183,82 @@ type StdinInfo struct {
 type ExternalModules struct {
 	NodeModules map[string]bool
 	AbsPaths    map[string]bool
+
+	// Patterns with a "*" wildcard are matched against both the import path
+	// and the resolved path, so "@company/*" and "*.png" both work
+	Patterns []WildcardPattern
+}
+
+type WildcardPattern struct {
+	Prefix string
+	Suffix string
+}
+
+func (p WildcardPattern) Matches(path string) bool {
+	return len(path) >= len(p.Prefix)+len(p.Suffix) &&
+		strings.HasPrefix(path, p.Prefix) &&
+		strings.HasSuffix(path, p.Suffix)
+}
+
+// Files that match a manual chunk are put in that chunk when code splitting
+// instead of in a chunk determined by which entry points reach them. Patterns
+// may contain "*" wildcards, which match any sequence of characters.
+type ManualChunk struct {
+	Name string
+
+	// These are matched against the "name" field in the "package.json" file of
+	// the package containing the file
+	PackageNames []string
+
+	// These are matched against the file path. A path without a wildcard also
+	// matches all files inside of it if it's a directory.
+	AbsPaths []string
+}
+
+func (chunk ManualChunk) Matches(absPath string, packageName string) bool {
+	if packageName != "" {
+		for _, pattern := range chunk.PackageNames {
+			if MatchesWildcards(pattern, packageName) {
+				return true
+			}
+		}
+	}
+	for _, pattern := range chunk.AbsPaths {
+		if strings.IndexByte(pattern, '*') != -1 {
+			if MatchesWildcards(pattern, absPath) {
+				return true
+			}
+		} else if strings.HasPrefix(absPath, pattern) && (len(absPath) == len(pattern) ||
+			absPath[len(pattern)] == '/' || absPath[len(pattern)] == '\\') {
+			return true
+		}
+	}
+	return false
+}
+
+// Each "*" in the pattern matches any sequence of characters
+func MatchesWildcards(pattern string, text string) bool {
+	pieces := strings.Split(pattern, "*")
+	if len(pieces) == 1 {
+		return pattern == text
+	}
+
+	// The first and last pieces must be at the start and end of the text
+	first, last := pieces[0], pieces[len(pieces)-1]
+	if len(text) < len(first)+len(last) || !strings.HasPrefix(text, first) || !strings.HasSuffix(text, last) {
+		return false
+	}
+	text = text[len(first) : len(text)-len(last)]
+
+	// The pieces in between can be anywhere as long as they are in order
+	for _, piece := range pieces[1 : len(pieces)-1] {
+		index := strings.Index(text, piece)
+		if index == -1 {
+			return false
+		}
+		text = text[index+len(piece):]
+	}
+	return true
 }
 
 type Mode uint8
@@ -150,6 +276,13 @@ type Options struct {
 	MangleSyntax      bool
 	CodeSplitting     bool
 
+	// These are sorted by name for determinism
+	ManualChunks []ManualChunk
+
+	// When code splitting, shared chunks with an estimated size in bytes below
+	// this are merged with other shared chunks if possible. Zero disables this.
+	MinChunkSize int
+
 	// Setting this to true disables warnings about code that is very likely to
 	// be a bug. This is used to ignore issues inside "node_modules" directories.
 	// This has caught real issues in the past. However, it's not esbuild's job
@@ -165,6 +298,19 @@ type Options struct {
 	// If true, make sure to generate a single file that can be written to stdout
 	WriteToStdout bool
 
+	// If true, output files are only served from memory by the development
+	// server and are never written to the file system. They are then allowed
+	// to have the same paths as input files.
+	ServeFromMemory bool
+
+	// If true, every module is registered with the hot module replacement
+	// runtime and "import.meta.hot" is available to modules
+	HotModuleReplacement bool
+
+	// If true, a warning is generated for each cycle in the graph of import
+	// statements. This is opt-in because cycles are often intentional.
+	DetectCycles bool
+
 	OmitRuntimeForTests     bool
 	PreserveUnusedImportsTS bool
 	UseDefineForClassFields bool
@@ -183,6 +329,24 @@ type Options struct {
 	MainFields      []string
 	ExternalModules ExternalModules
 
+	// If true, all package paths (i.e. imports that aren't relative or absolute
+	// paths) are marked as external
+	ExternalPackages bool
+
+	// Custom conditions for the "exports" and "imports" fields in "package.json"
+	// files. These are active in addition to "default", "import" or "require"
+	// (depending on the import kind), and "browser" or "node" (depending on the
+	// platform).
+	Conditions []string
+
+	// Package paths that are substituted before resolving. The key is a package
+	// name and also matches subpaths of that package. Relative substitutions
+	// are resolved relative to the current working directory.
+	PackageAliases map[string]string
+
+	// The absolute path of a browser import map file, if any
+	ImportMapPath string
+
 	AbsOutputFile     string
 	AbsOutputDir      string
 	OutputExtensions  map[string]string
@@ -194,11 +358,36 @@ type Options struct {
 	InjectAbsPaths    []string
 	InjectedFiles     []InjectedFile
 
+	// Maps the path of an external import to the global variable that holds
+	// it. This is only used by the UMD format when there is no module system.
+	// Each global is a dotted name that has already been split apart like
+	// ModuleName.
+	UMDGlobals map[string][]string
+
 	// If present, metadata about the bundle is written as JSON here
 	AbsMetadataFile string
 
 	SourceMap SourceMap
 	Stdin     *StdinInfo
+
+	Plugins []Plugin
+
+	// If present, the build stops early once this has been cancelled
+	CancelFlag *CancelFlag
+}
+
+// This is checked at various points during a build so that the build can stop
+// early when its result is no longer needed. A nil flag is never cancelled.
+type CancelFlag struct {
+	cancelled uint32
+}
+
+func (flag *CancelFlag) Cancel() {
+	atomic.StoreUint32(&flag.cancelled, 1)
+}
+
+func (flag *CancelFlag) DidCancel() bool {
+	return flag != nil && atomic.LoadUint32(&flag.cancelled) != 0
 }
 
 type InjectedFile struct {
@@ -213,3 +402,58 @@ func (options *Options) OutputExtensionFor(key string) string {
 	}
 	return key
 }
+
+type Plugin struct {
+	Name      string
+	OnResolve []OnResolve
+	OnLoad    []OnLoad
+}
+
+type OnResolve struct {
+	Filter    *regexp.Regexp
+	Namespace string
+	Callback  func(OnResolveArgs) OnResolveResult
+}
+
+type OnResolveArgs struct {
+	Path       string
+	Importer   logger.Path
+	ResolveDir string
+}
+
+type OnResolveResult struct {
+	PluginName string
+
+	Path     logger.Path
+	External bool
+
+	Msgs        []logger.Msg
+	ThrownError error
+}
+
+type OnLoad struct {
+	Filter    *regexp.Regexp
+	Namespace string
+	Callback  func(OnLoadArgs) OnLoadResult
+}
+
+type OnLoadArgs struct {
+	Path logger.Path
+}
+
+type OnLoadResult struct {
+	PluginName string
+
+	Contents      *string
+	AbsResolveDir string
+	Loader        Loader
+
+	Msgs        []logger.Msg
+	ThrownError error
+}
+
+// Plugin callbacks only run for paths in the namespace they were registered
+// for. An empty namespace means the callback applies to all namespaces.
+func PluginAppliesToPath(path logger.Path, filter *regexp.Regexp, namespace string) bool {
+	return (namespace == "" || path.Namespace == namespace) && filter.MatchString(path.Text)
+}
@@ -118,7 +118,7 @@ func (p *parser) expect(kind css_lexer.T) bool {
 		}
 	}
 	if t.Range.Loc.Start > p.prevError.Start {
-		p.log.AddRangeWarning(&p.source, t.Range, text)
+		p.log.AddRangeWarning(logger.MsgIDCSSSyntaxError, &p.source, t.Range, text)
 		p.prevError = t.Range.Loc
 	}
 	return false
@@ -136,7 +136,7 @@ func (p *parser) unexpected() {
 		default:
 			text = fmt.Sprintf("Unexpected %q", p.raw())
 		}
-		p.log.AddRangeWarning(&p.source, t.Range, text)
+		p.log.AddRangeWarning(logger.MsgIDCSSSyntaxError, &p.source, t.Range, text)
 		p.prevError = t.Range.Loc
 	}
 }
@@ -275,7 +275,7 @@ func (p *parser) parseAtRule(context atRuleContext) css_ast.R {
 		if p.peek(css_lexer.TString) {
 			encoding := p.decoded()
 			if encoding != "UTF-8" {
-				p.log.AddRangeWarning(&p.source, p.current().Range,
+				p.log.AddRangeWarning(logger.MsgIDUnsupportedAtCharset, &p.source, p.current().Range,
 					fmt.Sprintf("\"UTF-8\" will be used instead of unsupported charset %q", encoding))
 			}
 			p.advance()
@@ -415,7 +415,7 @@ func (p *parser) parseAtRule(context atRuleContext) css_ast.R {
 		// https://developer.mozilla.org/en-US/docs/Web/CSS/At-rule. Deprecated
 		// and Firefox-only at-rules have been removed.
 		if kind == atRuleUnknown {
-			p.log.AddRangeWarning(&p.source, atRange, fmt.Sprintf("%q is not a known rule name", "@"+atToken))
+			p.log.AddRangeWarning(logger.MsgIDUnsupportedAtRule, &p.source, atRange, fmt.Sprintf("%q is not a known rule name", "@"+atToken))
 		}
 	}
 
@@ -104,6 +104,36 @@ type FS interface {
 	Join(parts ...string) string
 	Cwd() string
 	Rel(base string, target string) (string, bool)
+
+	// This is a set of all files and directories that have been read so far,
+	// used for watch mode. It's empty unless it was explicitly requested.
+	WatchData() WatchData
+}
+
+// This is a cheap way to tell if a file has changed without reading it. It's
+// only available for file systems that implement "ModKeyFS".
+type ModKey struct {
+	size    int64
+	modTime int64
+}
+
+// This is implemented by file systems where getting a "ModKey" is much cheaper
+// than reading the file. A path that the "ModKey" was requested for is also
+// watched in watch mode.
+type ModKeyFS interface {
+	ModKey(path string) (ModKey, error)
+}
+
+func modKeyFromInfo(info os.FileInfo) ModKey {
+	return ModKey{size: info.Size(), modTime: info.ModTime().UnixNano()}
+}
+
+type WatchData struct {
+	// These functions return a non-empty path as a string if the file system
+	// entry has been modified. For files, the returned path is the same as the
+	// file path. For directories, the returned path is either the directory
+	// itself or a file in the directory that was changed.
+	Paths map[string]func() string
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -195,6 +225,10 @@ func splitOnSlash(path string) (string, string) {
 	return path, ""
 }
 
+func (*mockFS) WatchData() WatchData {
+	return WatchData{}
+}
+
 func (*mockFS) Rel(base string, target string) (string, bool) {
 	// Base cases
 	if base == "" || base == "." {
@@ -240,6 +274,31 @@ type realFS struct {
 
 	// For the current working directory
 	cwd string
+
+	// If non-nil, this records everything that has been read so far. Reading
+	// files happens in parallel so access to it must be synchronized.
+	watchMutex sync.Mutex
+	watchData  map[string]privateWatchData
+}
+
+type privateWatchData struct {
+	// For files, this is the contents of the file or the error from reading it.
+	// The "ModKey" is used to avoid reading the file again when polling for
+	// changes unless it looks like it has changed. If only the "ModKey" was
+	// requested, then there are no contents and only the "ModKey" is checked.
+	contents    string
+	modKey      ModKey
+	hasContents bool
+	err         error
+
+	// For directories, this is the list of entry names or the error from
+	// reading it
+	isDir bool
+	names map[string]bool
+}
+
+type RealFSOptions struct {
+	WantWatchData bool
 }
 
 type entriesOrErr struct {
@@ -277,7 +336,7 @@ func realpath(path string) string {
 	return path
 }
 
-func RealFS() FS {
+func RealFS(options RealFSOptions) FS {
 	cwd, err := os.Getwd()
 	if err != nil {
 		cwd = ""
@@ -292,9 +351,14 @@ func RealFS() FS {
 		// symlinked directory.
 		cwd = realpath(cwd)
 	}
+	var watchData map[string]privateWatchData
+	if options.WantWatchData {
+		watchData = make(map[string]privateWatchData)
+	}
 	return &realFS{
-		entries: make(map[string]entriesOrErr),
-		cwd:     cwd,
+		entries:   make(map[string]entriesOrErr),
+		cwd:       cwd,
+		watchData: watchData,
 	}
 }
 
@@ -329,12 +393,36 @@ func (fs *realFS) ReadDirectory(dir string) (map[string]*Entry, error) {
 		entries = nil
 	}
 	fs.entries[dir] = entriesOrErr{entries: entries, err: err}
+
+	// Failed reads are also recorded so that creating the directory later on
+	// can be detected
+	if fs.watchData != nil {
+		data := privateWatchData{isDir: true, err: err}
+		if err == nil {
+			data.names = make(map[string]bool, len(names))
+			for _, name := range names {
+				data.names[name] = true
+			}
+		}
+		fs.watchMutex.Lock()
+		fs.watchData[dir] = data
+		fs.watchMutex.Unlock()
+	}
 	return entries, err
 }
 
 func (fs *realFS) ReadFile(path string) (string, error) {
 	BeforeFileOpen()
 	defer AfterFileClose()
+
+	// The "ModKey" is computed before reading so that a change while the file
+	// is being read is detected later on
+	var modKey ModKey
+	if fs.watchData != nil {
+		if info, err := os.Stat(path); err == nil {
+			modKey = modKeyFromInfo(info)
+		}
+	}
 	buffer, err := ioutil.ReadFile(path)
 
 	// Unwrap to get the underlying error
@@ -347,10 +435,39 @@ func (fs *realFS) ReadFile(path string) (string, error) {
 	// so callers that check for ENOENT will successfully detect this file as
 	// missing.
 	if err == syscall.ENOTDIR {
-		return "", syscall.ENOENT
+		err = syscall.ENOENT
+		buffer = nil
 	}
 
-	return string(buffer), err
+	// Failed reads are also recorded so that creating the file later on can be
+	// detected
+	contents := string(buffer)
+	if fs.watchData != nil {
+		fs.watchMutex.Lock()
+		fs.watchData[path] = privateWatchData{contents: contents, modKey: modKey, hasContents: true, err: err}
+		fs.watchMutex.Unlock()
+	}
+	return contents, err
+}
+
+func (fs *realFS) ModKey(path string) (ModKey, error) {
+	BeforeFileOpen()
+	defer AfterFileClose()
+	var modKey ModKey
+	info, err := os.Stat(path)
+	if err == nil {
+		modKey = modKeyFromInfo(info)
+	}
+
+	// Don't overwrite the contents if the file has already been read
+	if fs.watchData != nil {
+		fs.watchMutex.Lock()
+		if data, ok := fs.watchData[path]; !ok || !data.hasContents {
+			fs.watchData[path] = privateWatchData{modKey: modKey, err: err}
+		}
+		fs.watchMutex.Unlock()
+	}
+	return modKey, err
 }
 
 func (*realFS) IsAbs(p string) bool {
@@ -382,6 +499,61 @@ func (fs *realFS) Cwd() string {
 	return fs.cwd
 }
 
+func (fs *realFS) WatchData() WatchData {
+	paths := make(map[string]func() string)
+
+	fs.watchMutex.Lock()
+	defer fs.watchMutex.Unlock()
+
+	for path, data := range fs.watchData {
+		// Each entry is captured by value so later builds can't affect it
+		path, data := path, data
+
+		if data.isDir {
+			paths[path] = func() string {
+				names, err := readdir(path)
+				if (err == nil) != (data.err == nil) {
+					return path
+				}
+				for _, name := range names {
+					if !data.names[name] {
+						return filepath.Join(path, name)
+					}
+				}
+				if len(names) != len(data.names) {
+					return path
+				}
+				return ""
+			}
+		} else {
+			paths[path] = func() string {
+				// Avoid reading the file if the size and modification time are the
+				// same. This matters for large files such as Yarn's zip archives.
+				info, err := os.Stat(path)
+				if err == nil && data.err == nil && modKeyFromInfo(info) == data.modKey {
+					return ""
+				}
+				if err != nil && data.err != nil {
+					return ""
+				}
+				if !data.hasContents {
+					return path
+				}
+
+				BeforeFileOpen()
+				defer AfterFileClose()
+				buffer, err := ioutil.ReadFile(path)
+				if (err == nil) != (data.err == nil) || string(buffer) != data.contents {
+					return path
+				}
+				return ""
+			}
+		}
+	}
+
+	return WatchData{Paths: paths}
+}
+
 func (*realFS) Rel(base string, target string) (string, bool) {
 	if rel, err := filepath.Rel(base, target); err == nil {
 		return rel, true
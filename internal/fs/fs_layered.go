@@ -0,0 +1,74 @@
+package fs
+
+import (
+	"os"
+	"sync"
+)
+
+// This is a file system provided by the user of the API. It only needs to
+// know about some paths. Returning an error for which "os.IsNotExist" is true
+// means the path should be read from the underlying file system instead.
+type Layer interface {
+	ReadDirectory(path string) (map[string]EntryKind, error)
+	ReadFile(path string) (string, error)
+}
+
+type layeredFS struct {
+	FS
+	layer Layer
+
+	// Stores the merged file entries for directories we've listed before
+	entriesMutex sync.Mutex
+	entries      map[string]entriesOrErr
+}
+
+// This layers the given file system on top of another file system. Files in
+// the layer hide files with the same path in the underlying file system, and
+// directory listings are merged together. Watch data only covers paths read
+// from the underlying file system.
+func LayeredFS(layer Layer, base FS) FS {
+	return &layeredFS{
+		FS:      base,
+		layer:   layer,
+		entries: make(map[string]entriesOrErr),
+	}
+}
+
+func (fs *layeredFS) ReadDirectory(dir string) (map[string]*Entry, error) {
+	fs.entriesMutex.Lock()
+	defer fs.entriesMutex.Unlock()
+
+	// First, check the cache
+	if cached, ok := fs.entries[dir]; ok {
+		return cached.entries, cached.err
+	}
+
+	// Cache miss: merge the entries from both file systems
+	kinds, err := fs.layer.ReadDirectory(dir)
+	if err != nil && !os.IsNotExist(err) {
+		fs.entries[dir] = entriesOrErr{err: err}
+		return nil, err
+	}
+	baseEntries, baseErr := fs.FS.ReadDirectory(dir)
+	if kinds == nil {
+		fs.entries[dir] = entriesOrErr{entries: baseEntries, err: baseErr}
+		return baseEntries, baseErr
+	}
+	entries := make(map[string]*Entry, len(baseEntries)+len(kinds))
+	for name, entry := range baseEntries {
+		entries[name] = entry
+	}
+	for name, kind := range kinds {
+		entries[name] = &Entry{dir: dir, base: name, kind: kind}
+	}
+	fs.entries[dir] = entriesOrErr{entries: entries}
+	return entries, nil
+}
+
+func (fs *layeredFS) ReadFile(path string) (string, error) {
+	contents, err := fs.layer.ReadFile(path)
+	if err != nil && os.IsNotExist(err) {
+		return fs.FS.ReadFile(path)
+	}
+	return contents, err
+}
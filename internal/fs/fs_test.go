@@ -1,8 +1,14 @@
 package fs
 
 import (
+	"archive/zip"
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestBasic(t *testing.T) {
@@ -62,6 +68,150 @@ func TestBasic(t *testing.T) {
 	}
 }
 
+func TestZip(t *testing.T) {
+	buffer := bytes.Buffer{}
+	writer := zip.NewWriter(&buffer)
+	for _, name := range []string{"node_modules/pkg/index.js", "node_modules/pkg/lib/util.js"} {
+		file, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write([]byte("// " + name))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := ZipFS(MockFS(map[string]string{
+		"/project/.yarn/cache/pkg.zip":  buffer.String(),
+		"/project/.yarn/cache/not.zip":  "not a zip file",
+		"/project/src/entry.js":         "// src/entry.js",
+		"/project/dir.zip/nested/file":  "// dir.zip/nested/file",
+		"/project/.yarn/cache/other.js": "// other.js",
+	}), nil)
+
+	expectFile := func(path string, expected string) {
+		t.Helper()
+		contents, err := fs.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected to find %s", path)
+		}
+		if contents != expected {
+			t.Fatalf("Incorrect contents for %s: %q", path, contents)
+		}
+	}
+
+	expectDir := func(path string, expected map[string]EntryKind) {
+		t.Helper()
+		entries, err := fs.ReadDirectory(path)
+		if err != nil {
+			t.Fatalf("Expected to find %s", path)
+		}
+		if len(entries) != len(expected) {
+			t.Fatalf("Incorrect contents for %s: %v", path, entries)
+		}
+		for name, kind := range expected {
+			if entry, ok := entries[name]; !ok || entry.Kind() != kind {
+				t.Fatalf("Incorrect contents for %s: %v", path, entries)
+			}
+		}
+	}
+
+	// Files and directories inside the archive
+	expectFile("/project/.yarn/cache/pkg.zip/node_modules/pkg/index.js", "// node_modules/pkg/index.js")
+	expectFile("/project/.yarn/cache/pkg.zip/node_modules/pkg/lib/util.js", "// node_modules/pkg/lib/util.js")
+	expectDir("/project/.yarn/cache/pkg.zip", map[string]EntryKind{"node_modules": DirEntry})
+	expectDir("/project/.yarn/cache/pkg.zip/node_modules/pkg", map[string]EntryKind{"index.js": FileEntry, "lib": DirEntry})
+	if _, err := fs.ReadFile("/project/.yarn/cache/pkg.zip/node_modules/pkg/missing.js"); err == nil {
+		t.Fatal("Unexpectedly found a missing file in the archive")
+	}
+	if _, err := fs.ReadDirectory("/project/.yarn/cache/pkg.zip/missing"); err == nil {
+		t.Fatal("Unexpectedly found a missing directory in the archive")
+	}
+
+	// Other paths are passed through
+	expectFile("/project/.yarn/cache/pkg.zip", buffer.String())
+	expectFile("/project/src/entry.js", "// src/entry.js")
+	expectFile("/project/dir.zip/nested/file", "// dir.zip/nested/file")
+	if _, err := fs.ReadDirectory("/project/.yarn/cache/not.zip"); err == nil {
+		t.Fatal("Unexpectedly read an invalid archive as a directory")
+	}
+
+	// Virtual paths map to the real path
+	expectFile("/project/.yarn/__virtual__/pkg-virtual-123/0/cache/pkg.zip/node_modules/pkg/index.js", "// node_modules/pkg/index.js")
+	expectFile("/project/.yarn/__virtual__/pkg-virtual-123/2/project/src/entry.js", "// src/entry.js")
+	expectDir("/project/.yarn/__virtual__", map[string]EntryKind{})
+	expectDir("/project/.yarn/__virtual__/pkg-virtual-123", map[string]EntryKind{})
+}
+
+func TestZipCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	zipPath := filepath.Join(dir, "pkg.zip")
+	filePath := filepath.Join(zipPath, "index.js")
+
+	writeZip := func(contents string, modTime time.Time) {
+		t.Helper()
+		buffer := bytes.Buffer{}
+		writer := zip.NewWriter(&buffer)
+		file, err := writer.Create("index.js")
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write([]byte(contents))
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(zipPath, buffer.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(zipPath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	build := func(cache *ZipCache, expected string) (*zipFile, WatchData) {
+		t.Helper()
+		fs := ZipFS(RealFS(RealFSOptions{WantWatchData: true}), cache)
+		contents, err := fs.ReadFile(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contents != expected {
+			t.Fatalf("Expected %q, got %q", expected, contents)
+		}
+		return fs.(*zipFS).readZipFile(zipPath), fs.WatchData()
+	}
+
+	// The archive is reused by later builds if it hasn't changed
+	cache := NewZipCache()
+	writeZip("// v1", time.Now())
+	first, _ := build(cache, "// v1")
+	second, watchData := build(cache, "// v1")
+	if first != second {
+		t.Fatal("Expected the archive to be reused")
+	}
+
+	// Reused archives are still watched
+	isDirty, ok := watchData.Paths[zipPath]
+	if !ok || isDirty() != "" {
+		t.Fatal("Expected the archive to be watched")
+	}
+	writeZip("// v2", time.Now().Add(time.Hour))
+	if isDirty() != zipPath {
+		t.Fatal("Expected the archive to be dirty")
+	}
+
+	// The archive is read again if it has changed
+	third, _ := build(cache, "// v2")
+	if third == second {
+		t.Fatal("Expected the archive to be read again")
+	}
+}
+
 func TestRel(t *testing.T) {
 	fs := MockFS(map[string]string{})
 
@@ -89,3 +239,76 @@ func TestRel(t *testing.T) {
 	expect("/a/b/c", "/a/b/x/y", "../x/y")
 	expect("/a/b/c/d", "/a/b/x/y", "../../x/y")
 }
+
+func TestWatchData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, contents string) {
+		t.Helper()
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	touch := func(name string, modTime time.Time) {
+		t.Helper()
+		if err := os.Chtimes(filepath.Join(dir, name), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectDirty := func(fs FS, expected string) {
+		t.Helper()
+		dirty := ""
+		for _, isDirty := range fs.WatchData().Paths {
+			if path := isDirty(); path != "" {
+				dirty = path
+			}
+		}
+		if dirty != expected {
+			t.Fatalf("Expected %q to be dirty, got %q", expected, dirty)
+		}
+	}
+
+	write("a.txt", "a")
+	fs := RealFS(RealFSOptions{WantWatchData: true})
+	fs.ReadDirectory(dir)
+	fs.ReadFile(filepath.Join(dir, "a.txt"))
+	fs.ReadFile(filepath.Join(dir, "missing.txt"))
+	expectDirty(fs, "")
+
+	// Changing a file that was read is detected. The modification time is set
+	// explicitly since some file systems have a coarse timestamp resolution.
+	write("a.txt", "b")
+	touch("a.txt", time.Now().Add(time.Hour))
+	expectDirty(fs, filepath.Join(dir, "a.txt"))
+
+	// Changing the modification time without changing the contents isn't
+	fs = RealFS(RealFSOptions{WantWatchData: true})
+	fs.ReadFile(filepath.Join(dir, "a.txt"))
+	touch("a.txt", time.Now().Add(2*time.Hour))
+	expectDirty(fs, "")
+
+	// Creating a file that failed to be read is detected
+	fs = RealFS(RealFSOptions{WantWatchData: true})
+	fs.ReadFile(filepath.Join(dir, "missing.txt"))
+	write("missing.txt", "")
+	expectDirty(fs, filepath.Join(dir, "missing.txt"))
+
+	// Adding a directory entry is detected
+	fs = RealFS(RealFSOptions{WantWatchData: true})
+	fs.ReadDirectory(dir)
+	write("new.txt", "")
+	expectDirty(fs, filepath.Join(dir, "new.txt"))
+
+	// Nothing is recorded unless it was requested
+	fs = RealFS(RealFSOptions{})
+	fs.ReadDirectory(dir)
+	if len(fs.WatchData().Paths) != 0 {
+		t.Fatal("Expected no watch data")
+	}
+}
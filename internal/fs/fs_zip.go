@@ -0,0 +1,285 @@
+package fs
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Yarn Plug'n'Play installs packages into zip archives in the Yarn cache
+// instead of into "node_modules" directories. Paths into these archives look
+// like "/project/.yarn/cache/pkg-npm-1.0.0-abc123.zip/node_modules/pkg/index.js".
+// This file system treats each zip archive as a read-only directory so that
+// these paths can be read like any other path.
+//
+// Yarn also uses "virtual" paths to give packages with peer dependencies a
+// separate identity for each set of peer dependencies. These paths look like
+// "/project/.yarn/__virtual__/pkg-virtual-abc123/0/cache/pkg.zip/..." and
+// don't exist on disk. They are mapped back to the real path here.
+type zipFS struct {
+	FS
+	cache *ZipCache
+
+	// The archives that have been looked up by this file system. Each archive
+	// is checked against the cache once and then reused.
+	zipFilesMutex sync.Mutex
+	zipFiles      map[string]*zipFile
+}
+
+// Reading and indexing a zip archive is expensive, so the results can be kept
+// around in between builds. Archives are only reused if the underlying file
+// system implements "ModKeyFS" and the archive hasn't changed.
+type ZipCache struct {
+	mutex    sync.Mutex
+	zipFiles map[string]*zipFile
+}
+
+func NewZipCache() *ZipCache {
+	return &ZipCache{zipFiles: make(map[string]*zipFile)}
+}
+
+type zipFile struct {
+	// The archive is only read once even if it's looked up in parallel
+	once   sync.Once
+	modKey ModKey
+
+	// This is nil if the file couldn't be read or isn't a valid zip archive
+	reader *zip.Reader
+
+	// The keys are paths inside the archive without a leading or trailing
+	// slash. The root directory of the archive has an empty path.
+	dirs  map[string]map[string]*Entry
+	files map[string]*zip.File
+}
+
+// The cache is optional and may be nil
+func ZipFS(inner FS, cache *ZipCache) FS {
+	if cache == nil {
+		cache = NewZipCache()
+	}
+	return &zipFS{
+		FS:       inner,
+		cache:    cache,
+		zipFiles: make(map[string]*zipFile),
+	}
+}
+
+func (fs *zipFS) ReadDirectory(path string) (map[string]*Entry, error) {
+	path, isVirtualDir := fs.mangleYarnPnPVirtualPath(path)
+
+	// Virtual directories without a depth don't exist anywhere. They are only
+	// ever traversed to reach the paths inside them, so they are empty.
+	if isVirtualDir {
+		return map[string]*Entry{}, nil
+	}
+
+	if zip, pathInZip, ok := fs.findZipFile(path); ok {
+		if entries, ok := zip.dirs[pathInZip]; ok {
+			return entries, nil
+		}
+		return nil, syscall.ENOENT
+	}
+
+	return fs.FS.ReadDirectory(path)
+}
+
+func (fs *zipFS) ReadFile(path string) (string, error) {
+	path, isVirtualDir := fs.mangleYarnPnPVirtualPath(path)
+	if isVirtualDir {
+		return "", syscall.ENOENT
+	}
+
+	if zip, pathInZip, ok := fs.findZipFile(path); ok && pathInZip != "" {
+		file, ok := zip.files[pathInZip]
+		if !ok {
+			return "", syscall.ENOENT
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return "", err
+		}
+		defer reader.Close()
+		buffer, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return "", err
+		}
+		return string(buffer), nil
+	}
+
+	return fs.FS.ReadFile(path)
+}
+
+// This finds the zip archive containing the given path, if any. For example,
+// "/a/b.zip/c/d" is split into the archive "/a/b.zip" and the path "c/d". The
+// path inside the archive always uses forward slashes.
+func (fs *zipFS) findZipFile(path string) (*zipFile, string, bool) {
+	var segments []string
+	var candidates []int
+	for dir := path; ; {
+		base := fs.Base(dir)
+		parent := fs.Dir(dir)
+		if parent == dir {
+			break
+		}
+		if strings.HasSuffix(base, ".zip") {
+			candidates = append(candidates, len(segments))
+		}
+		segments = append(segments, base)
+		dir = parent
+	}
+
+	// Try the outermost archive first. A directory whose name ends in ".zip"
+	// isn't an archive, in which case the next one is tried.
+	for i := len(candidates) - 1; i >= 0; i-- {
+		depth := candidates[i]
+		zipPath := path
+		for j := 0; j < depth; j++ {
+			zipPath = fs.Dir(zipPath)
+		}
+		if zip := fs.readZipFile(zipPath); zip.reader != nil {
+			pathInZip := make([]string, 0, depth)
+			for j := depth - 1; j >= 0; j-- {
+				pathInZip = append(pathInZip, segments[j])
+			}
+			return zip, strings.Join(pathInZip, "/"), true
+		}
+	}
+	return nil, "", false
+}
+
+func (fs *zipFS) readZipFile(path string) *zipFile {
+	fs.zipFilesMutex.Lock()
+	result, ok := fs.zipFiles[path]
+	fs.zipFilesMutex.Unlock()
+
+	// Failures are cached too since this is called for every path inside the
+	// archive. Another goroutine may have looked up the same archive in the
+	// meantime, in which case the first one wins.
+	if !ok {
+		result = fs.cache.zipFileFor(fs.FS, path)
+		fs.zipFilesMutex.Lock()
+		if existing, ok := fs.zipFiles[path]; ok {
+			result = existing
+		} else {
+			fs.zipFiles[path] = result
+		}
+		fs.zipFilesMutex.Unlock()
+	}
+
+	// Read and index the archive without holding any locks so that other
+	// archives can be read in parallel
+	result.once.Do(func() {
+		fs.indexZipFile(result, path)
+	})
+	return result
+}
+
+// This returns the cached archive if it hasn't changed since it was cached.
+// Otherwise it returns a new archive that still needs to be read.
+func (cache *ZipCache) zipFileFor(inner FS, path string) *zipFile {
+	modKeyFS, ok := inner.(ModKeyFS)
+	if !ok {
+		return &zipFile{}
+	}
+	modKey, err := modKeyFS.ModKey(path)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if err != nil {
+		delete(cache.zipFiles, path)
+		return &zipFile{}
+	}
+	if cached, ok := cache.zipFiles[path]; ok && cached.modKey == modKey {
+		return cached
+	}
+	result := &zipFile{modKey: modKey}
+	cache.zipFiles[path] = result
+	return result
+}
+
+func (fs *zipFS) indexZipFile(result *zipFile, path string) {
+	contents, err := fs.FS.ReadFile(path)
+	if err != nil {
+		return
+	}
+	reader, err := zip.NewReader(strings.NewReader(contents), int64(len(contents)))
+	if err != nil {
+		return
+	}
+
+	// Build a listing for each directory. Zip archives don't always contain
+	// entries for directories, so these are inferred from the file paths.
+	dirs := map[string]map[string]*Entry{"": {}}
+	files := make(map[string]*zip.File)
+	var addDir func(dir string)
+	addDir = func(dir string) {
+		if _, ok := dirs[dir]; ok || dir == "" {
+			return
+		}
+		dirs[dir] = make(map[string]*Entry)
+		parent, base := splitZipDir(dir)
+		addDir(parent)
+		dirs[parent][base] = &Entry{dir: fs.Join(path, parent), base: base, kind: DirEntry}
+	}
+	for _, file := range reader.File {
+		name := strings.TrimPrefix(file.Name, "/")
+		if strings.HasSuffix(name, "/") {
+			addDir(strings.TrimSuffix(name, "/"))
+			continue
+		}
+		parent, base := splitZipDir(name)
+		addDir(parent)
+		dirs[parent][base] = &Entry{dir: fs.Join(path, parent), base: base, kind: FileEntry}
+		files[name] = file
+	}
+
+	result.reader = reader
+	result.dirs = dirs
+	result.files = files
+}
+
+func splitZipDir(path string) (string, string) {
+	if slash := strings.LastIndexByte(path, '/'); slash != -1 {
+		return path[:slash], path[slash+1:]
+	}
+	return "", path
+}
+
+// Virtual paths have the form "<dir>/__virtual__/<hash>/<depth>/<subpath>" and
+// map to "<dir>" followed by "<depth>" parent directory traversals followed by
+// "<subpath>". The second return value is true for "<dir>/__virtual__" and
+// "<dir>/__virtual__/<hash>", which don't map to anything.
+func (fs *zipFS) mangleYarnPnPVirtualPath(path string) (string, bool) {
+	var segments []string
+	for dir := path; ; {
+		base := fs.Base(dir)
+		parent := fs.Dir(dir)
+		if parent == dir {
+			break
+		}
+		if base == "__virtual__" || base == "$$virtual" {
+			n := len(segments)
+			if n < 2 {
+				return path, true
+			}
+			depth, err := strconv.Atoi(segments[n-2])
+			if err != nil || depth < 0 {
+				return path, false
+			}
+			result := parent
+			for i := 0; i < depth; i++ {
+				result = fs.Dir(result)
+			}
+			for i := n - 3; i >= 0; i-- {
+				result = fs.Join(result, segments[i])
+			}
+			return result, false
+		}
+		segments = append(segments, base)
+		dir = parent
+	}
+	return path, false
+}
@@ -1138,7 +1138,7 @@ func (lexer *Lexer) Next() {
 				// Handle legacy HTML-style comments
 				if lexer.codePoint == '>' && lexer.HasNewlineBefore {
 					lexer.step()
-					lexer.log.AddRangeWarning(&lexer.source, lexer.Range(),
+					lexer.log.AddRangeWarning(logger.MsgIDHTMLCommentInJS, &lexer.source, lexer.Range(),
 						"Treating \"-->\" as the start of a legacy HTML single-line comment")
 				singleLineHTMLCloseComment:
 					for {
@@ -1291,7 +1291,7 @@ func (lexer *Lexer) Next() {
 					lexer.step()
 					lexer.step()
 					lexer.step()
-					lexer.log.AddRangeWarning(&lexer.source, lexer.Range(),
+					lexer.log.AddRangeWarning(logger.MsgIDHTMLCommentInJS, &lexer.source, lexer.Range(),
 						"Treating \"<!--\" as the start of a legacy HTML single-line comment")
 				singleLineHTMLOpenComment:
 					for {
@@ -90,6 +90,11 @@ type parser struct {
 	namedExports            map[string]js_ast.Ref
 	topLevelSymbolToParts   map[js_ast.Ref][]uint32
 
+	// This holds the first read of each import item or import namespace that
+	// happens during module evaluation (i.e. not inside a function). It's used
+	// to find import cycles that are likely to fail at run-time.
+	topLevelImportReads map[js_ast.Ref]logger.Range
+
 	// The parser does two passes and we need to pass the scope tree information
 	// from the first pass to the second pass. That's done by tracking the calls
 	// to pushScopeForParsePass() and popScope() during the first pass in
@@ -281,6 +286,10 @@ type fnOnlyDataVisit struct {
 	// or a class declaration). That means the top-level module scope "this" value
 	// has been shadowed and is now inaccessible.
 	isThisNested bool
+
+	// If true, we're inside a function or an arrow function. Code here doesn't
+	// run while the module is being evaluated, at least not directly.
+	isInsideFnOrArrow bool
 }
 
 const bloomFilterSize = 251
@@ -324,10 +333,10 @@ func (dc *duplicateCaseChecker) check(p *parser, expr js_ast.Expr) {
 					if equals, couldBeIncorrect := duplicateCaseEquals(c.value, expr); equals {
 						r := p.source.RangeOfOperatorBefore(expr.Loc, "case")
 						if couldBeIncorrect {
-							p.log.AddRangeWarning(&p.source, r,
+							p.log.AddRangeWarning(logger.MsgIDDuplicateCase, &p.source, r,
 								"This case clause may never be evaluated because it likely duplicates an earlier case clause")
 						} else {
-							p.log.AddRangeWarning(&p.source, r,
+							p.log.AddRangeWarning(logger.MsgIDDuplicateCase, &p.source, r,
 								"This case clause will never be evaluated because it duplicates an earlier case clause")
 						}
 					}
@@ -860,7 +869,9 @@ func (p *parser) declareSymbol(kind js_ast.SymbolKind, loc logger.Loc, name stri
 		switch p.canMergeSymbols(symbol.Kind, kind) {
 		case mergeForbidden:
 			r := js_lexer.RangeOfIdentifier(p.source, loc)
-			p.log.AddRangeError(&p.source, r, fmt.Sprintf("%q has already been declared", name))
+			p.log.AddRangeErrorWithNotes(&p.source, r, fmt.Sprintf("%q has already been declared", name),
+				[]logger.MsgData{logger.RangeData(&p.source, js_lexer.RangeOfIdentifier(p.source, existing.Loc),
+					fmt.Sprintf("%q was originally declared here", name))})
 			return existing.Ref
 
 		case mergeKeepExisting:
@@ -928,7 +939,9 @@ nextMember:
 						// declaration can both silently shadow another hoisted symbol
 						if symbol.Kind != js_ast.SymbolCatchIdentifier && symbol.Kind != js_ast.SymbolHoistedFunction {
 							r := js_lexer.RangeOfIdentifier(p.source, member.Loc)
-							p.log.AddRangeError(&p.source, r, fmt.Sprintf("%q has already been declared", symbol.OriginalName))
+							p.log.AddRangeErrorWithNotes(&p.source, r, fmt.Sprintf("%q has already been declared", symbol.OriginalName),
+								[]logger.MsgData{logger.RangeData(&p.source, js_lexer.RangeOfIdentifier(p.source, existingMember.Loc),
+									fmt.Sprintf("%q was originally declared here", symbol.OriginalName))})
 						}
 						continue nextMember
 					}
@@ -2022,61 +2035,6 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog []logger.Loc)
 	}
 }
 
-func (p *parser) convertBindingToExpr(binding js_ast.Binding, wrapIdentifier func(logger.Loc, js_ast.Ref) js_ast.Expr) js_ast.Expr {
-	loc := binding.Loc
-
-	switch b := binding.Data.(type) {
-	case *js_ast.BMissing:
-		return js_ast.Expr{Loc: loc, Data: &js_ast.EMissing{}}
-
-	case *js_ast.BIdentifier:
-		if wrapIdentifier != nil {
-			return wrapIdentifier(loc, b.Ref)
-		}
-		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: b.Ref}}
-
-	case *js_ast.BArray:
-		exprs := make([]js_ast.Expr, len(b.Items))
-		for i, item := range b.Items {
-			expr := p.convertBindingToExpr(item.Binding, wrapIdentifier)
-			if b.HasSpread && i+1 == len(b.Items) {
-				expr = js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ESpread{Value: expr}}
-			} else if item.DefaultValue != nil {
-				expr = js_ast.Assign(expr, *item.DefaultValue)
-			}
-			exprs[i] = expr
-		}
-		return js_ast.Expr{Loc: loc, Data: &js_ast.EArray{
-			Items:        exprs,
-			IsSingleLine: b.IsSingleLine,
-		}}
-
-	case *js_ast.BObject:
-		properties := make([]js_ast.Property, len(b.Properties))
-		for i, property := range b.Properties {
-			value := p.convertBindingToExpr(property.Value, wrapIdentifier)
-			kind := js_ast.PropertyNormal
-			if property.IsSpread {
-				kind = js_ast.PropertySpread
-			}
-			properties[i] = js_ast.Property{
-				Kind:        kind,
-				IsComputed:  property.IsComputed,
-				Key:         property.Key,
-				Value:       &value,
-				Initializer: property.DefaultValue,
-			}
-		}
-		return js_ast.Expr{Loc: loc, Data: &js_ast.EObject{
-			Properties:   properties,
-			IsSingleLine: b.IsSingleLine,
-		}}
-
-	default:
-		panic("Internal error")
-	}
-}
-
 type exprFlag uint8
 
 const (
@@ -3365,7 +3323,7 @@ func (p *parser) parseSuffix(left js_ast.Expr, level js_ast.L, errors *deferredE
 			// Warn about "!a in b" instead of "!(a in b)"
 			if !p.SuppressWarningsAboutWeirdCode {
 				if e, ok := left.Data.(*js_ast.EUnary); ok && e.Op == js_ast.UnOpNot {
-					p.log.AddWarning(&p.source, left.Loc,
+					p.log.AddWarning(logger.MsgIDSuspiciousBooleanNot, &p.source, left.Loc,
 						"Suspicious use of the \"!\" operator inside the \"in\" operator")
 				}
 			}
@@ -3382,7 +3340,7 @@ func (p *parser) parseSuffix(left js_ast.Expr, level js_ast.L, errors *deferredE
 			// example of code with this problem: https://github.com/mrdoob/three.js/pull/11182.
 			if !p.SuppressWarningsAboutWeirdCode {
 				if e, ok := left.Data.(*js_ast.EUnary); ok && e.Op == js_ast.UnOpNot {
-					p.log.AddWarning(&p.source, left.Loc,
+					p.log.AddWarning(logger.MsgIDSuspiciousBooleanNot, &p.source, left.Loc,
 						"Suspicious use of the \"!\" operator inside the \"instanceof\" operator")
 				}
 			}
@@ -5618,7 +5576,7 @@ func (p *parser) parseStmtsUpTo(end js_lexer.T, opts parseStmtOpts) []js_ast.Stm
 			} else {
 				if returnWithoutSemicolonStart != -1 {
 					if _, ok := stmt.Data.(*js_ast.SExpr); ok {
-						p.log.AddWarning(&p.source, logger.Loc{Start: returnWithoutSemicolonStart + 6},
+						p.log.AddWarning(logger.MsgIDSemicolonAfterReturn, &p.source, logger.Loc{Start: returnWithoutSemicolonStart + 6},
 							"The following expression is not returned because of an automatically-inserted semicolon")
 					}
 				}
@@ -6892,7 +6850,7 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			}
 			for _, decl := range s.Decls {
 				if decl.Value != nil {
-					target := p.convertBindingToExpr(decl.Binding, wrapIdentifier)
+					target := js_ast.ConvertBindingToExpr(decl.Binding, wrapIdentifier)
 					if result, ok := p.lowerObjectRestInAssign(target, *decl.Value); ok {
 						target = result
 					} else {
@@ -7635,7 +7593,7 @@ func (p *parser) checkForTypeofAndString(a js_ast.Expr, b js_ast.Expr) bool {
 				// https://github.com/olifolkerd/tabulator/issues/2962
 				if !p.SuppressWarningsAboutWeirdCode {
 					r := p.source.RangeOfString(b.Loc)
-					p.log.AddRangeWarning(&p.source, r, fmt.Sprintf("The \"typeof\" operator will never evaluate to %q", value))
+					p.log.AddRangeWarning(logger.MsgIDImpossibleTypeof, &p.source, r, fmt.Sprintf("The \"typeof\" operator will never evaluate to %q", value))
 				}
 			}
 			return true
@@ -7663,7 +7621,7 @@ func (p *parser) warnAboutEqualityCheck(op string, value js_ast.Expr, afterOpLoc
 			if op == "case" {
 				text = "Comparison with -0 using a case clause will also match 0"
 			}
-			p.log.AddRangeWarning(&p.source, r, text)
+			p.log.AddRangeWarning(logger.MsgIDEqualsNegativeZero, &p.source, r, text)
 			return true
 		}
 
@@ -7673,7 +7631,7 @@ func (p *parser) warnAboutEqualityCheck(op string, value js_ast.Expr, afterOpLoc
 			if op == "case" {
 				text = "This case clause will never be evaluated because equality with NaN is always false"
 			}
-			p.log.AddRangeWarning(&p.source, p.source.RangeOfOperatorBefore(afterOpLoc, op), text)
+			p.log.AddRangeWarning(logger.MsgIDEqualsNaN, &p.source, p.source.RangeOfOperatorBefore(afterOpLoc, op), text)
 			return true
 		}
 
@@ -7688,7 +7646,7 @@ func (p *parser) warnAboutEqualityCheck(op string, value js_ast.Expr, afterOpLoc
 			if op == "case" {
 				text = "This case clause will never be evaluated because the comparison is always false"
 			}
-			p.log.AddRangeWarning(&p.source, p.source.RangeOfOperatorBefore(afterOpLoc, op), text)
+			p.log.AddRangeWarning(logger.MsgIDEqualsNewObject, &p.source, p.source.RangeOfOperatorBefore(afterOpLoc, op), text)
 			return true
 		}
 	}
@@ -7703,7 +7661,7 @@ func (p *parser) warnAboutLackOfDefine(name string, r logger.Range) {
 		}
 		if !p.lackOfDefineWarnings[name] {
 			p.lackOfDefineWarnings[name] = true
-			p.log.AddRangeWarning(&p.source, r,
+			p.log.AddRangeWarning(logger.MsgIDSuspiciousDefine, &p.source, r,
 				fmt.Sprintf("Define %q when bundling for the browser", name))
 		}
 	}
@@ -8100,7 +8058,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		// Warn about assigning to a constant
 		if in.assignTarget != js_ast.AssignTargetNone && p.symbols[result.ref.InnerIndex].Kind == js_ast.SymbolConst {
 			r := js_lexer.RangeOfIdentifier(p.source, expr.Loc)
-			p.log.AddRangeWarning(&p.source, r, fmt.Sprintf("This assignment will throw because %q is a constant", name))
+			p.log.AddRangeWarning(logger.MsgIDAssignToConstant, &p.source, r, fmt.Sprintf("This assignment will throw because %q is a constant", name))
 		}
 
 		// Substitute user-specified defines for unbound symbols
@@ -8637,10 +8595,10 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			} else if !p.SuppressWarningsAboutWeirdCode {
 				if in.assignTarget != js_ast.AssignTargetNone && (kind == js_ast.SymbolPrivateGet || kind == js_ast.SymbolPrivateStaticGet) {
 					r := logger.Range{Loc: e.Index.Loc, Len: int32(len(name))}
-					p.log.AddRangeWarning(&p.source, r, fmt.Sprintf("Writing to getter-only property %q will throw", name))
+					p.log.AddRangeWarning(logger.MsgIDPrivateNameWillThrow, &p.source, r, fmt.Sprintf("Writing to getter-only property %q will throw", name))
 				} else if in.assignTarget != js_ast.AssignTargetReplace && (kind == js_ast.SymbolPrivateSet || kind == js_ast.SymbolPrivateStaticSet) {
 					r := logger.Range{Loc: e.Index.Loc, Len: int32(len(name))}
-					p.log.AddRangeWarning(&p.source, r, fmt.Sprintf("Reading from setter-only property %q will throw", name))
+					p.log.AddRangeWarning(logger.MsgIDPrivateNameWillThrow, &p.source, r, fmt.Sprintf("Reading from setter-only property %q will throw", name))
 				}
 			}
 
@@ -8734,7 +8692,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 			if !p.SuppressWarningsAboutWeirdCode && superPropLoc.Start != 0 {
 				r := js_lexer.RangeOfIdentifier(p.source, superPropLoc)
-				p.log.AddRangeWarning(&p.source, r, "Attempting to delete a property of \"super\" will throw a ReferenceError")
+				p.log.AddRangeWarning(logger.MsgIDDeleteSuperProperty, &p.source, r, "Attempting to delete a property of \"super\" will throw a ReferenceError")
 			}
 
 			p.deleteTarget = e.Value.Data
@@ -9049,7 +9007,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			e.ImportRecordIndex = &importRecordIndex
 		} else if p.Mode == config.ModeBundle {
 			r := js_lexer.RangeOfIdentifier(p.source, expr.Loc)
-			p.log.AddRangeWarning(&p.source, r,
+			p.log.AddRangeWarning(logger.MsgIDUnsupportedDynamicImport, &p.source, r,
 				"This dynamic import will not be bundled because the argument is not a string literal")
 		}
 
@@ -9188,7 +9146,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 					// There must be one argument
 					if len(e.Args) != 1 {
 						r := js_lexer.RangeOfIdentifier(p.source, e.Target.Loc)
-						p.log.AddRangeWarning(&p.source, r, fmt.Sprintf(
+						p.log.AddRangeWarning(logger.MsgIDUnsupportedRequireCall, &p.source, r, fmt.Sprintf(
 							"This call to \"require\" will not be bundled because it has %d arguments", len(e.Args)))
 					} else {
 						arg := e.Args[0]
@@ -9212,12 +9170,13 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 						}
 
 						r := js_lexer.RangeOfIdentifier(p.source, e.Target.Loc)
-						p.log.AddRangeWarning(&p.source, r,
+						p.log.AddRangeWarning(logger.MsgIDUnsupportedRequireCall, &p.source, r,
 							"This call to \"require\" will not be bundled because the argument is not a string literal")
 					}
-				} else if p.OutputFormat == config.FormatESModule {
+				} else if p.OutputFormat == config.FormatESModule || p.OutputFormat == config.FormatSystem {
 					r := js_lexer.RangeOfIdentifier(p.source, e.Target.Loc)
-					p.log.AddRangeWarning(&p.source, r, "Converting \"require\" to \"esm\" is currently not supported")
+					p.log.AddRangeWarning(logger.MsgIDUnsupportedRequireCall, &p.source, r,
+						fmt.Sprintf("Converting \"require\" to %q is currently not supported", p.OutputFormat.String()))
 				}
 			}
 		}
@@ -9247,6 +9206,8 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		if e.IsAsync {
 			p.fnOnlyDataVisit.isInsideAsyncArrowFn = true
 		}
+		oldInsideFnOrArrow := p.fnOnlyDataVisit.isInsideFnOrArrow
+		p.fnOnlyDataVisit.isInsideFnOrArrow = true
 
 		p.pushScopeForVisitPass(js_ast.ScopeFunctionArgs, expr.Loc)
 		p.visitArgs(e.Args)
@@ -9269,6 +9230,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		}
 
 		p.fnOnlyDataVisit.isInsideAsyncArrowFn = oldInsideAsyncArrowFn
+		p.fnOnlyDataVisit.isInsideFnOrArrow = oldInsideFnOrArrow
 		p.fnOrArrowDataVisit = oldFnOrArrowData
 
 		// Convert arrow functions to function expressions when lowering
@@ -9347,6 +9309,15 @@ func (p *parser) handleIdentifier(loc logger.Loc, assignTarget js_ast.AssignTarg
 		}
 	}
 
+	// Remember the first read of an import during module evaluation
+	if p.Mode == config.ModeBundle && !p.fnOnlyDataVisit.isInsideFnOrArrow {
+		if _, ok := p.topLevelImportReads[ref]; !ok {
+			if _, ok := p.importItemsForNamespace[ref]; ok || p.isImportItem[ref] {
+				p.topLevelImportReads[ref] = js_lexer.RangeOfIdentifier(p.source, loc)
+			}
+		}
+	}
+
 	// Substitute an EImportIdentifier now if this is an import item
 	if p.isImportItem[ref] {
 		return js_ast.Expr{Loc: loc, Data: &js_ast.EImportIdentifier{Ref: ref}}
@@ -9384,7 +9355,7 @@ func (p *parser) handleIdentifier(loc logger.Loc, assignTarget js_ast.AssignTarg
 			}
 		} else if e != p.resolveCallTarget {
 			r := js_lexer.RangeOfIdentifier(p.source, loc)
-			p.log.AddRangeWarning(&p.source, r,
+			p.log.AddRangeWarning(logger.MsgIDUnsupportedRequireCall, &p.source, r,
 				"Indirect calls to \"require\" will not be bundled (surround with a try/catch to silence this warning)")
 		}
 	}
@@ -9408,8 +9379,9 @@ func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc) {
 		isAsync: fn.IsAsync,
 	}
 	p.fnOnlyDataVisit = fnOnlyDataVisit{
-		isThisNested: true,
-		argumentsRef: &fn.ArgumentsRef,
+		isThisNested:      true,
+		isInsideFnOrArrow: true,
+		argumentsRef:      &fn.ArgumentsRef,
 	}
 
 	if fn.Name != nil {
@@ -9428,12 +9400,39 @@ func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc) {
 	p.fnOnlyDataVisit = oldFnOnlyData
 }
 
+// This must be called before unused imports are removed from the statement
+func (p *parser) recordTopLevelImportRead(s *js_ast.SImport) {
+	record := &p.importRecords[s.ImportRecordIndex]
+	check := func(ref js_ast.Ref) {
+		if r, ok := p.topLevelImportReads[ref]; ok && (record.TopLevelReadRange == nil || r.Loc.Start < record.TopLevelReadRange.Loc.Start) {
+			record.TopLevelReadRange = &r
+		}
+	}
+
+	if s.DefaultName != nil {
+		check(s.DefaultName.Ref)
+	}
+	if s.Items != nil {
+		for _, item := range *s.Items {
+			check(item.Name.Ref)
+		}
+	}
+
+	// This covers both "ns" and property accesses like "ns.foo"
+	check(s.NamespaceRef)
+	for _, item := range p.importItemsForNamespace[s.NamespaceRef] {
+		check(item.Ref)
+	}
+}
+
 func (p *parser) scanForImportsAndExports(stmts []js_ast.Stmt) []js_ast.Stmt {
 	stmtsEnd := 0
 
 	for _, stmt := range stmts {
 		switch s := stmt.Data.(type) {
 		case *js_ast.SImport:
+			p.recordTopLevelImportRead(s)
+
 			// TypeScript always trims unused imports. This is important for
 			// correctness since some imports might be fake (only in the type
 			// system and used for type-only imports).
@@ -10146,6 +10145,7 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 		isImportItem:            make(map[js_ast.Ref]bool),
 		namedImports:            make(map[js_ast.Ref]js_ast.NamedImport),
 		namedExports:            make(map[string]js_ast.Ref),
+		topLevelImportReads:     make(map[js_ast.Ref]logger.Range),
 	}
 
 	p.findSymbolHelper = func(loc logger.Loc, name string) js_ast.Ref { return p.findSymbol(loc, name).ref }
@@ -10209,11 +10209,30 @@ func Parse(log logger.Log, source logger.Source, options config.Options) (result
 	// happens when bundling, in which case we are flatting the module scopes of
 	// all modules together anyway so such directives are meaningless.
 	if p.importMetaRef != js_ast.InvalidRef {
+		importMetaValue := js_ast.Expr{Data: &js_ast.EObject{}}
+
+		// With hot module replacement, "import.meta.hot" is the "hot" property
+		// that the runtime adds to the module object:
+		//
+		//   const import_meta = {hot: module.hot};
+		//
+		// This statement is visited along with the others, so the identifier is
+		// stored by name and bound to the "module" symbol during the visit pass.
+		if p.HotModuleReplacement {
+			importMetaValue.Data = &js_ast.EObject{Properties: []js_ast.Property{{
+				Key: js_ast.Expr{Data: &js_ast.EString{Value: js_lexer.StringToUTF16("hot")}},
+				Value: &js_ast.Expr{Data: &js_ast.EDot{
+					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: p.storeNameInRef("module")}},
+					Name:   "hot",
+				}},
+			}}}
+		}
+
 		importMetaStmt := js_ast.Stmt{Data: &js_ast.SLocal{
 			Kind: js_ast.LocalConst,
 			Decls: []js_ast.Decl{{
 				Binding: js_ast.Binding{Data: &js_ast.BIdentifier{Ref: p.importMetaRef}},
-				Value:   &js_ast.Expr{Data: &js_ast.EObject{}},
+				Value:   &importMetaValue,
 			}},
 		}}
 		stmts = append(append(make([]js_ast.Stmt, 0, len(stmts)+1), importMetaStmt), stmts...)
@@ -10313,7 +10332,7 @@ func (p *parser) validateJSX(span js_ast.Span, name string) []string {
 	parts := strings.Split(span.Text, ".")
 	for _, part := range parts {
 		if !js_lexer.IsIdentifier(part) {
-			p.log.AddRangeWarning(&p.source, span.Range, fmt.Sprintf("Invalid JSX %s: %s", name, span.Text))
+			p.log.AddRangeWarning(logger.MsgIDUnsupportedJSXComment, &p.source, span.Range, fmt.Sprintf("Invalid JSX %s: %s", name, span.Text))
 			return nil
 		}
 	}
@@ -10336,7 +10355,8 @@ func (p *parser) prepareForVisitPass(options *config.Options) {
 	}
 
 	// Convert "import.meta" to a variable if it's not supported in the output format
-	if p.hasImportMeta && (p.UnsupportedJSFeatures.Has(compat.ImportMeta) || (p.Mode != config.ModePassThrough && !p.OutputFormat.KeepES6ImportExportSyntax())) {
+	if p.hasImportMeta && (p.UnsupportedJSFeatures.Has(compat.ImportMeta) || p.HotModuleReplacement ||
+		(p.Mode != config.ModePassThrough && !p.OutputFormat.KeepES6ImportExportSyntax())) {
 		p.importMetaRef = p.newSymbol(js_ast.SymbolOther, "import_meta")
 		p.moduleScope.Generated = append(p.moduleScope.Generated, p.importMetaRef)
 	} else {
@@ -102,7 +102,7 @@ func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (di
 
 	case compat.ImportMeta:
 		// This can't be polyfilled
-		p.log.AddRangeWarning(&p.source, r,
+		p.log.AddRangeWarning(logger.MsgIDEmptyImportMeta, &p.source, r,
 			fmt.Sprintf("\"import.meta\" is not available in %s and will be empty", where))
 		return
 
@@ -191,7 +191,7 @@ func (p *parser) lowerFunction(
 		for i, arg := range *args {
 			if bindingHasObjectRest(arg.Binding) {
 				ref := p.generateTempRef(tempRefNoDeclare, "")
-				target := p.convertBindingToExpr(arg.Binding, nil)
+				target := js_ast.ConvertBindingToExpr(arg.Binding, nil)
 				init := js_ast.Expr{Loc: arg.Binding.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
 				p.recordUsage(ref)
 
@@ -1039,7 +1039,7 @@ func (p *parser) lowerObjectRestInDecls(decls []js_ast.Decl) []js_ast.Decl {
 			clone := append([]js_ast.Decl{}, decls[:i]...)
 			for _, decl := range decls[i:] {
 				if decl.Value != nil {
-					target := p.convertBindingToExpr(decl.Binding, nil)
+					target := js_ast.ConvertBindingToExpr(decl.Binding, nil)
 					if result, ok := p.lowerObjectRestToDecls(target, *decl.Value, clone); ok {
 						clone = result
 						continue
@@ -418,32 +418,32 @@ func TestScope(t *testing.T) {
 	expectParseError(t, "let x; let y", "")
 
 	expectParseError(t, "var x; var x", "")
-	expectParseError(t, "var x; let x", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "let x; var x", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "let x; let x", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "function x() {} let x", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "let x; function x() {}", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "var x; let x", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "let x; var x", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "let x; let x", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "function x() {} let x", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "let x; function x() {}", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 
 	expectParseError(t, "var x; {var x}", "")
 	expectParseError(t, "var x; {let x}", "")
-	expectParseError(t, "let x; {var x}", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "let x; {var x}", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 	expectParseError(t, "let x; {let x}", "")
 	expectParseError(t, "let x; {function x() {}}", "")
 
 	expectParseError(t, "{var x} var x", "")
-	expectParseError(t, "{var x} let x", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "{var x} let x", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 	expectParseError(t, "{let x} var x", "")
 	expectParseError(t, "{let x} let x", "")
 	expectParseError(t, "{function x() {}} let x", "")
 
 	expectParseError(t, "{var x; {var x}}", "")
 	expectParseError(t, "{var x; {let x}}", "")
-	expectParseError(t, "{let x; {var x}}", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "{let x; {var x}}", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 	expectParseError(t, "{let x; {let x}}", "")
 	expectParseError(t, "{let x; {function x() {}}}", "")
 
 	expectParseError(t, "{{var x} var x}", "")
-	expectParseError(t, "{{var x} let x}", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "{{var x} let x}", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 	expectParseError(t, "{{let x} var x}", "")
 	expectParseError(t, "{{let x} let x}", "")
 	expectParseError(t, "{{function x() {}} let x}", "")
@@ -456,19 +456,19 @@ func TestScope(t *testing.T) {
 	expectParseError(t, "{function x() {}} {let x}", "")
 
 	expectParseError(t, "var x=1, x=2", "")
-	expectParseError(t, "let x=1, x=2", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "const x=1, x=2", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "let x=1, x=2", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "const x=1, x=2", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 
 	expectParseError(t, "function foo(x) { var x }", "")
-	expectParseError(t, "function foo(x) { let x }", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "function foo(x) { const x = 0 }", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "function foo(x) { let x }", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "function foo(x) { const x = 0 }", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 	expectParseError(t, "function foo() { var foo }", "")
 	expectParseError(t, "function foo() { let foo }", "")
 	expectParseError(t, "function foo() { const foo = 0 }", "")
 
 	expectParseError(t, "(function foo(x) { var x })", "")
-	expectParseError(t, "(function foo(x) { let x })", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "(function foo(x) { const x = 0 })", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "(function foo(x) { let x })", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "(function foo(x) { const x = 0 })", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 	expectParseError(t, "(function foo() { var foo })", "")
 	expectParseError(t, "(function foo() { let foo })", "")
 	expectParseError(t, "(function foo() { const foo = 0 })", "")
@@ -476,18 +476,18 @@ func TestScope(t *testing.T) {
 	expectParseError(t, "var x; function x() {}", "")
 	expectParseError(t, "var x; function *x() {}", "")
 	expectParseError(t, "var x; async function x() {}", "")
-	expectParseError(t, "let x; function x() {}", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "let x; function x() {}", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 	expectParseError(t, "function x() {} var x", "")
 	expectParseError(t, "function* x() {} var x", "")
 	expectParseError(t, "async function x() {} var x", "")
-	expectParseError(t, "function x() {} let x", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "function x() {} let x", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 	expectParseError(t, "function x() {} function x() {}", "")
 
-	expectParseError(t, "var x; class x {}", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "let x; class x {}", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "class x {} var x", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "class x {} let x", "<stdin>: error: \"x\" has already been declared\n")
-	expectParseError(t, "class x {} class x {}", "<stdin>: error: \"x\" has already been declared\n")
+	expectParseError(t, "var x; class x {}", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "let x; class x {}", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "class x {} var x", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "class x {} let x", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
+	expectParseError(t, "class x {} class x {}", "<stdin>: error: \"x\" has already been declared\n<stdin>: note: \"x\" was originally declared here\n")
 }
 
 func TestASI(t *testing.T) {
@@ -1510,11 +1510,11 @@ func TestCatch(t *testing.T) {
 	expectPrinted(t, "try {} catch (e) { if (1) function e() {} }", "try {\n} catch (e) {\n  if (1)\n    function e() {\n    }\n}\n")
 	expectPrinted(t, "try {} catch (e) { if (0) ; else function e() {} }", "try {\n} catch (e) {\n  if (0)\n    ;\n  else\n    function e() {\n    }\n}\n")
 
-	expectParseError(t, "try {} catch (e) { function e() {} }", "<stdin>: error: \"e\" has already been declared\n")
-	expectParseError(t, "try {} catch ({e}) { var e }", "<stdin>: error: \"e\" has already been declared\n")
-	expectParseError(t, "try {} catch ({e}) { function e() {} }", "<stdin>: error: \"e\" has already been declared\n")
-	expectParseError(t, "try {} catch (e) { let e }", "<stdin>: error: \"e\" has already been declared\n")
-	expectParseError(t, "try {} catch (e) { const e = 0 }", "<stdin>: error: \"e\" has already been declared\n")
+	expectParseError(t, "try {} catch (e) { function e() {} }", "<stdin>: error: \"e\" has already been declared\n<stdin>: note: \"e\" was originally declared here\n")
+	expectParseError(t, "try {} catch ({e}) { var e }", "<stdin>: error: \"e\" has already been declared\n<stdin>: note: \"e\" was originally declared here\n")
+	expectParseError(t, "try {} catch ({e}) { function e() {} }", "<stdin>: error: \"e\" has already been declared\n<stdin>: note: \"e\" was originally declared here\n")
+	expectParseError(t, "try {} catch (e) { let e }", "<stdin>: error: \"e\" has already been declared\n<stdin>: note: \"e\" was originally declared here\n")
+	expectParseError(t, "try {} catch (e) { const e = 0 }", "<stdin>: error: \"e\" has already been declared\n<stdin>: note: \"e\" was originally declared here\n")
 }
 
 func TestWarningEqualsNegativeZero(t *testing.T) {
@@ -3008,19 +3008,19 @@ func TestPrivateIdentifiers(t *testing.T) {
 	expectParseError(t, "class Foo { #fo\\u0020 }", "<stdin>: error: Invalid identifier: \"#fo \"\n")
 
 	// Scope tests
-	expectParseError(t, "class Foo { #foo; #foo }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { #foo; static #foo }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { static #foo; #foo }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { #foo; #foo() {} }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { #foo; get #foo() {} }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { #foo; set #foo(x) {} }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { #foo() {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { get #foo() {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { set #foo(x) {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { get #foo() {} get #foo() {} }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { set #foo(x) {} set #foo(x) {} }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { get #foo() {} set #foo(x) {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n")
-	expectParseError(t, "class Foo { set #foo(x) {} get #foo() {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n")
+	expectParseError(t, "class Foo { #foo; #foo }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { #foo; static #foo }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { static #foo; #foo }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { #foo; #foo() {} }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { #foo; get #foo() {} }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { #foo; set #foo(x) {} }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { #foo() {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { get #foo() {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { set #foo(x) {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { get #foo() {} get #foo() {} }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { set #foo(x) {} set #foo(x) {} }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { get #foo() {} set #foo(x) {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
+	expectParseError(t, "class Foo { set #foo(x) {} get #foo() {} #foo }", "<stdin>: error: \"#foo\" has already been declared\n<stdin>: note: \"#foo\" was originally declared here\n")
 	expectPrinted(t, "class Foo { get #foo() {} set #foo(x) { this.#foo } }",
 		"class Foo {\n  get #foo() {\n  }\n  set #foo(x) {\n    this.#foo;\n  }\n}\n")
 	expectPrinted(t, "class Foo { set #foo(x) { this.#foo } get #foo() {} }",
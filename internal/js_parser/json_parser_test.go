@@ -150,7 +150,7 @@ func TestJSONNumber(t *testing.T) {
 func TestJSONObject(t *testing.T) {
 	expectPrintedJSON(t, "{\"x\":0}", "{x:0}")
 	expectPrintedJSON(t, "{\"x\":0,\"y\":1}", "{x:0,y:1}")
-	expectPrintedJSONWithWarning(t, "{\"x\":0,\"x\":1}", "<stdin>: warning: Duplicate key: \"x\"\n", "{x:0,x:1}")
+	expectPrintedJSONWithWarning(t, "{\"x\":0,\"x\":1}", "<stdin>: warning: Duplicate key: \"x\"\n<stdin>: note: The original \"x\" is here\n", "{x:0,x:1}")
 	expectParseErrorJSON(t, "{\"x\":0,}", "<stdin>: error: JSON does not support trailing commas\n")
 	expectParseErrorJSON(t, "{x:0}", "<stdin>: error: Expected string but found \"x\"\n")
 	expectParseErrorJSON(t, "{1:0}", "<stdin>: error: Expected string but found \"1\"\n")
@@ -34,7 +34,7 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 
 		switch js_lexer.UTF16ToString(prop.Key.Data.(*js_ast.EString).Value) {
 		case "sections":
-			log.AddRangeWarning(&source, keyRange, "Source maps with \"sections\" are not supported")
+			log.AddRangeWarning(logger.MsgIDUnsupportedSourceMapComment, &source, keyRange, "Source maps with \"sections\" are not supported")
 			return nil
 
 		case "version":
@@ -213,7 +213,7 @@ func ParseSourceMap(log logger.Log, source logger.Source) *sourcemap.SourceMap {
 
 	if errorText != "" {
 		r := logger.Range{Loc: logger.Loc{Start: mappingsStart + int32(current)}, Len: int32(errorLen)}
-		log.AddRangeWarning(&source, r,
+		log.AddRangeWarning(logger.MsgIDInvalidSourceMappings, &source, r,
 			fmt.Sprintf("Bad \"mappings\" data in source map at character %d: %s", current, errorText))
 		return nil
 	}
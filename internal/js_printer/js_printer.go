@@ -523,6 +523,10 @@ type printer struct {
 	prevRegExpEnd      int
 	intToBytesBuffer   [64]byte
 
+	// This is the assignment currently being wrapped in calls to the SystemJS
+	// "exports" function, so it isn't wrapped again when it's printed
+	systemExportAssign js_ast.E
+
 	// For source maps
 	sourceMap           []byte
 	prevLoc             logger.Loc
@@ -806,10 +810,160 @@ func (p *printer) printIndent() {
 }
 
 func (p *printer) printSymbol(ref js_ast.Ref) {
+	// Symbols from other chunks may be accessed as properties
+	if alias, ok := p.crossChunkImportAlias(ref); ok {
+		p.printSymbol(alias.NamespaceRef)
+		p.printDotThenAlias(alias.Alias)
+		return
+	}
+
 	p.printSpaceBeforeIdentifier()
 	p.printIdentifier(p.renamer.NameForSymbol(ref))
 }
 
+func (p *printer) crossChunkImportAlias(ref js_ast.Ref) (js_ast.NamespaceAlias, bool) {
+	if p.options.CrossChunkImports == nil {
+		return js_ast.NamespaceAlias{}, false
+	}
+	alias, ok := p.options.CrossChunkImports[js_ast.FollowSymbols(p.symbols, ref)]
+	return alias, ok
+}
+
+func (p *printer) systemExportAliases(ref js_ast.Ref) []string {
+	if p.options.SystemExports == nil {
+		return nil
+	}
+	return p.options.SystemExports[js_ast.FollowSymbols(p.symbols, ref)]
+}
+
+// This appends the exported identifiers in an assignment target to "refs"
+func (p *printer) systemExportsInAssignTarget(target js_ast.Expr, refs []js_ast.Ref) []js_ast.Ref {
+	switch t := target.Data.(type) {
+	case *js_ast.EIdentifier:
+		if p.systemExportAliases(t.Ref) != nil {
+			refs = append(refs, t.Ref)
+		}
+
+	case *js_ast.EArray:
+		for _, item := range t.Items {
+			refs = p.systemExportsInAssignTarget(item, refs)
+		}
+
+	case *js_ast.EObject:
+		for _, property := range t.Properties {
+			if property.Value != nil {
+				refs = p.systemExportsInAssignTarget(*property.Value, refs)
+			}
+		}
+
+	case *js_ast.ESpread:
+		refs = p.systemExportsInAssignTarget(t.Value, refs)
+
+	case *js_ast.EBinary:
+		if t.Op == js_ast.BinOpAssign {
+			refs = p.systemExportsInAssignTarget(t.Left, refs)
+		}
+	}
+	return refs
+}
+
+// With the SystemJS format, other modules only see changes to an exported
+// variable if the "exports" function is called with the new value. This
+// prints assignments to exported variables wrapped in calls to that function
+// and returns false if the expression isn't one of those assignments:
+// "a = b" becomes "exports('a', a = b)" and "a++" becomes "[a++, exports('a',
+// a)][0]" since the old value is the result of the expression.
+func (p *printer) printSystemExportAssign(expr js_ast.Expr, flags int) bool {
+	if p.options.SystemExports == nil || expr.Data == p.systemExportAssign {
+		return false
+	}
+
+	var target js_ast.Expr
+	valueIsResult := false
+	switch e := expr.Data.(type) {
+	case *js_ast.EUnary:
+		if e.Op.UnaryAssignTarget() == js_ast.AssignTargetNone {
+			return false
+		}
+		target = e.Value
+		valueIsResult = e.Op.IsPrefix()
+
+	case *js_ast.EBinary:
+		if e.Op.BinaryAssignTarget() == js_ast.AssignTargetNone {
+			return false
+		}
+		target = e.Left
+		_, valueIsResult = e.Left.Data.(*js_ast.EIdentifier)
+
+	default:
+		return false
+	}
+
+	refs := p.systemExportsInAssignTarget(target, nil)
+	if len(refs) == 0 {
+		return false
+	}
+
+	// The expression is printed again inside the wrapper without wrapping it
+	p.systemExportAssign = expr.Data
+	defer func() { p.systemExportAssign = nil }()
+
+	// A single identifier whose new value is the result of the expression can
+	// be passed directly to "exports()", which returns the value
+	if valueIsResult {
+		aliases := p.systemExportAliases(refs[0])
+		wrap := (flags & forbidCall) != 0
+		if wrap {
+			p.print("(")
+		}
+		for i := len(aliases) - 1; i >= 0; i-- {
+			p.printSystemExportCallPrefix(aliases[i])
+		}
+		p.printExpr(expr, js_ast.LComma, 0)
+		for range aliases {
+			p.print(")")
+		}
+		if wrap {
+			p.print(")")
+		}
+		return true
+	}
+
+	// Otherwise the expression is evaluated first and its result is kept
+	p.print("[")
+	p.printExpr(expr, js_ast.LComma, 0)
+	for _, ref := range refs {
+		for _, alias := range p.systemExportAliases(ref) {
+			p.print(",")
+			p.printSpace()
+			p.printSystemExportCallPrefix(alias)
+			p.printSymbol(ref)
+			p.print(")")
+		}
+	}
+	p.print("][0]")
+	return true
+}
+
+func (p *printer) printSystemExportCallPrefix(alias string) {
+	p.printSymbol(p.options.SystemExportsRef)
+	p.print("(")
+	p.printQuotedUTF8(alias, true /* allowBacktick */)
+	p.print(",")
+	p.printSpace()
+}
+
+func (p *printer) printDotThenAlias(alias string) {
+	if p.canPrintIdentifier(alias) {
+		p.print(".")
+		p.printIdentifier(alias)
+	} else {
+		p.print("[")
+		p.printQuotedUTF8(alias, true /* allowBacktick */)
+		p.print("]")
+	}
+}
+
 func CanQuoteIdentifier(name string, options *config.Options) bool {
 	return js_lexer.IsIdentifier(name) && (!options.ASCIIOnly ||
 		!options.UnsupportedJSFeatures.Has(compat.UnicodeEscapes) ||
@@ -1225,7 +1379,8 @@ func (p *printer) printProperty(item js_ast.Property) {
 			if !p.options.UnsupportedFeatures.Has(compat.ObjectExtensions) && item.Value != nil {
 				switch e := item.Value.Data.(type) {
 				case *js_ast.EIdentifier:
-					if js_lexer.UTF16EqualsString(key.Value, p.renamer.NameForSymbol(e.Ref)) {
+					// Make sure we're not using a property access instead of an identifier
+					if _, ok := p.crossChunkImportAlias(e.Ref); !ok && js_lexer.UTF16EqualsString(key.Value, p.renamer.NameForSymbol(e.Ref)) {
 						if item.Initializer != nil {
 							p.printSpace()
 							p.print("=")
@@ -1239,7 +1394,8 @@ func (p *printer) printProperty(item js_ast.Property) {
 					// Make sure we're not using a property access instead of an identifier
 					ref := js_ast.FollowSymbols(p.symbols, e.Ref)
 					symbol := p.symbols.Get(ref)
-					if symbol.NamespaceAlias == nil && js_lexer.UTF16EqualsString(key.Value, p.renamer.NameForSymbol(e.Ref)) {
+					_, isCrossChunkImport := p.crossChunkImportAlias(ref)
+					if symbol.NamespaceAlias == nil && !isCrossChunkImport && js_lexer.UTF16EqualsString(key.Value, p.renamer.NameForSymbol(e.Ref)) {
 						if item.Initializer != nil {
 							p.printSpace()
 							p.print("=")
@@ -1342,7 +1498,13 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, leadingInte
 
 	// Preserve "import()" expressions that don't point inside the bundle
 	if record.SourceIndex == nil && record.Kind == ast.ImportDynamic && p.options.OutputFormat.KeepES6ImportExportSyntax() {
-		p.print("import(")
+		if p.options.OutputFormat == config.FormatSystem {
+			// "module.import()"
+			p.printSymbol(p.options.SystemModuleRef)
+			p.print(".import(")
+		} else {
+			p.print("import(")
+		}
 		if len(leadingInteriorComments) > 0 {
 			p.printNewline()
 			p.options.Indent++
@@ -1361,6 +1523,26 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, leadingInte
 		return
 	}
 
+	// Chunks generated by code splitting in the "iife" format are loaded by the
+	// chunk loader, which returns a promise for the exports of that chunk
+	if record.IsChunkImport && p.options.OutputFormat == config.FormatIIFE {
+		// "__import('./chunk.js').then((m) => __toModule(m))"
+		p.printSymbol(p.options.ChunkLoaderRef)
+		p.print("(")
+		p.printQuotedUTF8(record.Path.Text, true /* allowBacktick */)
+		p.print(")")
+		if record.WrapWithToModule {
+			if p.options.RemoveWhitespace {
+				p.print(".then(m=>")
+			} else {
+				p.print(".then((m) => ")
+			}
+			p.printSymbol(p.options.ToModuleRef)
+			p.print("(m))")
+		}
+		return
+	}
+
 	// Make sure "import()" expressions return promises
 	if record.Kind == ast.ImportDynamic {
 		if p.options.RemoveWhitespace {
@@ -1415,6 +1597,10 @@ func (p *printer) printUndefined(level js_ast.L) {
 }
 
 func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags int) {
+	if p.printSystemExportAssign(expr, flags) {
+		return
+	}
+
 	p.addSourceMapping(expr.Loc)
 
 	switch e := expr.Data.(type) {
@@ -1445,7 +1631,13 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags int) {
 
 	case *js_ast.EImportMeta:
 		p.printSpaceBeforeIdentifier()
-		p.print("import.meta")
+		if p.options.OutputFormat == config.FormatSystem {
+			// "module.meta"
+			p.printSymbol(p.options.SystemModuleRef)
+			p.print(".meta")
+		} else {
+			p.print("import.meta")
+		}
 
 	case *js_ast.ENew:
 		wrap := level >= js_ast.LCall
@@ -1967,15 +2159,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags int) {
 			p.printUndefined(level)
 		} else if symbol.NamespaceAlias != nil {
 			p.printSymbol(symbol.NamespaceAlias.NamespaceRef)
-			alias := symbol.NamespaceAlias.Alias
-			if p.canPrintIdentifier(alias) {
-				p.print(".")
-				p.printIdentifier(alias)
-			} else {
-				p.print("[")
-				p.printQuotedUTF8(alias, true /* allowBacktick */)
-				p.print("]")
-			}
+			p.printDotThenAlias(symbol.NamespaceAlias.Alias)
 		} else {
 			p.printSymbol(e.Ref)
 		}
@@ -3033,9 +3217,23 @@ func (p *printer) printStmt(stmt js_ast.Stmt) {
 		p.printSemicolonAfterStatement()
 
 	case *js_ast.SExpr:
+		value := s.Value
+
+		// The result of a postfix update is unused here, so exporting the new
+		// value doesn't need to keep the old one around: "a++" => "++a"
+		if unary, ok := value.Data.(*js_ast.EUnary); ok && p.options.SystemExports != nil && !unary.Op.IsPrefix() {
+			if id, ok := unary.Value.Data.(*js_ast.EIdentifier); ok && p.systemExportAliases(id.Ref) != nil {
+				op := js_ast.UnOpPreInc
+				if unary.Op == js_ast.UnOpPostDec {
+					op = js_ast.UnOpPreDec
+				}
+				value = js_ast.Expr{Loc: value.Loc, Data: &js_ast.EUnary{Op: op, Value: unary.Value}}
+			}
+		}
+
 		p.printIndent()
 		p.stmtStart = len(p.js)
-		p.printExpr(s.Value, js_ast.LLowest, 0)
+		p.printExpr(value, js_ast.LLowest, 0)
 		p.printSemicolonAfterStatement()
 
 	default:
@@ -3061,6 +3259,26 @@ type PrintOptions struct {
 	Indent              int
 	ToModuleRef         js_ast.Ref
 	WrapperRefForSource func(uint32) js_ast.Ref
+
+	// This is the "module" argument passed to the "System.register()" callback.
+	// It's used to implement "import()" and "import.meta" for that format.
+	SystemModuleRef js_ast.Ref
+
+	// This is the function that loads other chunks with the "iife" format when
+	// code splitting is enabled. It's used to implement "import()" expressions.
+	ChunkLoaderRef js_ast.Ref
+
+	// With the SystemJS format, assignments to these symbols also pass the new
+	// value to the "exports" function so that exports are live bindings. Each
+	// symbol maps to the names it's exported as.
+	SystemExports    map[js_ast.Ref][]string
+	SystemExportsRef js_ast.Ref
+
+	// With formats that don't have import statements, symbols imported from
+	// other chunks are properties on the exports object for that chunk. These
+	// symbols are printed as property accesses so that they are live bindings.
+	CrossChunkImports map[js_ast.Ref]js_ast.NamespaceAlias
+
 	UnsupportedFeatures compat.JSFeature
 
 	// This contains the contents of the input file to map back to in the source
@@ -37,9 +37,19 @@ const (
 )
 
 type Msg struct {
+	ID       MsgID
 	Kind     MsgKind
 	Text     string
 	Location *MsgLocation
+
+	// Additional information that is printed after the message. Each note can
+	// optionally point to a different location (e.g. a previous declaration).
+	Notes []MsgData
+}
+
+type MsgData struct {
+	Text     string
+	Location *MsgLocation
 }
 
 type MsgLocation struct {
@@ -260,6 +270,18 @@ func errorAndWarningSummary(errors int, warnings int) string {
 	}
 }
 
+// This is used for file sizes in messages and reports (e.g. "1.5kb")
+func PrettyPrintedBytes(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%db", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1fkb", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1fmb", float64(n)/(1024*1024))
+	}
+}
+
 type TerminalInfo struct {
 	IsTTY           bool
 	UseColorEscapes bool
@@ -267,6 +289,10 @@ type TerminalInfo struct {
 }
 
 func NewStderrLog(options StderrOptions) Log {
+	if options.Format == FormatJSON {
+		return newStderrJSONLog(options)
+	}
+
 	var mutex sync.Mutex
 	var msgs msgsArray
 	terminalInfo := GetTerminalInfo(os.Stderr)
@@ -357,6 +383,8 @@ func PrintMessageToStderr(osArgs []string, msg Msg) {
 			options.LogLevel = LevelError
 		case "--log-level=silent":
 			options.LogLevel = LevelSilent
+		case "--log-format=json":
+			options.Format = FormatJSON
 		}
 	}
 
@@ -408,11 +436,19 @@ const (
 	ColorAlways
 )
 
+type StderrFormat uint8
+
+const (
+	FormatText StderrFormat = iota
+	FormatJSON
+)
+
 type StderrOptions struct {
 	IncludeSource bool
 	ErrorLimit    int
 	Color         StderrColor
 	LogLevel      LogLevel
+	Format        StderrFormat
 }
 
 func (msg Msg) String(options StderrOptions, terminalInfo TerminalInfo) string {
@@ -424,6 +460,15 @@ func (msg Msg) String(options StderrOptions, terminalInfo TerminalInfo) string {
 		kindColor = colorMagenta
 	}
 
+	var sb strings.Builder
+	sb.WriteString(msgString(kind, kindColor, MsgData{Text: msg.Text, Location: msg.Location}, options, terminalInfo))
+	for _, note := range msg.Notes {
+		sb.WriteString(msgString("note", colorBold, note, options, terminalInfo))
+	}
+	return sb.String()
+}
+
+func msgString(kind string, kindColor string, msg MsgData, options StderrOptions, terminalInfo TerminalInfo) string {
 	if msg.Location == nil {
 		if terminalInfo.UseColorEscapes {
 			return fmt.Sprintf("%s%s%s: %s%s%s\n",
@@ -447,7 +492,7 @@ func (msg Msg) String(options StderrOptions, terminalInfo TerminalInfo) string {
 		return fmt.Sprintf("%s: %s: %s\n", msg.Location.File, kind, msg.Text)
 	}
 
-	d := detailStruct(msg, terminalInfo)
+	d := detailStruct(kind, msg, terminalInfo)
 
 	if terminalInfo.UseColorEscapes {
 		return fmt.Sprintf("%s%s:%d:%d: %s%s: %s%s\n%s%s%s%s%s%s\n%s%s%s%s%s\n",
@@ -523,7 +568,7 @@ loop:
 	return
 }
 
-func locationOrNil(source *Source, r Range) *MsgLocation {
+func LocationOrNil(source *Source, r Range) *MsgLocation {
 	if source == nil {
 		return nil
 	}
@@ -540,7 +585,7 @@ func locationOrNil(source *Source, r Range) *MsgLocation {
 	}
 }
 
-func detailStruct(msg Msg, terminalInfo TerminalInfo) MsgDetail {
+func detailStruct(kind string, msg MsgData, terminalInfo TerminalInfo) MsgDetail {
 	// Only highlight the first line of the line text
 	loc := *msg.Location
 	endOfFirstLine := len(loc.LineText)
@@ -656,11 +701,6 @@ func detailStruct(msg Msg, terminalInfo TerminalInfo) MsgDetail {
 		marker = strings.Repeat("~", markerEnd-markerStart)
 	}
 
-	kind := "error"
-	if msg.Kind == Warning {
-		kind = "warning"
-	}
-
 	return MsgDetail{
 		Path:    loc.File,
 		Line:    loc.Line,
@@ -708,15 +748,16 @@ func (log Log) AddError(source *Source, loc Loc, text string) {
 	log.AddMsg(Msg{
 		Kind:     Error,
 		Text:     text,
-		Location: locationOrNil(source, Range{Loc: loc}),
+		Location: LocationOrNil(source, Range{Loc: loc}),
 	})
 }
 
-func (log Log) AddWarning(source *Source, loc Loc, text string) {
+func (log Log) AddWarning(id MsgID, source *Source, loc Loc, text string) {
 	log.AddMsg(Msg{
+		ID:       id,
 		Kind:     Warning,
 		Text:     text,
-		Location: locationOrNil(source, Range{Loc: loc}),
+		Location: LocationOrNil(source, Range{Loc: loc}),
 	})
 }
 
@@ -724,14 +765,43 @@ func (log Log) AddRangeError(source *Source, r Range, text string) {
 	log.AddMsg(Msg{
 		Kind:     Error,
 		Text:     text,
-		Location: locationOrNil(source, r),
+		Location: LocationOrNil(source, r),
+	})
+}
+
+func (log Log) AddRangeWarning(id MsgID, source *Source, r Range, text string) {
+	log.AddMsg(Msg{
+		ID:       id,
+		Kind:     Warning,
+		Text:     text,
+		Location: LocationOrNil(source, r),
+	})
+}
+
+func (log Log) AddRangeErrorWithNotes(source *Source, r Range, text string, notes []MsgData) {
+	log.AddMsg(Msg{
+		Kind:     Error,
+		Text:     text,
+		Location: LocationOrNil(source, r),
+		Notes:    notes,
 	})
 }
 
-func (log Log) AddRangeWarning(source *Source, r Range, text string) {
+func (log Log) AddRangeWarningWithNotes(id MsgID, source *Source, r Range, text string, notes []MsgData) {
 	log.AddMsg(Msg{
+		ID:       id,
 		Kind:     Warning,
 		Text:     text,
-		Location: locationOrNil(source, r),
+		Location: LocationOrNil(source, r),
+		Notes:    notes,
 	})
 }
+
+// This is a convenience function for creating a note that points to a range
+// in a source file
+func RangeData(source *Source, r Range, text string) MsgData {
+	return MsgData{
+		Text:     text,
+		Location: LocationOrNil(source, r),
+	}
+}
@@ -0,0 +1,138 @@
+package logger
+
+// This is an alternative to the terminal-oriented log format that is meant to
+// be consumed by other programs (e.g. to annotate code in a CI system). Each
+// message is written to stderr as a single line containing a JSON object, and
+// a final summary object is written when the log is done.
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+type jsonLocation struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Length   int    `json:"length"`
+	LineText string `json:"lineText"`
+}
+
+type jsonNote struct {
+	Text string `json:"text"`
+	*jsonLocation
+}
+
+type jsonMsg struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id,omitempty"`
+	Text string `json:"text"`
+	*jsonLocation
+	Notes []jsonNote `json:"notes"`
+}
+
+type jsonSummary struct {
+	Kind      string `json:"kind"`
+	Errors    int    `json:"errors"`
+	Warnings  int    `json:"warnings"`
+	ElapsedMS int64  `json:"elapsedMs"`
+}
+
+func jsonLocationOrNil(loc *MsgLocation) *jsonLocation {
+	if loc == nil {
+		return nil
+	}
+	return &jsonLocation{
+		File:     loc.File,
+		Line:     loc.Line,
+		Column:   loc.Column,
+		Length:   loc.Length,
+		LineText: loc.LineText,
+	}
+}
+
+func (msg Msg) JSON() []byte {
+	kind := "error"
+	if msg.Kind == Warning {
+		kind = "warning"
+	}
+	notes := []jsonNote{}
+	for _, note := range msg.Notes {
+		notes = append(notes, jsonNote{Text: note.Text, jsonLocation: jsonLocationOrNil(note.Location)})
+	}
+	bytes, _ := json.Marshal(jsonMsg{
+		Kind:         kind,
+		ID:           string(msg.ID),
+		Text:         msg.Text,
+		jsonLocation: jsonLocationOrNil(msg.Location),
+		Notes:        notes,
+	})
+	return bytes
+}
+
+func newStderrJSONLog(options StderrOptions) Log {
+	return newJSONLog(os.Stderr, options)
+}
+
+// The error limit is deliberately ignored here. It exists to avoid flooding
+// the terminal, which isn't a concern when a program is reading the output.
+func newJSONLog(writer io.Writer, options StderrOptions) Log {
+	var mutex sync.Mutex
+	var msgs msgsArray
+	start := time.Now()
+	errors := 0
+	warnings := 0
+
+	writeLine := func(bytes []byte) {
+		writer.Write(append(bytes, '\n'))
+	}
+
+	return Log{
+		AddMsg: func(msg Msg) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			msgs = append(msgs, msg)
+
+			switch msg.Kind {
+			case Error:
+				errors++
+				if options.LogLevel <= LevelError {
+					writeLine(msg.JSON())
+				}
+			case Warning:
+				warnings++
+				if options.LogLevel <= LevelWarning {
+					writeLine(msg.JSON())
+				}
+			}
+		},
+		HasErrors: func() bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return errors > 0
+		},
+		Done: func() []Msg {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			// Unlike the text format, the summary is always written so consumers
+			// can tell that the output is complete
+			if options.LogLevel != LevelSilent {
+				bytes, _ := json.Marshal(jsonSummary{
+					Kind:      "summary",
+					Errors:    errors,
+					Warnings:  warnings,
+					ElapsedMS: int64(time.Since(start) / time.Millisecond),
+				})
+				writeLine(bytes)
+			}
+
+			sort.Stable(msgs)
+			return msgs
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONLogMessages(t *testing.T) {
+	buffer := bytes.Buffer{}
+	log := newJSONLog(&buffer, StderrOptions{LogLevel: LevelInfo, Format: FormatJSON})
+	log.AddMsg(Msg{
+		ID:   MsgIDDuplicateCase,
+		Kind: Warning,
+		Text: "Duplicate case",
+		Location: &MsgLocation{
+			File:     "file.js",
+			Line:     2,
+			Column:   4,
+			Length:   6,
+			LineText: "  case 1:",
+		},
+		Notes: []MsgData{
+			{Text: "The earlier case is here", Location: &MsgLocation{File: "file.js", Line: 1, Column: 2, Length: 6, LineText: "case 1:"}},
+			{Text: "A note without a location"},
+		},
+	})
+	log.AddMsg(Msg{Kind: Error, Text: "Something went wrong"})
+	msgs := log.Done()
+
+	lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", buffer.String())
+	}
+
+	expected := `{"kind":"warning","id":"duplicate-case","text":"Duplicate case",` +
+		`"file":"file.js","line":2,"column":4,"length":6,"lineText":"  case 1:",` +
+		`"notes":[{"text":"The earlier case is here","file":"file.js","line":1,"column":2,"length":6,"lineText":"case 1:"},` +
+		`{"text":"A note without a location"}]}`
+	if lines[0] != expected {
+		t.Fatalf("Expected %s, got %s", expected, lines[0])
+	}
+
+	// Messages without an identifier or a location leave those fields out
+	expected = `{"kind":"error","text":"Something went wrong","notes":[]}`
+	if lines[1] != expected {
+		t.Fatalf("Expected %s, got %s", expected, lines[1])
+	}
+
+	// The summary comes last
+	var summary map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[2]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	elapsed, ok := summary["elapsedMs"].(float64)
+	if summary["kind"] != "summary" || summary["errors"] != 1.0 || summary["warnings"] != 1.0 || !ok || elapsed < 0 || len(summary) != 4 {
+		t.Fatalf("Unexpected summary: %s", lines[2])
+	}
+
+	// All messages are returned regardless of the log level
+	if len(msgs) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(msgs))
+	}
+}
+
+func TestJSONLogLevel(t *testing.T) {
+	check := func(level LogLevel, expected string) {
+		t.Helper()
+		buffer := bytes.Buffer{}
+		log := newJSONLog(&buffer, StderrOptions{LogLevel: level, Format: FormatJSON})
+		log.AddMsg(Msg{Kind: Warning, Text: "warning"})
+		log.AddMsg(Msg{Kind: Error, Text: "error"})
+		if !log.HasErrors() {
+			t.Fatal("Expected errors")
+		}
+		log.Done()
+
+		// Ignore the elapsed time since it's not deterministic
+		var kinds []string
+		for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n") {
+			var object struct {
+				Kind     string
+				Text     string
+				Errors   int
+				Warnings int
+			}
+			if line == "" {
+				continue
+			}
+			if err := json.Unmarshal([]byte(line), &object); err != nil {
+				t.Fatal(err)
+			}
+			if object.Kind == "summary" {
+				// Filtered messages are still counted
+				if object.Errors != 1 || object.Warnings != 1 {
+					t.Fatalf("Unexpected summary: %s", line)
+				}
+			} else if object.Kind != object.Text {
+				t.Fatalf("Unexpected message: %s", line)
+			}
+			kinds = append(kinds, object.Kind)
+		}
+		if actual := strings.Join(kinds, ","); actual != expected {
+			t.Fatalf("Expected %q, got %q", expected, actual)
+		}
+	}
+
+	check(LevelInfo, "warning,error,summary")
+	check(LevelWarning, "warning,error,summary")
+	check(LevelError, "error,summary")
+	check(LevelSilent, "")
+}
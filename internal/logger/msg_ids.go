@@ -0,0 +1,103 @@
+package logger
+
+// Every warning has a stable identifier that can be used to refer to it from
+// the command line (e.g. "--log-override:duplicate-case=error"). These are
+// part of the public API so they must never be renamed once they've been
+// released. Several closely-related warnings may share the same identifier.
+type MsgID string
+
+const MsgIDNone MsgID = ""
+
+// Identifiers are only ever declared using "newMsgID" so that the list of
+// known identifiers can't get out of sync with the declarations below
+var msgIDs []MsgID
+
+func newMsgID(id string) MsgID {
+	msgIDs = append(msgIDs, MsgID(id))
+	return MsgID(id)
+}
+
+var (
+	// JavaScript
+	MsgIDAssignToConstant            = newMsgID("assign-to-constant")
+	MsgIDDeleteSuperProperty         = newMsgID("delete-super-property")
+	MsgIDDuplicateCase               = newMsgID("duplicate-case")
+	MsgIDDuplicateObjectKey          = newMsgID("duplicate-object-key")
+	MsgIDEmptyImportMeta             = newMsgID("empty-import-meta")
+	MsgIDEqualsNaN                   = newMsgID("equals-nan")
+	MsgIDEqualsNegativeZero          = newMsgID("equals-negative-zero")
+	MsgIDEqualsNewObject             = newMsgID("equals-new-object")
+	MsgIDHTMLCommentInJS             = newMsgID("html-comment-in-js")
+	MsgIDImpossibleTypeof            = newMsgID("impossible-typeof")
+	MsgIDPrivateNameWillThrow        = newMsgID("private-name-will-throw")
+	MsgIDSemicolonAfterReturn        = newMsgID("semicolon-after-return")
+	MsgIDSuspiciousBooleanNot        = newMsgID("suspicious-boolean-not")
+	MsgIDSuspiciousDefine            = newMsgID("suspicious-define")
+	MsgIDUnsupportedDynamicImport    = newMsgID("unsupported-dynamic-import")
+	MsgIDUnsupportedJSXComment       = newMsgID("unsupported-jsx-comment")
+	MsgIDUnsupportedRequireCall      = newMsgID("unsupported-require-call")
+	MsgIDInvalidSourceMappings       = newMsgID("invalid-source-mappings")
+	MsgIDUnsupportedSourceMapComment = newMsgID("unsupported-source-map-comment")
+
+	// CSS
+	MsgIDCSSSyntaxError       = newMsgID("css-syntax-error")
+	MsgIDJSCommentInCSS       = newMsgID("js-comment-in-css")
+	MsgIDUnsupportedAtCharset = newMsgID("unsupported-@charset")
+	MsgIDUnsupportedAtRule    = newMsgID("unsupported-at-rule")
+
+	// Bundler
+	MsgIDDuplicatePackage          = newMsgID("duplicate-package")
+	MsgIDImportCycle               = newMsgID("import-cycle")
+	MsgIDImportCycleHarmless       = newMsgID("import-cycle-harmless")
+	MsgIDImportIsUndefined         = newMsgID("import-is-undefined")
+	MsgIDMissingGlobalName         = newMsgID("missing-global-name")
+	MsgIDRequireResolveNotExternal = newMsgID("require-resolve-not-external")
+
+	// Resolver
+	MsgIDImportMap    = newMsgID("import-map")
+	MsgIDPackageJSON  = newMsgID("package.json")
+	MsgIDTSConfigJSON = newMsgID("tsconfig.json")
+)
+
+var knownMsgIDs map[MsgID]bool
+
+func init() {
+	knownMsgIDs = make(map[MsgID]bool, len(msgIDs))
+	for _, id := range msgIDs {
+		knownMsgIDs[id] = true
+	}
+}
+
+func IsKnownMsgID(id MsgID) bool {
+	return knownMsgIDs[id]
+}
+
+// This changes the severity of messages with certain identifiers before they
+// are passed on to the underlying log. Overriding a message to "LevelSilent"
+// drops it entirely. Only "LevelWarning", "LevelError", and "LevelSilent" are
+// valid overrides.
+func OverrideLog(log Log, overrides map[MsgID]LogLevel) Log {
+	if len(overrides) == 0 {
+		return log
+	}
+
+	return Log{
+		AddMsg: func(msg Msg) {
+			if msg.ID != MsgIDNone {
+				if level, ok := overrides[msg.ID]; ok {
+					switch level {
+					case LevelSilent:
+						return
+					case LevelWarning:
+						msg.Kind = Warning
+					case LevelError:
+						msg.Kind = Error
+					}
+				}
+			}
+			log.AddMsg(msg)
+		},
+		HasErrors: log.HasErrors,
+		Done:      log.Done,
+	}
+}
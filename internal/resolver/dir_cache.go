@@ -0,0 +1,108 @@
+package resolver
+
+import (
+	"sync"
+
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This is a cache of directory information that can be shared between
+// builds. Computing directory information involves parsing "package.json"
+// and "tsconfig.json" files, which can be skipped if nothing that was read
+// while computing it has changed since last time.
+//
+// The cache must only be shared between resolvers that were created with the
+// same options, since the stored information depends on them.
+type DirCache struct {
+	mutex   sync.Mutex
+	entries map[string]*dirCacheEntry
+}
+
+func NewDirCache() *DirCache {
+	return &DirCache{
+		entries: make(map[string]*dirCacheEntry),
+	}
+}
+
+type dirCacheEntry struct {
+	info *dirInfo
+	deps *dirInfoDeps
+}
+
+// This records everything that was read from the file system while computing
+// the information for a single directory, as well as any messages that were
+// logged in the process so they can be logged again when the entry is reused.
+type dirInfoDeps struct {
+	dirs  map[string]dirListing
+	files map[string]fileContents
+	msgs  []logger.Msg
+}
+
+type dirListing struct {
+	names map[string]bool
+	ok    bool
+}
+
+type fileContents struct {
+	contents string
+	ok       bool
+}
+
+func (c *DirCache) get(path string) *dirCacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.entries[path]
+}
+
+func (c *DirCache) set(path string, entry *dirCacheEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[path] = entry
+}
+
+func (deps *dirInfoDeps) recordDirectory(path string, entries map[string]*fs.Entry, err error) {
+	if _, ok := deps.dirs[path]; ok {
+		return
+	}
+	listing := dirListing{ok: err == nil}
+	if err == nil {
+		listing.names = make(map[string]bool, len(entries))
+		for name := range entries {
+			listing.names[name] = true
+		}
+	}
+	deps.dirs[path] = listing
+}
+
+func (deps *dirInfoDeps) recordFile(path string, contents string, err error) {
+	if _, ok := deps.files[path]; ok {
+		return
+	}
+	deps.files[path] = fileContents{contents: contents, ok: err == nil}
+}
+
+// Note that only the names of directory entries are compared, not their kinds.
+// Replacing a file with a directory of the same name is not detected.
+func (deps *dirInfoDeps) isUpToDate(fs fs.FS) bool {
+	for path, listing := range deps.dirs {
+		entries, err := fs.ReadDirectory(path)
+		if (err == nil) != listing.ok || len(entries) != len(listing.names) {
+			return false
+		}
+		for name := range entries {
+			if !listing.names[name] {
+				return false
+			}
+		}
+	}
+
+	for path, file := range deps.files {
+		contents, err := fs.ReadFile(path)
+		if (err == nil) != file.ok || contents != file.contents {
+			return false
+		}
+	}
+
+	return true
+}
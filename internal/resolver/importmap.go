@@ -0,0 +1,259 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This implements import maps, which browsers use to resolve import paths:
+// https://github.com/WICG/import-maps. The directory containing the import
+// map file is treated as the root of the web site. Addresses that are remote
+// URLs are marked as external, and other addresses are mapped to files.
+//
+// Scopes are matched against the URL of the importing file, so a scope like
+// "/vendor/" applies to all files in the "vendor" directory next to the import
+// map file and a scope like "/vendor/legacy.js" only applies to that file.
+
+type importMap struct {
+	source  logger.Source
+	absDir  string
+	imports importMapSpecifiers
+
+	// Sorted so that the most specific scope comes first
+	scopes []importMapScope
+}
+
+type importMapScope struct {
+	prefix     string
+	specifiers importMapSpecifiers
+}
+
+// Sorted so that the longest key comes first. This is the order that prefix
+// matches must be tried in.
+type importMapSpecifiers []importMapEntry
+
+type importMapEntry struct {
+	key      string
+	keyRange logger.Range
+	address  string // This is empty for a null address
+}
+
+func (r *resolver) parseImportMap(absPath string) *importMap {
+	contents, err := r.readFile(absPath)
+	if err != nil {
+		r.log.AddError(nil, logger.Loc{},
+			fmt.Sprintf("Cannot read file %q: %s",
+				r.PrettyPath(logger.Path{Text: absPath, Namespace: "file"}), err.Error()))
+		return nil
+	}
+
+	keyPath := logger.Path{Text: absPath, Namespace: "file"}
+	m := &importMap{
+		source: logger.Source{
+			KeyPath:    keyPath,
+			PrettyPath: r.PrettyPath(keyPath),
+			Contents:   contents,
+		},
+		absDir: r.fs.Dir(absPath),
+	}
+
+	json, ok := js_parser.ParseJSON(r.log, m.source, js_parser.ParseJSONOptions{})
+	if !ok {
+		return nil
+	}
+	if _, ok := json.Data.(*js_ast.EObject); !ok {
+		r.log.AddError(&m.source, json.Loc, "The import map must be a JSON object")
+		return nil
+	}
+
+	if importsJson, _, ok := getProperty(json, "imports"); ok {
+		m.imports = r.parseImportMapSpecifiers(m, importsJson)
+	}
+
+	if scopesJson, _, ok := getProperty(json, "scopes"); ok {
+		scopes, ok := scopesJson.Data.(*js_ast.EObject)
+		if !ok {
+			r.log.AddRangeWarning(logger.MsgIDImportMap, &m.source, logger.Range{Loc: scopesJson.Loc},
+				"The \"scopes\" field must be an object")
+		} else {
+			for _, prop := range scopes.Properties {
+				key, ok := getString(prop.Key)
+				if !ok || prop.Value == nil {
+					continue
+				}
+				prefix, ok := r.importMapURLForKey(m, key)
+				if !ok {
+					r.log.AddRangeWarning(logger.MsgIDImportMap, &m.source, m.source.RangeOfString(prop.Key.Loc),
+						fmt.Sprintf("Ignoring the scope %q because it's outside of the directory containing the import map", key))
+					continue
+				}
+				m.scopes = append(m.scopes, importMapScope{
+					prefix:     prefix,
+					specifiers: r.parseImportMapSpecifiers(m, *prop.Value),
+				})
+			}
+			sort.SliceStable(m.scopes, func(i int, j int) bool {
+				return len(m.scopes[i].prefix) > len(m.scopes[j].prefix)
+			})
+		}
+	}
+
+	return m
+}
+
+func (r *resolver) parseImportMapSpecifiers(m *importMap, json js_ast.Expr) importMapSpecifiers {
+	obj, ok := json.Data.(*js_ast.EObject)
+	if !ok {
+		r.log.AddRangeWarning(logger.MsgIDImportMap, &m.source, logger.Range{Loc: json.Loc},
+			"This value must be an object")
+		return nil
+	}
+
+	var result importMapSpecifiers
+	for _, prop := range obj.Properties {
+		key, ok := getString(prop.Key)
+		if !ok || prop.Value == nil || key == "" {
+			continue
+		}
+		keyRange := m.source.RangeOfString(prop.Key.Loc)
+		entry := importMapEntry{keyRange: keyRange}
+
+		// Relative keys are normalized so they can be compared with other paths
+		if entry.key, ok = r.importMapURLForKey(m, key); !ok {
+			r.log.AddRangeWarning(logger.MsgIDImportMap, &m.source, keyRange,
+				fmt.Sprintf("Ignoring the key %q because it's outside of the directory containing the import map", key))
+			continue
+		}
+
+		// Addresses must be URLs, relative paths, or null
+		if _, isNull := prop.Value.Data.(*js_ast.ENull); !isNull {
+			address, ok := getString(*prop.Value)
+			if !ok || (!isImportMapRemoteURL(address) && !strings.HasPrefix(address, "/") &&
+				!strings.HasPrefix(address, "./") && !strings.HasPrefix(address, "../")) {
+				r.log.AddRangeWarning(logger.MsgIDImportMap, &m.source, logger.Range{Loc: prop.Value.Loc},
+					fmt.Sprintf("Ignoring the address for %q because it must be a URL or a path starting with \"/\", \"./\", or \"../\"", key))
+				continue
+			}
+			if strings.HasSuffix(key, "/") && !strings.HasSuffix(address, "/") {
+				r.log.AddRangeWarning(logger.MsgIDImportMap, &m.source, logger.Range{Loc: prop.Value.Loc},
+					fmt.Sprintf("Ignoring the address for %q because it must end in \"/\" like the key does", key))
+				continue
+			}
+			entry.address = address
+		}
+
+		result = append(result, entry)
+	}
+
+	sort.SliceStable(result, func(i int, j int) bool {
+		return len(result[i].key) > len(result[j].key)
+	})
+	return result
+}
+
+func isImportMapRemoteURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "//")
+}
+
+// Keys that are paths are converted to URLs relative to the root of the site.
+// Bare specifiers and remote URLs are left alone.
+func (r *resolver) importMapURLForKey(m *importMap, key string) (string, bool) {
+	if !strings.HasPrefix(key, "/") && !strings.HasPrefix(key, "./") && !strings.HasPrefix(key, "../") {
+		return key, true
+	}
+	url, ok := r.importMapURLForPath(m, r.fs.Join(m.absDir, key))
+	if ok && strings.HasSuffix(key, "/") && !strings.HasSuffix(url, "/") {
+		url += "/"
+	}
+	return url, ok
+}
+
+func (r *resolver) importMapURLForPath(m *importMap, absPath string) (string, bool) {
+	rel, ok := r.fs.Rel(m.absDir, absPath)
+	if !ok {
+		return "", false
+	}
+	rel = strings.ReplaceAll(rel, "\\", "/")
+	if rel == "." {
+		return "/", true
+	}
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return "/" + rel, true
+}
+
+type importMapStatus uint8
+
+const (
+	importMapNotFound importMapStatus = iota
+	importMapFile
+	importMapExternal
+	importMapNull
+)
+
+// This returns the mapped file path or URL for the import path, if the import
+// map has an entry for it. The importer is the absolute path of the importing
+// file, if there is one.
+func (r *resolver) resolveImportMap(m *importMap, sourceDir string, importer string, importPath string) (string, importMapStatus) {
+	// Relative and absolute paths are matched using their URL
+	specifier := importPath
+	if !IsPackagePath(importPath) {
+		absPath := importPath
+		if !r.fs.IsAbs(absPath) {
+			absPath = r.fs.Join(sourceDir, importPath)
+		}
+		url, ok := r.importMapURLForPath(m, absPath)
+		if !ok {
+			return "", importMapNotFound
+		}
+		specifier = url
+	}
+
+	// Check the scopes that contain the importer first, most specific first.
+	// Without an importing file, only scopes for directories can match.
+	importerURL, ok := "", false
+	if importer != "" {
+		importerURL, ok = r.importMapURLForPath(m, importer)
+	} else if importerURL, ok = r.importMapURLForPath(m, sourceDir); ok && !strings.HasSuffix(importerURL, "/") {
+		importerURL += "/"
+	}
+	if ok {
+		for _, scope := range m.scopes {
+			if importerURL == scope.prefix || (strings.HasSuffix(scope.prefix, "/") && strings.HasPrefix(importerURL, scope.prefix)) {
+				if result, status := r.resolveImportMapSpecifier(m, scope.specifiers, specifier); status != importMapNotFound {
+					return result, status
+				}
+			}
+		}
+	}
+
+	return r.resolveImportMapSpecifier(m, m.imports, specifier)
+}
+
+func (r *resolver) resolveImportMapSpecifier(m *importMap, specifiers importMapSpecifiers, specifier string) (string, importMapStatus) {
+	for _, entry := range specifiers {
+		if specifier != entry.key && (!strings.HasSuffix(entry.key, "/") || !strings.HasPrefix(specifier, entry.key)) {
+			continue
+		}
+
+		if entry.address == "" {
+			r.addDebugNote(logger.RangeData(&m.source, entry.keyRange,
+				fmt.Sprintf("The import map maps %q to null", specifier)))
+			return "", importMapNull
+		}
+
+		address := entry.address + specifier[len(entry.key):]
+		if isImportMapRemoteURL(address) {
+			return address, importMapExternal
+		}
+		return r.fs.Join(m.absDir, address), importMapFile
+	}
+
+	return "", importMapNotFound
+}
@@ -0,0 +1,468 @@
+package resolver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This implements the "exports" and "imports" fields in "package.json" files
+// using the algorithm from node. The algorithm is described here:
+// https://nodejs.org/api/esm.html#esm_resolver_algorithm_specification.
+//
+// When a package has an "exports" field, importing the package by name only
+// considers the paths in that field and ignores the "main" fields. Specifiers
+// starting with "#" are only ever resolved using the "imports" field of the
+// nearest enclosing "package.json" file.
+
+type pjKind uint8
+
+const (
+	pjNull pjKind = iota
+	pjString
+	pjArray
+	pjObject
+	pjInvalid
+)
+
+type pjEntry struct {
+	kind       pjKind
+	firstToken logger.Range
+	strData    string
+	arrData    []pjEntry
+	mapData    []pjMapEntry // Keys are in source order, which matters for conditions
+
+	// Keys that contain a "*" are subpath patterns. These are sorted so that
+	// the pattern with the longest prefix before the "*" comes first.
+	expansionKeys []pjMapEntry
+}
+
+type pjMapEntry struct {
+	key      string
+	keyRange logger.Range
+	value    pjEntry
+}
+
+// This is the "exports" or "imports" field of a "package.json" file
+type pjMap struct {
+	source   logger.Source
+	keyRange logger.Range // The range of the "exports" or "imports" key
+	root     pjEntry
+}
+
+func (entry pjEntry) valueForKey(key string) (pjEntry, bool) {
+	for _, item := range entry.mapData {
+		if item.key == key {
+			return item.value, true
+		}
+	}
+	return pjEntry{}, false
+}
+
+// Returns true if every key starts with ".", false if no key starts with ".",
+// and nil if the keys are mixed
+func (entry pjEntry) keysStartWithDot() *bool {
+	var result *bool
+	for _, item := range entry.mapData {
+		startsWithDot := strings.HasPrefix(item.key, ".")
+		if result == nil {
+			result = &startsWithDot
+		} else if *result != startsWithDot {
+			return nil
+		}
+	}
+	if result == nil {
+		startsWithDot := false
+		result = &startsWithDot
+	}
+	return result
+}
+
+func parseExportsOrImportsMap(log logger.Log, source logger.Source, json js_ast.Expr, keyLoc logger.Loc) *pjMap {
+	var visit func(expr js_ast.Expr) pjEntry
+	visit = func(expr js_ast.Expr) pjEntry {
+		var firstToken logger.Range
+
+		switch e := expr.Data.(type) {
+		case *js_ast.ENull:
+			return pjEntry{
+				kind:       pjNull,
+				firstToken: js_lexer.RangeOfIdentifier(source, expr.Loc),
+			}
+
+		case *js_ast.EString:
+			return pjEntry{
+				kind:       pjString,
+				firstToken: source.RangeOfString(expr.Loc),
+				strData:    js_lexer.UTF16ToString(e.Value),
+			}
+
+		case *js_ast.EArray:
+			arrData := make([]pjEntry, len(e.Items))
+			for i, item := range e.Items {
+				arrData[i] = visit(item)
+			}
+			return pjEntry{
+				kind:       pjArray,
+				firstToken: logger.Range{Loc: expr.Loc, Len: 1},
+				arrData:    arrData,
+			}
+
+		case *js_ast.EObject:
+			mapData := make([]pjMapEntry, 0, len(e.Properties))
+			var expansionKeys []pjMapEntry
+			for _, prop := range e.Properties {
+				keyStr, ok := prop.Key.Data.(*js_ast.EString)
+				if !ok || prop.Value == nil {
+					continue
+				}
+				item := pjMapEntry{
+					key:      js_lexer.UTF16ToString(keyStr.Value),
+					keyRange: source.RangeOfString(prop.Key.Loc),
+					value:    visit(*prop.Value),
+				}
+				mapData = append(mapData, item)
+				if strings.Count(item.key, "*") == 1 {
+					expansionKeys = append(expansionKeys, item)
+				}
+			}
+
+			// This follows "PATTERN_KEY_COMPARE" in the specification
+			sort.SliceStable(expansionKeys, func(i int, j int) bool {
+				a, b := expansionKeys[i].key, expansionKeys[j].key
+				baseA, baseB := strings.IndexByte(a, '*'), strings.IndexByte(b, '*')
+				if baseA != baseB {
+					return baseA > baseB
+				}
+				return len(a) > len(b)
+			})
+
+			return pjEntry{
+				kind:          pjObject,
+				firstToken:    logger.Range{Loc: expr.Loc, Len: 1},
+				mapData:       mapData,
+				expansionKeys: expansionKeys,
+			}
+
+		case *js_ast.EBoolean:
+			firstToken = js_lexer.RangeOfIdentifier(source, expr.Loc)
+
+		case *js_ast.ENumber:
+			firstToken = source.RangeOfNumber(expr.Loc)
+
+		default:
+			firstToken.Loc = expr.Loc
+		}
+
+		log.AddRangeWarning(logger.MsgIDPackageJSON, &source, firstToken,
+			"This value must be a string, an object, an array, or null")
+		return pjEntry{kind: pjInvalid, firstToken: firstToken}
+	}
+
+	return &pjMap{
+		source:   source,
+		keyRange: source.RangeOfString(keyLoc),
+		root:     visit(json),
+	}
+}
+
+type pjStatus uint8
+
+const (
+	pjStatusUndefined pjStatus = iota
+	pjStatusExact
+	pjStatusNull // The target was explicitly disabled with "null"
+	pjStatusInvalidModuleSpecifier
+	pjStatusInvalidPackageConfiguration
+	pjStatusInvalidPackageTarget
+	pjStatusPackagePathNotExported
+	pjStatusPackageImportNotDefined
+)
+
+type pjResult struct {
+	status pjStatus
+	path   string       // For "pjStatusExact", this is relative to the package directory
+	token  logger.Range // Where in "package.json" the status came from
+}
+
+// These are the conditions that are active for a given import. The "default"
+// condition always matches and isn't included here.
+func (r *resolver) esmConditions(kind ast.ImportKind) map[string]bool {
+	if kind == ast.ImportRequire || kind == ast.ImportRequireResolve {
+		return r.esmConditionsRequire
+	}
+	return r.esmConditionsImport
+}
+
+// The "import" and "require" conditions are mutually exclusive and are picked
+// by the kind of import, so they are ignored if they are passed as custom
+// conditions. Otherwise "require()" calls could resolve to ESM files.
+func esmConditionsForKind(options config.Options, importOrRequire string) map[string]bool {
+	conditions := map[string]bool{importOrRequire: true}
+	switch options.Platform {
+	case config.PlatformBrowser:
+		conditions["browser"] = true
+	case config.PlatformNode:
+		conditions["node"] = true
+	}
+	for _, condition := range options.Conditions {
+		if condition != "import" && condition != "require" {
+			conditions[condition] = true
+		}
+	}
+	return conditions
+}
+
+// This implements "PACKAGE_EXPORTS_RESOLVE". The subpath is either "." or
+// starts with "./".
+func esmPackageExportsResolve(subpath string, exports pjEntry, conditions map[string]bool) pjResult {
+	startsWithDot := exports.keysStartWithDot()
+	if exports.kind == pjObject && startsWithDot == nil {
+		return pjResult{status: pjStatusInvalidPackageConfiguration, token: exports.firstToken}
+	}
+
+	if subpath == "." {
+		var mainExport pjEntry
+		hasMainExport := false
+		if exports.kind == pjString || exports.kind == pjArray || (exports.kind == pjObject && !*startsWithDot) {
+			mainExport = exports
+			hasMainExport = true
+		} else if exports.kind == pjObject {
+			mainExport, hasMainExport = exports.valueForKey(".")
+		}
+		if hasMainExport {
+			if result := esmPackageTargetResolve(mainExport, "", false, false, conditions); result.status != pjStatusUndefined {
+				return result
+			}
+		}
+	} else if exports.kind == pjObject && *startsWithDot {
+		if result := esmPackageImportsExportsResolve(subpath, exports, false, conditions); result.status != pjStatusUndefined {
+			return result
+		}
+	}
+
+	return pjResult{status: pjStatusPackagePathNotExported, token: exports.firstToken}
+}
+
+// This implements "PACKAGE_IMPORTS_RESOLVE" for a specifier starting with "#"
+func esmPackageImportsResolve(specifier string, imports pjEntry, conditions map[string]bool) pjResult {
+	if specifier == "#" || strings.HasPrefix(specifier, "#/") {
+		return pjResult{status: pjStatusInvalidModuleSpecifier}
+	}
+
+	if imports.kind == pjObject {
+		if result := esmPackageImportsExportsResolve(specifier, imports, true, conditions); result.status != pjStatusUndefined {
+			return result
+		}
+	}
+
+	return pjResult{status: pjStatusPackageImportNotDefined, token: imports.firstToken}
+}
+
+// This implements "PACKAGE_IMPORTS_EXPORTS_RESOLVE"
+func esmPackageImportsExportsResolve(matchKey string, matchObj pjEntry, isImports bool, conditions map[string]bool) pjResult {
+	if !strings.Contains(matchKey, "*") {
+		if target, ok := matchObj.valueForKey(matchKey); ok {
+			return esmPackageTargetResolve(target, "", false, isImports, conditions)
+		}
+	}
+
+	for _, expansion := range matchObj.expansionKeys {
+		star := strings.IndexByte(expansion.key, '*')
+		patternBase := expansion.key[:star]
+		if strings.HasPrefix(matchKey, patternBase) && matchKey != patternBase {
+			patternTrailer := expansion.key[star+1:]
+			if patternTrailer == "" || (strings.HasSuffix(matchKey, patternTrailer) && len(matchKey) >= len(expansion.key)) {
+				patternMatch := matchKey[len(patternBase) : len(matchKey)-len(patternTrailer)]
+				return esmPackageTargetResolve(expansion.value, patternMatch, true, isImports, conditions)
+			}
+		}
+	}
+
+	return pjResult{status: pjStatusUndefined}
+}
+
+// This implements "PACKAGE_TARGET_RESOLVE". Targets in the "imports" map that
+// are package names instead of relative paths are returned as-is so that the
+// caller can resolve them.
+func esmPackageTargetResolve(target pjEntry, patternMatch string, isPattern bool, isImports bool, conditions map[string]bool) pjResult {
+	switch target.kind {
+	case pjString:
+		if !strings.HasPrefix(target.strData, "./") {
+			if !isImports || strings.HasPrefix(target.strData, "../") || strings.HasPrefix(target.strData, "/") ||
+				strings.HasPrefix(target.strData, "#") || strings.Contains(target.strData, "://") {
+				return pjResult{status: pjStatusInvalidPackageTarget, token: target.firstToken}
+			}
+			path := target.strData
+			if isPattern {
+				path = strings.ReplaceAll(path, "*", patternMatch)
+			}
+			return pjResult{status: pjStatusExact, path: path, token: target.firstToken}
+		}
+
+		// The target must not escape from the package directory
+		if hasInvalidSegment(target.strData[2:], true) {
+			return pjResult{status: pjStatusInvalidPackageTarget, token: target.firstToken}
+		}
+
+		if !isPattern {
+			return pjResult{status: pjStatusExact, path: target.strData, token: target.firstToken}
+		}
+		if hasInvalidSegment(patternMatch, false) {
+			return pjResult{status: pjStatusInvalidModuleSpecifier, token: target.firstToken}
+		}
+		return pjResult{status: pjStatusExact, path: strings.ReplaceAll(target.strData, "*", patternMatch), token: target.firstToken}
+
+	case pjObject:
+		for _, item := range target.mapData {
+			if item.key == "default" || conditions[item.key] {
+				result := esmPackageTargetResolve(item.value, patternMatch, isPattern, isImports, conditions)
+				if result.status == pjStatusUndefined {
+					continue
+				}
+				return result
+			}
+		}
+		return pjResult{status: pjStatusUndefined}
+
+	case pjArray:
+		if len(target.arrData) == 0 {
+			return pjResult{status: pjStatusNull, token: target.firstToken}
+		}
+		var lastError pjResult
+		for _, item := range target.arrData {
+			result := esmPackageTargetResolve(item, patternMatch, isPattern, isImports, conditions)
+			if result.status == pjStatusInvalidPackageTarget || result.status == pjStatusUndefined {
+				lastError = result
+				continue
+			}
+			return result
+		}
+		return lastError
+
+	case pjNull:
+		return pjResult{status: pjStatusNull, token: target.firstToken}
+	}
+
+	return pjResult{status: pjStatusInvalidPackageTarget, token: target.firstToken}
+}
+
+// Path segments like "..", ".", and "node_modules" aren't allowed in targets
+// and in the text matched by a "*" in a pattern. Targets also can't contain
+// empty segments.
+func hasInvalidSegment(path string, isTarget bool) bool {
+	for _, segment := range strings.FieldsFunc(path+"/", func(c rune) bool { return c == '/' || c == '\\' }) {
+		if segment == "." || segment == ".." || strings.EqualFold(segment, "node_modules") {
+			return true
+		}
+	}
+	return isTarget && (strings.Contains(path, "//") || strings.Contains(path, "\\\\"))
+}
+
+// This splits a package path into the package name and a subpath that is
+// either "." or starts with "./". For example, "@scope/pkg/dir/file.js" is
+// split into "@scope/pkg" and "./dir/file.js".
+func esmParsePackageName(packageSpecifier string) (packageName string, packageSubpath string, ok bool) {
+	if packageSpecifier == "" {
+		return
+	}
+
+	slash := strings.IndexByte(packageSpecifier, '/')
+	if !strings.HasPrefix(packageSpecifier, "@") {
+		if slash == -1 {
+			slash = len(packageSpecifier)
+		}
+		packageName = packageSpecifier[:slash]
+	} else {
+		if slash == -1 {
+			return
+		}
+		slash2 := strings.IndexByte(packageSpecifier[slash+1:], '/')
+		if slash2 == -1 {
+			slash2 = len(packageSpecifier[slash+1:])
+		}
+		packageName = packageSpecifier[:slash+1+slash2]
+	}
+
+	if strings.HasPrefix(packageName, ".") || strings.ContainsAny(packageName, "\\%") {
+		return
+	}
+
+	packageSubpath = "." + packageSpecifier[len(packageName):]
+	ok = true
+	return
+}
+
+// This turns the result of resolving an "exports" or "imports" map into a
+// path pair, or adds a note explaining why that failed. The package name is
+// empty for the "imports" map.
+func (r *resolver) finalizeExportsOrImportsResult(
+	result pjResult, pjMap *pjMap, absPkgPath string, specifier string, packageName string,
+	kind ast.ImportKind, dirInfo *dirInfo,
+) (PathPair, bool) {
+	source := &pjMap.source
+	field := "exports"
+	if packageName == "" {
+		field = "imports"
+	}
+
+	switch result.status {
+	case pjStatusExact:
+		// Targets in the "imports" map may be package names
+		if !strings.HasPrefix(result.path, "./") {
+			return r.loadNodeModules(result.path, kind, dirInfo)
+		}
+
+		// The target must be a file that exists. Unlike other paths, extensions
+		// and "index" files are not tried.
+		absPath := r.fs.Join(absPkgPath, result.path)
+		if entries, err := r.readDirectory(r.fs.Dir(absPath)); err == nil {
+			if entry, ok := entries[r.fs.Base(absPath)]; ok && entry.Kind() == fs.FileEntry {
+				return PathPair{Primary: logger.Path{Text: absPath, Namespace: "file"}}, true
+			}
+		}
+		r.addDebugNote(logger.RangeData(source, result.token,
+			fmt.Sprintf("The %q field resolved %q to %q, which is not a file", field, specifier, result.path)))
+
+	case pjStatusNull:
+		if packageName != "" {
+			r.addDebugNote(logger.RangeData(source, result.token,
+				fmt.Sprintf("The path %q cannot be imported from package %q because it was disabled by the package author here", specifier, packageName)))
+		} else {
+			r.addDebugNote(logger.RangeData(source, result.token,
+				fmt.Sprintf("The package import %q was disabled by the package author here", specifier)))
+		}
+
+	case pjStatusPackagePathNotExported:
+		r.addDebugNote(logger.RangeData(source, pjMap.keyRange,
+			fmt.Sprintf("The path %q is not exported by package %q", specifier, packageName)))
+
+	case pjStatusPackageImportNotDefined:
+		r.addDebugNote(logger.RangeData(source, pjMap.keyRange,
+			fmt.Sprintf("The package import %q is not defined in this \"imports\" map", specifier)))
+
+	case pjStatusInvalidModuleSpecifier:
+		r.addDebugNote(logger.MsgData{Text: fmt.Sprintf("The module specifier %q is invalid", specifier)})
+
+	case pjStatusInvalidPackageConfiguration:
+		r.addDebugNote(logger.RangeData(source, result.token,
+			fmt.Sprintf("Invalid %q map: either all keys must start with \".\" or no keys can start with \".\"", field)))
+
+	case pjStatusInvalidPackageTarget:
+		r.addDebugNote(logger.RangeData(source, result.token,
+			fmt.Sprintf("The package target %q is invalid", source.TextForRange(result.token))))
+
+	default:
+		r.addDebugNote(logger.RangeData(source, pjMap.keyRange,
+			fmt.Sprintf("No condition in this %q map matched %q", field, specifier)))
+	}
+
+	return PathPair{}, false
+}
@@ -60,6 +60,10 @@ type PathPair struct {
 	// will be "main"
 	Primary   logger.Path
 	Secondary logger.Path
+
+	// This is set when the path is a package that has been marked as external
+	// by "ExternalPackages", in which case the primary path is the package path
+	IsExternal bool
 }
 
 func (pp *PathPair) iter() []*logger.Path {
@@ -96,10 +100,36 @@ type ResolveResult struct {
 
 	// This is true if the file is inside a "node_modules" directory
 	SuppressWarningsAboutWeirdCode bool
+
+	// This is the package that contains the file, if any. It's used to detect
+	// when more than one copy of the same package ends up in the bundle.
+	Package *PackageInfo
+}
+
+// This comes from the nearest enclosing "package.json" file with a "name"
+// field. Nested "package.json" files without a name (e.g. ones that only set
+// "type") are considered to be part of the enclosing package.
+type PackageInfo struct {
+	Name    string
+	Version string // Empty if there is no "version" field
+	AbsDir  string // The directory containing the "package.json" file
+}
+
+// This is returned along with the result of a resolve operation. If the
+// operation failed, it may contain notes that explain why (e.g. a package has
+// an "exports" field that doesn't include the imported path).
+type DebugMeta struct {
+	Notes []logger.MsgData
 }
 
 type Resolver interface {
-	Resolve(sourceDir string, importPath string, kind ast.ImportKind) *ResolveResult
+	// The importer is the absolute path of the importing file, or the empty
+	// string if there isn't one. It's only needed for import map scopes.
+	Resolve(sourceDir string, importer string, importPath string, kind ast.ImportKind) (*ResolveResult, DebugMeta)
+
+	// This is the same as "Resolve" but also returns the paths of the
+	// "package.json" and "tsconfig.json" files that affected the result
+	ResolveWithAffectingFiles(sourceDir string, importer string, importPath string, kind ast.ImportKind) (*ResolveResult, []string, DebugMeta)
 	ResolveAbs(absPath string) *ResolveResult
 	PrettyPath(path logger.Path) string
 }
@@ -113,9 +143,33 @@ type resolver struct {
 	// This cache maps a directory path to information about that directory and
 	// all parent directories
 	dirCache map[string]*dirInfo
+
+	// This optional cache outlives this resolver and is used to avoid redoing
+	// work between builds. It's only consulted when "dirCache" misses.
+	sharedDirCache *DirCache
+
+	// If non-nil, file system reads and log messages are recorded here while
+	// directory information is being computed
+	deps *dirInfoDeps
+
+	// If non-nil, the paths of configuration files that affect the current
+	// resolve operation are recorded here
+	affectingFiles *[]string
+
+	// Notes explaining why the current resolve operation failed are added here
+	debugMeta *DebugMeta
+
+	// The conditions that are active when resolving the "exports" and "imports"
+	// fields in "package.json" files for each kind of import
+	esmConditionsImport  map[string]bool
+	esmConditionsRequire map[string]bool
+
+	// This is non-nil if an import map was provided
+	importMap *importMap
 }
 
-func NewResolver(fs fs.FS, log logger.Log, options config.Options) Resolver {
+// The directory cache is optional and may be nil.
+func NewResolver(fs fs.FS, log logger.Log, sharedDirCache *DirCache, options config.Options) Resolver {
 	// Bundling for node implies allowing node's builtin modules
 	if options.Platform == config.PlatformNode {
 		externalNodeModules := make(map[string]bool)
@@ -130,15 +184,111 @@ func NewResolver(fs fs.FS, log logger.Log, options config.Options) Resolver {
 		options.ExternalModules.NodeModules = externalNodeModules
 	}
 
-	return &resolver{
-		fs:       fs,
-		log:      log,
-		options:  options,
-		dirCache: make(map[string]*dirInfo),
+	r := &resolver{
+		fs:                   fs,
+		options:              options,
+		dirCache:             make(map[string]*dirInfo),
+		sharedDirCache:       sharedDirCache,
+		esmConditionsImport:  esmConditionsForKind(options, "import"),
+		esmConditionsRequire: esmConditionsForKind(options, "require"),
+	}
+
+	// Capture messages logged while computing directory information so they can
+	// be logged again if that information is reused by a later build
+	r.log = logger.Log{
+		AddMsg: func(msg logger.Msg) {
+			if r.deps != nil {
+				r.deps.msgs = append(r.deps.msgs, msg)
+			}
+			log.AddMsg(msg)
+		},
+		HasErrors: log.HasErrors,
+		Done:      log.Done,
+	}
+
+	if options.ImportMapPath != "" {
+		r.importMap = r.parseImportMap(options.ImportMapPath)
+	}
+
+	return r
+}
+
+func (r *resolver) readDirectory(path string) (map[string]*fs.Entry, error) {
+	entries, err := r.fs.ReadDirectory(path)
+	if r.deps != nil {
+		r.deps.recordDirectory(path, entries, err)
+	}
+	return entries, err
+}
+
+func (r *resolver) readFile(path string) (string, error) {
+	contents, err := r.fs.ReadFile(path)
+	if r.deps != nil {
+		r.deps.recordFile(path, contents, err)
+	}
+	return contents, err
+}
+
+func (r *resolver) Resolve(sourceDir string, importer string, importPath string, kind ast.ImportKind) (*ResolveResult, DebugMeta) {
+	return r.resolve(sourceDir, importer, importPath, kind, nil)
+}
+
+func (r *resolver) ResolveWithAffectingFiles(sourceDir string, importer string, importPath string, kind ast.ImportKind) (*ResolveResult, []string, DebugMeta) {
+	affectingFiles := []string{}
+	result, debug := r.resolve(sourceDir, importer, importPath, kind, &affectingFiles)
+	return result, affectingFiles, debug
+}
+
+// Configuration files can affect the result in many places. Each one is only
+// recorded once.
+func (r *resolver) recordAffectingFile(path string) {
+	if r.affectingFiles != nil {
+		for _, existing := range *r.affectingFiles {
+			if existing == path {
+				return
+			}
+		}
+		*r.affectingFiles = append(*r.affectingFiles, path)
+	}
+}
+
+func (r *resolver) addDebugNote(note logger.MsgData) {
+	if r.debugMeta != nil {
+		r.debugMeta.Notes = append(r.debugMeta.Notes, note)
 	}
 }
 
-func (r *resolver) Resolve(sourceDir string, importPath string, kind ast.ImportKind) *ResolveResult {
+func (r *resolver) resolve(sourceDir string, importer string, importPath string, kind ast.ImportKind, affectingFiles *[]string) (*ResolveResult, DebugMeta) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.affectingFiles = affectingFiles
+	defer func() { r.affectingFiles = nil }()
+	debugMeta := DebugMeta{}
+	r.debugMeta = &debugMeta
+	defer func() { r.debugMeta = nil }()
+
+	// Substitute aliased package paths before doing anything else
+	if r.options.PackageAliases != nil && IsPackagePath(importPath) {
+		if aliased, ok := r.checkPackageAlias(importPath); ok {
+			importPath = aliased
+			if !IsPackagePath(importPath) {
+				sourceDir = r.fs.Cwd()
+			}
+		}
+	}
+
+	// Then check the import map. Browsers don't use import maps for CSS.
+	if r.importMap != nil && !kind.IsFromCSS() {
+		switch mapped, status := r.resolveImportMap(r.importMap, sourceDir, importer, importPath); status {
+		case importMapFile, importMapExternal:
+			// Remote URLs are marked as external below
+			importPath = mapped
+
+		case importMapNull:
+			return nil, debugMeta
+		}
+	}
+
 	// Certain types of URLs default to being external for convenience
 	if
 	// "fill: url(#filter);"
@@ -159,19 +309,32 @@ func (r *resolver) Resolve(sourceDir string, importPath string, kind ast.ImportK
 		return &ResolveResult{
 			PathPair:   PathPair{Primary: logger.Path{Text: importPath}},
 			IsExternal: true,
-		}
+		}, DebugMeta{}
 	}
 
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
-
 	result := r.resolveWithoutSymlinks(sourceDir, importPath, kind)
 	if result == nil {
-		return nil
+		return nil, debugMeta
 	}
 
 	// If successful, resolve symlinks using the directory info cache
-	return r.finalizeResolve(*result)
+	return r.finalizeResolve(*result), DebugMeta{}
+}
+
+// This matches the import path against the package aliases. An alias for
+// "pkg" matches "pkg" and "pkg/subpath" but not "pkg-other". If several
+// aliases match, the longest one is used.
+func (r *resolver) checkPackageAlias(importPath string) (string, bool) {
+	longest := ""
+	for name := range r.options.PackageAliases {
+		if len(name) > len(longest) && (importPath == name || strings.HasPrefix(importPath, name+"/")) {
+			longest = name
+		}
+	}
+	if longest == "" {
+		return "", false
+	}
+	return r.options.PackageAliases[longest] + importPath[len(longest):], true
 }
 
 func (r *resolver) ResolveAbs(absPath string) *ResolveResult {
@@ -213,11 +376,26 @@ func (r *resolver) finalizeResolve(result ResolveResult) *ResolveResult {
 					if info.packageJSON != nil {
 						if info.packageJSON.sideEffectsMap != nil {
 							result.IgnoreIfUnused = !info.packageJSON.sideEffectsMap[path.Text]
+							r.recordAffectingFile(info.packageJSON.absPath)
 						}
 						break
 					}
 				}
 
+				// Remember which package this file belongs to
+				if path == &result.PathPair.Primary {
+					for info := dirInfo; info != nil; info = info.parent {
+						if info.packageJSON != nil && info.packageJSON.name != "" {
+							result.Package = &PackageInfo{
+								Name:    info.packageJSON.name,
+								Version: info.packageJSON.version,
+								AbsDir:  info.absPath,
+							}
+							break
+						}
+					}
+				}
+
 				// Copy various fields from the nearest enclosing "tsconfig.json" file if present
 				if path == &result.PathPair.Primary && dirInfo.tsConfigJSON != nil {
 					result.JSXFactory = dirInfo.tsConfigJSON.JSXFactory
@@ -247,6 +425,19 @@ func (r *resolver) resolveWithoutSymlinks(sourceDir string, importPath string, k
 	// described here: https://nodejs.org/api/modules.html#modules_all_together
 	var result PathPair
 
+	// Check for wildcard external patterns that match the path as written.
+	// Relative paths are made absolute so they are rewritten to be relative to
+	// the output directory instead of the source file.
+	for _, pattern := range r.options.ExternalModules.Patterns {
+		if pattern.Matches(importPath) {
+			if !IsPackagePath(importPath) && !r.fs.IsAbs(importPath) {
+				absPath := r.fs.Join(sourceDir, importPath)
+				return &ResolveResult{PathPair: PathPair{Primary: logger.Path{Text: absPath, Namespace: "file"}}, IsExternal: true}
+			}
+			return &ResolveResult{PathPair: PathPair{Primary: logger.Path{Text: importPath}}, IsExternal: true}
+		}
+	}
+
 	// Return early if this is already an absolute path
 	if r.fs.IsAbs(importPath) {
 		if r.options.ExternalModules.AbsPaths != nil && r.options.ExternalModules.AbsPaths[importPath] {
@@ -312,9 +503,10 @@ func (r *resolver) resolveWithoutSymlinks(sourceDir string, importPath string, k
 			packageJSON := sourceDirInfo.enclosingBrowserScope.packageJSON
 			if packageJSON.browserPackageMap != nil {
 				if remapped, ok := packageJSON.browserPackageMap[importPath]; ok {
+					r.recordAffectingFile(packageJSON.absPath)
 					if remapped == nil {
 						// "browser": {"module": false}
-						if absolute, ok := r.loadNodeModules(importPath, kind, sourceDirInfo); ok {
+						if absolute, ok := r.loadNodeModules(importPath, kind, sourceDirInfo); ok && !absolute.IsExternal {
 							absolute.Primary = logger.Path{Text: absolute.Primary.Text, Namespace: BrowserFalseNamespace}
 							if absolute.HasSecondary() {
 								absolute.Secondary = logger.Path{Text: absolute.Secondary.Text, Namespace: BrowserFalseNamespace}
@@ -334,6 +526,9 @@ func (r *resolver) resolveWithoutSymlinks(sourceDir string, importPath string, k
 		}
 
 		if absolute, ok := r.resolveWithoutRemapping(sourceDirInfo, importPath, kind); ok {
+			if absolute.IsExternal {
+				return &ResolveResult{PathPair: absolute, IsExternal: true}
+			}
 			result = absolute
 		} else {
 			// Note: node's "self references" are not currently supported
@@ -351,9 +546,13 @@ func (r *resolver) resolveWithoutSymlinks(sourceDir string, importPath string, k
 			packageJSON := resultDirInfo.enclosingBrowserScope.packageJSON
 			if packageJSON.browserNonPackageMap != nil {
 				if remapped, ok := packageJSON.browserNonPackageMap[path.Text]; ok {
+					r.recordAffectingFile(packageJSON.absPath)
 					if remapped == nil {
 						path.Namespace = BrowserFalseNamespace
 					} else if remappedResult, ok := r.resolveWithoutRemapping(resultDirInfo.enclosingBrowserScope, *remapped, kind); ok {
+						if remappedResult.IsExternal {
+							return &ResolveResult{PathPair: remappedResult, IsExternal: true}
+						}
 						*path = remappedResult.Primary
 					} else {
 						return nil
@@ -363,6 +562,16 @@ func (r *resolver) resolveWithoutSymlinks(sourceDir string, importPath string, k
 		}
 	}
 
+	// Check for wildcard external patterns that match the resolved path. The
+	// path is kept absolute so it's made relative to the output directory.
+	if result.Primary.Namespace == "file" {
+		for _, pattern := range r.options.ExternalModules.Patterns {
+			if pattern.Matches(result.Primary.Text) {
+				return &ResolveResult{PathPair: PathPair{Primary: result.Primary}, IsExternal: true}
+			}
+		}
+	}
+
 	return &ResolveResult{PathPair: result}
 }
 
@@ -398,8 +607,17 @@ func (r *resolver) PrettyPath(path logger.Path) string {
 ////////////////////////////////////////////////////////////////////////////////
 
 type packageJSON struct {
+	absPath       string
 	absMainFields map[string]string
 
+	// The "name" and "version" fields. The name is empty if it's not present.
+	name    string
+	version string
+
+	// These are non-nil if the "exports" and "imports" fields are present
+	exportsMap *pjMap
+	importsMap *pjMap
+
 	// Present if the "browser" field is present. This field is intended to be
 	// used by bundlers and lets you redirect the paths of certain 3rd-party
 	// modules that don't work in the browser to other modules that shim that
@@ -445,6 +663,7 @@ type dirInfo struct {
 	absPathIndex   *string       // Is there an "index.js" file?
 	packageJSON    *packageJSON  // Is there a "package.json" file?
 	tsConfigJSON   *TSConfigJSON // Is there a "tsconfig.json" file in this directory or a parent directory?
+	pnpData        *pnpData      // Is there a Yarn Plug'n'Play manifest in this directory or a parent directory?
 	absRealPath    string        // If non-empty, this is the real absolute path resolving any symlinks
 }
 
@@ -481,7 +700,7 @@ func (r *resolver) parseTSConfig(file string, visited map[string]bool) (*TSConfi
 	}
 	visited[file] = true
 
-	contents, err := r.fs.ReadFile(file)
+	contents, err := r.readFile(file)
 	if err != nil {
 		return nil, err
 	}
@@ -514,7 +733,7 @@ func (r *resolver) parseTSConfig(file string, visited map[string]bool) (*TSConfi
 						} else if err == syscall.ENOENT {
 							continue
 						} else if err == parseErrorImportCycle {
-							r.log.AddRangeWarning(&source, extendsRange,
+							r.log.AddRangeWarning(logger.MsgIDTSConfigJSON, &source, extendsRange,
 								fmt.Sprintf("Base config file %q forms cycle", extends))
 						} else if err != parseErrorAlreadyLogged {
 							r.log.AddRangeError(&source, extendsRange,
@@ -542,7 +761,7 @@ func (r *resolver) parseTSConfig(file string, visited map[string]bool) (*TSConfi
 				} else if err == syscall.ENOENT {
 					continue
 				} else if err == parseErrorImportCycle {
-					r.log.AddRangeWarning(&source, extendsRange,
+					r.log.AddRangeWarning(logger.MsgIDTSConfigJSON, &source, extendsRange,
 						fmt.Sprintf("Base config file %q forms cycle", extends))
 				} else if err != parseErrorAlreadyLogged {
 					r.log.AddRangeError(&source, extendsRange,
@@ -555,7 +774,7 @@ func (r *resolver) parseTSConfig(file string, visited map[string]bool) (*TSConfi
 
 		// Suppress warnings about missing base config files inside "node_modules"
 		if !isInsideNodeModules(r.fs, file) {
-			r.log.AddRangeWarning(&source, extendsRange,
+			r.log.AddRangeWarning(logger.MsgIDTSConfigJSON, &source, extendsRange,
 				fmt.Sprintf("Cannot find base config file %q", extends))
 		}
 
@@ -570,6 +789,7 @@ func (r *resolver) parseTSConfig(file string, visited map[string]bool) (*TSConfi
 		*result.BaseURL = r.fs.Join(fileDir, *result.BaseURL)
 	}
 
+	result.AbsPath = file
 	return result, nil
 }
 
@@ -586,8 +806,36 @@ func (r *resolver) dirInfoUncached(path string) *dirInfo {
 		}
 	}
 
+	// Reuse the information from a previous build if the parent directory is
+	// unchanged and nothing that was read last time has changed since then
+	if r.sharedDirCache != nil {
+		if entry := r.sharedDirCache.get(path); entry != nil && entry.info.parent == parentInfo && entry.deps.isUpToDate(r.fs) {
+			for _, msg := range entry.deps.msgs {
+				r.log.AddMsg(msg)
+			}
+			return entry.info
+		}
+
+		deps := &dirInfoDeps{
+			dirs:  make(map[string]dirListing),
+			files: make(map[string]fileContents),
+		}
+		prevDeps := r.deps
+		r.deps = deps
+		info := r.dirInfoUncachedWithParent(path, parentInfo)
+		r.deps = prevDeps
+		if info != nil {
+			r.sharedDirCache.set(path, &dirCacheEntry{info: info, deps: deps})
+		}
+		return info
+	}
+
+	return r.dirInfoUncachedWithParent(path, parentInfo)
+}
+
+func (r *resolver) dirInfoUncachedWithParent(path string, parentInfo *dirInfo) *dirInfo {
 	// List the directories
-	entries, err := r.fs.ReadDirectory(path)
+	entries, err := r.readDirectory(path)
 	if err != nil {
 		if err != syscall.ENOENT {
 			r.log.AddError(nil, logger.Loc{},
@@ -668,6 +916,18 @@ func (r *resolver) dirInfoUncached(path string) *dirInfo {
 		info.tsConfigJSON = parentInfo.tsConfigJSON
 	}
 
+	// Record if this directory has a Yarn Plug'n'Play manifest. Otherwise
+	// propagate the enclosing manifest from the parent directory.
+	for _, name := range pnpManifestNames {
+		if entry, ok := entries[name]; ok && entry.Kind() == fs.FileEntry {
+			info.pnpData = r.parsePnPManifest(r.fs.Join(path, name))
+			break
+		}
+	}
+	if info.pnpData == nil && parentInfo != nil {
+		info.pnpData = parentInfo.pnpData
+	}
+
 	// Look for an "index" file with known extensions
 	if absolute, ok := r.loadAsIndex(path, entries); ok {
 		info.absPathIndex = &absolute
@@ -678,7 +938,7 @@ func (r *resolver) dirInfoUncached(path string) *dirInfo {
 
 func (r *resolver) parsePackageJSON(path string) *packageJSON {
 	packageJsonPath := r.fs.Join(path, "package.json")
-	contents, err := r.fs.ReadFile(packageJsonPath)
+	contents, err := r.readFile(packageJsonPath)
 	if err != nil {
 		r.log.AddError(nil, logger.Loc{},
 			fmt.Sprintf("Cannot read file %q: %s",
@@ -705,7 +965,7 @@ func (r *resolver) parsePackageJSON(path string) *packageJSON {
 		}
 
 		// Is it a directory?
-		if mainEntries, err := r.fs.ReadDirectory(pathText); err == nil {
+		if mainEntries, err := r.readDirectory(pathText); err == nil {
 			// Look for an "index" file with known extensions
 			if absolute, ok := r.loadAsIndex(pathText, mainEntries); ok {
 				return &absolute
@@ -718,7 +978,19 @@ func (r *resolver) parsePackageJSON(path string) *packageJSON {
 		return nil
 	}
 
-	packageJSON := &packageJSON{}
+	packageJSON := &packageJSON{absPath: packageJsonPath}
+
+	// Read the "name" and "version" fields
+	if nameJson, _, ok := getProperty(json, "name"); ok {
+		if name, ok := getString(nameJson); ok {
+			packageJSON.name = name
+		}
+	}
+	if versionJson, _, ok := getProperty(json, "version"); ok {
+		if version, ok := getString(versionJson); ok {
+			packageJSON.version = version
+		}
+	}
 
 	// Read the "main" fields
 	mainFields := r.options.MainFields
@@ -789,6 +1061,30 @@ func (r *resolver) parsePackageJSON(path string) *packageJSON {
 		}
 	}
 
+	// Read the "exports" map
+	if exportsJson, exportsKeyLoc, ok := getProperty(json, "exports"); ok {
+		if _, isNull := exportsJson.Data.(*js_ast.ENull); !isNull {
+			packageJSON.exportsMap = parseExportsOrImportsMap(r.log, jsonSource, exportsJson, exportsKeyLoc)
+			if root := packageJSON.exportsMap.root; root.kind == pjObject && root.keysStartWithDot() == nil {
+				r.log.AddRangeWarning(logger.MsgIDPackageJSON, &jsonSource, root.firstToken,
+					"The keys in the \"exports\" map must either all start with \".\" or all not start with \".\"")
+			}
+		}
+	}
+
+	// Read the "imports" map
+	if importsJson, importsKeyLoc, ok := getProperty(json, "imports"); ok {
+		if _, isNull := importsJson.Data.(*js_ast.ENull); !isNull {
+			packageJSON.importsMap = parseExportsOrImportsMap(r.log, jsonSource, importsJson, importsKeyLoc)
+			for _, item := range packageJSON.importsMap.root.mapData {
+				if !strings.HasPrefix(item.key, "#") {
+					r.log.AddRangeWarning(logger.MsgIDPackageJSON, &jsonSource, item.keyRange,
+						"The keys in the \"imports\" map must start with \"#\"")
+				}
+			}
+		}
+	}
+
 	// Read the "sideEffects" property
 	if sideEffectsJson, _, ok := getProperty(json, "sideEffects"); ok {
 		switch data := sideEffectsJson.Data.(type) {
@@ -808,13 +1104,13 @@ func (r *resolver) parsePackageJSON(path string) *packageJSON {
 					absolute := r.fs.Join(path, js_lexer.UTF16ToString(item.Value))
 					packageJSON.sideEffectsMap[absolute] = true
 				} else {
-					r.log.AddWarning(&jsonSource, itemJson.Loc,
+					r.log.AddWarning(logger.MsgIDPackageJSON, &jsonSource, itemJson.Loc,
 						"Expected string in array for \"sideEffects\"")
 				}
 			}
 
 		default:
-			r.log.AddWarning(&jsonSource, sideEffectsJson.Loc,
+			r.log.AddWarning(logger.MsgIDPackageJSON, &jsonSource, sideEffectsJson.Loc,
 				"Invalid value for \"sideEffects\"")
 		}
 	}
@@ -825,7 +1121,7 @@ func (r *resolver) parsePackageJSON(path string) *packageJSON {
 func (r *resolver) loadAsFile(path string) (string, bool) {
 	// Read the directory entries once to minimize locking
 	dirPath := r.fs.Dir(path)
-	entries, err := r.fs.ReadDirectory(dirPath)
+	entries, err := r.readDirectory(dirPath)
 	if err != nil {
 		if err != syscall.ENOENT {
 			r.log.AddError(nil, logger.Loc{},
@@ -947,6 +1243,8 @@ func (r *resolver) loadAsFileOrDirectory(path string, kind ast.ImportKind) (Path
 
 		for _, field := range mainFields {
 			if absolute, ok := absMainFields[field]; ok {
+				r.recordAffectingFile(dirInfo.packageJSON.absPath)
+
 				// If the user did not manually configure a "main" field order, then
 				// use a special per-module automatic algorithm to decide whether to
 				// use "module" or "main" based on whether the package is imported
@@ -1056,6 +1354,7 @@ func (r *resolver) loadNodeModules(path string, kind ast.ImportKind, dirInfo *di
 		// Try path substitutions first
 		if dirInfo.tsConfigJSON.Paths != nil {
 			if absolute, ok := r.matchTSConfigPaths(dirInfo.tsConfigJSON, path, kind); ok {
+				r.recordAffectingFile(dirInfo.tsConfigJSON.AbsPath)
 				return absolute, true
 			}
 		}
@@ -1063,15 +1362,65 @@ func (r *resolver) loadNodeModules(path string, kind ast.ImportKind, dirInfo *di
 		// Try looking up the path relative to the base URL
 		basePath := r.fs.Join(*dirInfo.tsConfigJSON.BaseURL, path)
 		if absolute, ok := r.loadAsFileOrDirectory(basePath, kind); ok {
+			r.recordAffectingFile(dirInfo.tsConfigJSON.AbsPath)
 			return absolute, true
 		}
 	}
 
+	// Specifiers starting with "#" are only resolved using the "imports" map
+	// in the nearest enclosing "package.json" file
+	if strings.HasPrefix(path, "#") {
+		for info := dirInfo; info != nil; info = info.parent {
+			if info.packageJSON != nil {
+				r.recordAffectingFile(info.packageJSON.absPath)
+				if importsMap := info.packageJSON.importsMap; importsMap != nil {
+					result := esmPackageImportsResolve(path, importsMap.root, r.esmConditions(kind))
+					return r.finalizeExportsOrImportsResult(result, importsMap, info.absPath, path, "", kind, info)
+				}
+				break
+			}
+		}
+		r.addDebugNote(logger.MsgData{Text: fmt.Sprintf(
+			"The package import %q can't be resolved because there is no enclosing \"package.json\" file with an \"imports\" field", path)})
+		return PathPair{}, false
+	}
+
+	// Mark all packages as external if requested. This happens after the paths
+	// in "tsconfig.json" and the "imports" map in "package.json" are checked
+	// because those can map a package path to a file in the project.
+	if r.options.ExternalPackages {
+		return PathPair{Primary: logger.Path{Text: path}, IsExternal: true}, true
+	}
+
+	// Then check the Yarn Plug'n'Play manifest, if there is one. Importers that
+	// aren't in any Plug'n'Play package fall back to "node_modules" directories.
+	if dirInfo.pnpData != nil {
+		absPkgPath, packageName, packageSubpath, status := r.pnpResolve(dirInfo.pnpData, path, dirInfo.absPath)
+		switch status {
+		case pnpError:
+			return PathPair{}, false
+
+		case pnpSuccess:
+			if absolute, ok, hasExports := r.loadPackageExports(absPkgPath, packageName, packageSubpath, kind); hasExports {
+				return absolute, ok
+			}
+			return r.loadAsFileOrDirectory(r.fs.Join(absPkgPath, packageSubpath), kind)
+		}
+	}
+
 	// Then check for the package in any enclosing "node_modules" directories
 	for {
 		// Skip directories that are themselves called "node_modules", since we
 		// don't ever want to search for "node_modules/node_modules"
 		if dirInfo.hasNodeModules {
+			// If the package has an "exports" field, only paths in that field can be imported
+			if packageName, packageSubpath, ok := esmParsePackageName(path); ok {
+				absPkgPath := r.fs.Join(dirInfo.absPath, "node_modules", packageName)
+				if absolute, ok, hasExports := r.loadPackageExports(absPkgPath, packageName, packageSubpath, kind); hasExports {
+					return absolute, ok
+				}
+			}
+
 			absolute, ok := r.loadAsFileOrDirectory(r.fs.Join(dirInfo.absPath, "node_modules", path), kind)
 			if ok {
 				return absolute, true
@@ -1088,6 +1437,21 @@ func (r *resolver) loadNodeModules(path string, kind ast.ImportKind, dirInfo *di
 	return PathPair{}, false
 }
 
+// If the package in this directory has an "exports" field, the subpath is
+// resolved using that field and the last return value is true. Otherwise the
+// caller should look for the subpath in the package directory.
+func (r *resolver) loadPackageExports(absPkgPath string, packageName string, packageSubpath string, kind ast.ImportKind) (PathPair, bool, bool) {
+	pkgDirInfo := r.dirInfoCached(absPkgPath)
+	if pkgDirInfo == nil || pkgDirInfo.packageJSON == nil || pkgDirInfo.packageJSON.exportsMap == nil {
+		return PathPair{}, false, false
+	}
+	exportsMap := pkgDirInfo.packageJSON.exportsMap
+	r.recordAffectingFile(pkgDirInfo.packageJSON.absPath)
+	result := esmPackageExportsResolve(packageSubpath, exportsMap.root, r.esmConditions(kind))
+	absolute, ok := r.finalizeExportsOrImportsResult(result, exportsMap, absPkgPath, packageSubpath, packageName, kind, pkgDirInfo)
+	return absolute, ok, true
+}
+
 // Package paths are loaded from a "node_modules" directory. Non-package paths
 // are relative or absolute paths.
 func IsPackagePath(path string) bool {
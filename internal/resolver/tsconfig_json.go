@@ -11,6 +11,10 @@ import (
 )
 
 type TSConfigJSON struct {
+	// The absolute path of this file. Settings may also come from the files it
+	// extends, but only the path of the file that extends them is recorded.
+	AbsPath string
+
 	// The absolute path of "compilerOptions.baseUrl"
 	BaseURL *string
 
@@ -101,7 +105,7 @@ func ParseTSConfigJSON(
 					result.PreserveImportsNotUsedAsValues = true
 				case "remove":
 				default:
-					log.AddRangeWarning(&source, source.RangeOfString(valueJSON.Loc),
+					log.AddRangeWarning(logger.MsgIDTSConfigJSON, &source, source.RangeOfString(valueJSON.Loc),
 						fmt.Sprintf("Invalid value %q for \"importsNotUsedAsValues\"", value))
 				}
 			}
@@ -110,7 +114,7 @@ func ParseTSConfigJSON(
 		// Parse "paths"
 		if valueJSON, valueLoc, ok := getProperty(compilerOptionsJSON, "paths"); ok {
 			if result.BaseURL == nil {
-				log.AddRangeWarning(&source, source.RangeOfString(valueLoc),
+				log.AddRangeWarning(logger.MsgIDTSConfigJSON, &source, source.RangeOfString(valueLoc),
 					"Cannot use the \"paths\" property without the \"baseUrl\" property")
 			} else if paths, ok := valueJSON.Data.(*js_ast.EObject); ok {
 				result.Paths = make(map[string][]string)
@@ -150,7 +154,7 @@ func ParseTSConfigJSON(
 								}
 							}
 						} else {
-							log.AddRangeWarning(&source, source.RangeOfString(prop.Value.Loc), fmt.Sprintf(
+							log.AddRangeWarning(logger.MsgIDTSConfigJSON, &source, source.RangeOfString(prop.Value.Loc), fmt.Sprintf(
 								"Substitutions for pattern %q should be an array", key))
 						}
 					}
@@ -170,7 +174,7 @@ func parseMemberExpressionForJSX(log logger.Log, source logger.Source, loc logge
 	for _, part := range parts {
 		if !js_lexer.IsIdentifier(part) {
 			warnRange := source.RangeOfString(loc)
-			log.AddRangeWarning(&source, warnRange, fmt.Sprintf("Invalid JSX member expression: %q", text))
+			log.AddRangeWarning(logger.MsgIDTSConfigJSON, &source, warnRange, fmt.Sprintf("Invalid JSX member expression: %q", text))
 			return nil
 		}
 	}
@@ -183,7 +187,7 @@ func isValidTSConfigPathPattern(text string, log logger.Log, source logger.Sourc
 		if text[i] == '*' {
 			if foundAsterisk {
 				r := source.RangeOfString(loc)
-				log.AddRangeWarning(&source, r, fmt.Sprintf(
+				log.AddRangeWarning(logger.MsgIDTSConfigJSON, &source, r, fmt.Sprintf(
 					"Invalid pattern %q, must have at most one \"*\" character", text))
 				return false
 			}
@@ -0,0 +1,350 @@
+package resolver
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This implements Yarn's Plug'n'Play resolution algorithm. Projects that use
+// it don't have "node_modules" directories. Instead, a manifest file in the
+// project root lists the location of every package and the dependencies of
+// every package. The algorithm is described here:
+// https://yarnpkg.com/advanced/pnp-spec/.
+//
+// Package locations are often inside zip archives in the Yarn cache. Reading
+// files inside these archives is handled by the file system layer.
+
+type pnpIdentAndReference struct {
+	ident     string
+	reference string
+}
+
+type pnpPackage struct {
+	absLocation string
+
+	// A dependency with an empty reference is a missing peer dependency
+	dependencies      map[string]pnpIdentAndReference
+	discardFromLookup bool
+}
+
+type pnpData struct {
+	absManifestPath string
+	absDirPath      string
+
+	enableTopLevelFallback bool
+	ignorePattern          *regexp.Regexp
+
+	// Ident -> reference -> package. The top-level package has an empty
+	// ident and an empty reference.
+	packageRegistry map[string]map[string]*pnpPackage
+
+	fallbackPool          map[string]pnpIdentAndReference
+	fallbackExclusionList map[string]map[string]bool
+
+	// Packages sorted so that the longest location comes first, used to find
+	// the package that contains a given directory
+	locators []pnpIdentAndReference
+}
+
+// Newer versions of Yarn write the manifest to ".pnp.data.json". Otherwise
+// the manifest is embedded in the ".pnp.cjs" or ".pnp.js" file as a string.
+var pnpManifestNames = []string{".pnp.data.json", ".pnp.cjs", ".pnp.js"}
+
+func (r *resolver) parsePnPManifest(absPath string) *pnpData {
+	contents, err := r.readFile(absPath)
+	if err != nil {
+		r.log.AddError(nil, logger.Loc{},
+			fmt.Sprintf("Cannot read file %q: %s",
+				r.PrettyPath(logger.Path{Text: absPath, Namespace: "file"}), err.Error()))
+		return nil
+	}
+
+	keyPath := logger.Path{Text: absPath, Namespace: "file"}
+	source := logger.Source{
+		KeyPath:    keyPath,
+		PrettyPath: r.PrettyPath(keyPath),
+		Contents:   contents,
+	}
+
+	if !strings.HasSuffix(absPath, ".json") {
+		var ok bool
+		if source, ok = r.extractPnPDataFromJS(source); !ok {
+			return nil
+		}
+	}
+
+	json, ok := js_parser.ParseJSON(r.log, source, js_parser.ParseJSONOptions{})
+	if !ok {
+		return nil
+	}
+
+	data := &pnpData{
+		absManifestPath:       absPath,
+		absDirPath:            r.fs.Dir(absPath),
+		packageRegistry:       make(map[string]map[string]*pnpPackage),
+		fallbackPool:          make(map[string]pnpIdentAndReference),
+		fallbackExclusionList: make(map[string]map[string]bool),
+	}
+
+	if value, _, ok := getProperty(json, "enableTopLevelFallback"); ok {
+		data.enableTopLevelFallback, _ = getBool(value)
+	}
+
+	if value, _, ok := getProperty(json, "ignorePatternData"); ok {
+		if pattern, ok := getString(value); ok {
+			// Yarn generates JavaScript regular expressions. The ones it generates
+			// are also valid Go regular expressions.
+			if re, err := regexp.Compile(pattern); err == nil {
+				data.ignorePattern = re
+			} else {
+				r.log.AddRangeWarning(logger.MsgIDPackageJSON, &source, source.RangeOfString(value.Loc),
+					fmt.Sprintf("Ignoring unsupported regular expression: %s", err.Error()))
+			}
+		}
+	}
+
+	if value, _, ok := getProperty(json, "fallbackPool"); ok {
+		for _, item := range getArray(value) {
+			if tuple := getArray(item); len(tuple) == 2 {
+				if ident, ok := getString(tuple[0]); ok {
+					data.fallbackPool[ident] = getPnPDependency(ident, tuple[1])
+				}
+			}
+		}
+	}
+
+	if value, _, ok := getProperty(json, "fallbackExclusionList"); ok {
+		for _, item := range getArray(value) {
+			if tuple := getArray(item); len(tuple) == 2 {
+				if ident, ok := getString(tuple[0]); ok {
+					references := make(map[string]bool)
+					for _, reference := range getArray(tuple[1]) {
+						if reference, ok := getString(reference); ok {
+							references[reference] = true
+						}
+					}
+					data.fallbackExclusionList[ident] = references
+				}
+			}
+		}
+	}
+
+	if value, _, ok := getProperty(json, "packageRegistryData"); ok {
+		for _, item := range getArray(value) {
+			tuple := getArray(item)
+			if len(tuple) != 2 {
+				continue
+			}
+			ident, _ := getString(tuple[0]) // This is null for the top-level package
+			references := data.packageRegistry[ident]
+			if references == nil {
+				references = make(map[string]*pnpPackage)
+				data.packageRegistry[ident] = references
+			}
+
+			for _, item := range getArray(tuple[1]) {
+				tuple := getArray(item)
+				if len(tuple) != 2 {
+					continue
+				}
+				reference, _ := getString(tuple[0]) // This is null for the top-level package
+				pkg := &pnpPackage{dependencies: make(map[string]pnpIdentAndReference)}
+
+				if value, _, ok := getProperty(tuple[1], "packageLocation"); ok {
+					if location, ok := getString(value); ok {
+						pkg.absLocation = r.fs.Join(data.absDirPath, location)
+					}
+				}
+
+				if value, _, ok := getProperty(tuple[1], "packageDependencies"); ok {
+					for _, item := range getArray(value) {
+						if tuple := getArray(item); len(tuple) == 2 {
+							if dependency, ok := getString(tuple[0]); ok {
+								pkg.dependencies[dependency] = getPnPDependency(dependency, tuple[1])
+							}
+						}
+					}
+				}
+
+				if value, _, ok := getProperty(tuple[1], "discardFromLookup"); ok {
+					pkg.discardFromLookup, _ = getBool(value)
+				}
+
+				references[reference] = pkg
+				if !pkg.discardFromLookup && pkg.absLocation != "" {
+					data.locators = append(data.locators, pnpIdentAndReference{ident: ident, reference: reference})
+				}
+			}
+		}
+	}
+
+	// Sort by location so the innermost package is found first. Locations are
+	// compared by length and then by text for determinism.
+	locationOf := func(locator pnpIdentAndReference) string {
+		return data.packageRegistry[locator.ident][locator.reference].absLocation
+	}
+	sort.Slice(data.locators, func(i int, j int) bool {
+		a, b := locationOf(data.locators[i]), locationOf(data.locators[j])
+		return len(a) > len(b) || (len(a) == len(b) && a < b)
+	})
+
+	return data
+}
+
+// The ".pnp.cjs" file stores the manifest in a string like this:
+//
+//   const RAW_RUNTIME_STATE =
+//   '{\
+//     "__info": [],\
+//     ...
+//   }';
+//
+func (r *resolver) extractPnPDataFromJS(source logger.Source) (logger.Source, bool) {
+	tree, ok := js_parser.Parse(logger.NewDeferLog(), source, config.Options{})
+	if ok {
+		for _, part := range tree.Parts {
+			for _, stmt := range part.Stmts {
+				local, ok := stmt.Data.(*js_ast.SLocal)
+				if !ok {
+					continue
+				}
+				for _, decl := range local.Decls {
+					id, ok := decl.Binding.Data.(*js_ast.BIdentifier)
+					if !ok || decl.Value == nil || tree.Symbols[id.Ref.InnerIndex].OriginalName != "RAW_RUNTIME_STATE" {
+						continue
+					}
+					if str, ok := decl.Value.Data.(*js_ast.EString); ok {
+						source.Contents = js_lexer.UTF16ToString(str.Value)
+						return source, true
+					}
+				}
+			}
+		}
+	}
+
+	r.log.AddError(&source, logger.Loc{},
+		"Cannot find the Yarn Plug'n'Play manifest in this file (expected a \"RAW_RUNTIME_STATE\" variable)")
+	return source, false
+}
+
+// Dependencies are either a reference string, an [ident, reference] tuple
+// for aliased packages, or null for missing peer dependencies
+func getPnPDependency(ident string, json js_ast.Expr) pnpIdentAndReference {
+	if reference, ok := getString(json); ok {
+		return pnpIdentAndReference{ident: ident, reference: reference}
+	}
+	if tuple := getArray(json); len(tuple) == 2 {
+		alias, _ := getString(tuple[0])
+		reference, _ := getString(tuple[1])
+		return pnpIdentAndReference{ident: alias, reference: reference}
+	}
+	return pnpIdentAndReference{}
+}
+
+func getArray(json js_ast.Expr) []js_ast.Expr {
+	if value, ok := json.Data.(*js_ast.EArray); ok {
+		return value.Items
+	}
+	return nil
+}
+
+// This implements "FIND_LOCATOR" from the specification
+func (r *resolver) pnpFindLocator(data *pnpData, absDirPath string) (pnpIdentAndReference, bool) {
+	rel, ok := r.fs.Rel(data.absDirPath, absDirPath)
+	if !ok {
+		return pnpIdentAndReference{}, false
+	}
+	rel = strings.ReplaceAll(rel, "\\", "/")
+	if data.ignorePattern != nil && data.ignorePattern.MatchString(rel) {
+		return pnpIdentAndReference{}, false
+	}
+
+	for _, locator := range data.locators {
+		location := data.packageRegistry[locator.ident][locator.reference].absLocation
+		if absDirPath == location {
+			return locator, true
+		}
+		if rel, ok := r.fs.Rel(location, absDirPath); ok && rel != ".." && !strings.HasPrefix(rel, "../") && !strings.HasPrefix(rel, "..\\") {
+			return locator, true
+		}
+	}
+	return pnpIdentAndReference{}, false
+}
+
+type pnpStatus uint8
+
+const (
+	pnpSkipped pnpStatus = iota // The importer isn't part of a Plug'n'Play package
+	pnpSuccess
+	pnpError
+)
+
+// This implements "RESOLVE_TO_UNQUALIFIED" from the specification. If it
+// succeeds, it returns the absolute path of the package directory along with
+// the package name and subpath.
+func (r *resolver) pnpResolve(data *pnpData, path string, absDirPath string) (string, string, string, pnpStatus) {
+	packageName, packageSubpath, ok := esmParsePackageName(path)
+	if !ok {
+		return "", "", "", pnpSkipped
+	}
+
+	parentLocator, ok := r.pnpFindLocator(data, absDirPath)
+	if !ok {
+		return "", "", "", pnpSkipped
+	}
+	r.recordAffectingFile(data.absManifestPath)
+	parentPkg := data.packageRegistry[parentLocator.ident][parentLocator.reference]
+
+	// Check the dependencies of the importing package first
+	dependency, ok := parentPkg.dependencies[packageName]
+
+	// Then check the top-level package and the fallback pool
+	if !ok && data.enableTopLevelFallback && !data.fallbackExclusionList[parentLocator.ident][parentLocator.reference] {
+		if topLevelPkg := data.packageRegistry[""][""]; topLevelPkg != nil {
+			dependency, ok = topLevelPkg.dependencies[packageName]
+		}
+		if !ok {
+			dependency, ok = data.fallbackPool[packageName]
+		}
+	}
+
+	importer := "your project"
+	if parentLocator.ident != "" {
+		importer = fmt.Sprintf("the package %q", parentLocator.ident)
+	}
+	manifestNote := logger.MsgData{Text: fmt.Sprintf("This was resolved using the Yarn Plug'n'Play manifest %q",
+		r.PrettyPath(logger.Path{Text: data.absManifestPath, Namespace: "file"}))}
+
+	if !ok {
+		r.addDebugNote(logger.MsgData{Text: fmt.Sprintf(
+			"The package %q is not listed as a dependency of %s", packageName, importer)})
+		r.addDebugNote(manifestNote)
+		return "", "", "", pnpError
+	}
+
+	if dependency.reference == "" {
+		r.addDebugNote(logger.MsgData{Text: fmt.Sprintf(
+			"The package %q is a peer dependency of %s, but it was not provided by the package that depends on it",
+			packageName, importer)})
+		r.addDebugNote(manifestNote)
+		return "", "", "", pnpError
+	}
+
+	dependencyPkg := data.packageRegistry[dependency.ident][dependency.reference]
+	if dependencyPkg == nil || dependencyPkg.absLocation == "" {
+		r.addDebugNote(logger.MsgData{Text: fmt.Sprintf(
+			"The package %q (reference %q) is missing from the package registry", dependency.ident, dependency.reference)})
+		r.addDebugNote(manifestNote)
+		return "", "", "", pnpError
+	}
+
+	return dependencyPkg.absLocation, packageName, packageSubpath, pnpSuccess
+}
@@ -76,6 +76,114 @@ func code(isES6 bool) string {
 			return module.exports
 		}
 
+		// For hot module replacement. Modules are stored in a global registry that
+		// is shared by every version of the bundle, so loading an updated bundle
+		// only re-executes the modules whose code changed. The development server
+		// tells the page which output files changed using server-sent events.
+		var __hmrRegistry = () => {
+			var registry = globalThis.__esbuildHMR
+			if (!registry) {
+				registry = globalThis.__esbuildHMR = {records: {}, stale: [], current: null}
+				if (typeof EventSource !== 'undefined')
+					new EventSource('/esbuild').addEventListener('change', e => __hmrUpdate(JSON.parse(e.data)))
+			}
+			return registry
+		}
+
+		// Reloads changed output files. Stylesheets are swapped in place and
+		// scripts are loaded again, which registers any changed modules.
+		var __hmrUpdate = event => {
+			var matches = (url, path) => url && new URL(url, location.href).pathname === path
+			for (var i = 0; i < event.updated.length; i++) {
+				var path = event.updated[i], url = path + '?t=' + Date.now(), found = false
+				var links = document.querySelectorAll('link[rel="stylesheet"]')
+				for (var j = 0; j < links.length; j++)
+					if (matches(links[j].href, path)) links[j].href = url, found = true
+				var scripts = document.querySelectorAll('script[src]')
+				for (var j = 0; j < scripts.length; j++) {
+					if (matches(scripts[j].src, path)) {
+						var script = document.createElement('script')
+						script.type = scripts[j].type
+						script.src = url
+						scripts[j].parentNode.replaceChild(script, scripts[j])
+						found = true
+						break
+					}
+				}
+				if (!found && !/\.(css|map|json)$/.test(path)) return location.reload()
+			}
+		}
+
+		// Wraps a module closure and returns a require() function, like
+		// "__commonJS" does. Modules with changed code are queued for updating.
+		export var __hmrModule = (id, hash, callback) => {
+			var registry = __hmrRegistry(), record = registry.records[id]
+			if (!record)
+				record = registry.records[id] = {id: id, hash: hash, callback: callback, module: null, data: {}, importers: {}}
+			else if (record.hash !== hash) {
+				record.hash = hash
+				record.callback = callback
+				if (registry.stale.push(record) === 1) setTimeout(__hmrApply)
+			}
+			return () => {
+				if (registry.current) record.importers[registry.current.id] = registry.current
+				if (!record.module) __hmrRun(record)
+				return record.module.exports
+			}
+		}
+
+		// Runs a module closure with a fresh "module" object whose "hot" property
+		// is exposed to the module as "import.meta.hot"
+		var __hmrRun = record => {
+			var registry = __hmrRegistry(), previous = registry.current
+			var hot = {
+				data: record.data,
+				acceptCallback: null,
+				isAccepted: false,
+				disposeCallbacks: [],
+				accept: callback => {
+					hot.isAccepted = true
+					hot.acceptCallback = callback
+				},
+				dispose: callback => {
+					hot.disposeCallbacks.push(callback)
+				},
+			}
+			record.module = {exports: {}, hot: hot}
+			registry.current = record
+			try {
+				record.callback(record.module.exports, record.module)
+			} finally {
+				registry.current = previous
+			}
+		}
+
+		// Updates propagate from changed modules to the modules that imported
+		// them until a module that accepts its own updates is reached. The page is
+		// reloaded if an update reaches a module that nothing imports.
+		var __hmrApply = () => {
+			var registry = __hmrRegistry(), queue = []
+			for (var i = 0; i < registry.stale.length; i++) {
+				var record = registry.stale[i]
+				if (record.module && queue.indexOf(record) < 0) queue.push(record)
+			}
+			registry.stale = []
+			for (var i = 0; i < queue.length; i++) {
+				var record = queue[i], ids = Object.keys(record.importers)
+				if (record.module.hot.isAccepted) continue
+				if (!ids.length) return location.reload()
+				for (var j = 0; j < ids.length; j++)
+					if (queue.indexOf(record.importers[ids[j]]) < 0) queue.push(record.importers[ids[j]])
+			}
+			for (var i = 0; i < queue.length; i++) {
+				var record = queue[i], hot = record.module.hot
+				record.data = {}
+				for (var j = 0; j < hot.disposeCallbacks.length; j++) hot.disposeCallbacks[j](record.data)
+				__hmrRun(record)
+				if (hot.acceptCallback) hot.acceptCallback(record.module.exports)
+			}
+		}
+
 		// Used to implement ES6 exports to CommonJS
 		export var __export = (target, all) => {
 			__markAsModule(target)
@@ -218,6 +326,69 @@ var ES5Source = logger.Source{
 	Contents:       code(false /* isES6 */),
 }
 
+// This loads chunks generated by code splitting with the "iife" format. It's
+// only included in entry point chunks, and it's only run once per page even if
+// there are many entry points. It defines a global object with a "register"
+// function, and each chunk calls that function with the paths of the other
+// chunks it depends on ("deps") and a callback containing the code for the
+// chunk ("factory"). The callback is called with a function that loads other
+// chunks followed by the exports of each dependency, and returns the exports
+// of this chunk. Chunks are keyed by their URL, so each chunk is only loaded
+// and evaluated once even if many chunks depend on it.
+//
+// This code can't be shared like the rest of the runtime because it's needed
+// to load the other chunks. It must only use ES5 syntax since it isn't lowered.
+var ChunkLoaderSource = logger.Source{
+	Index:          SourceIndex,
+	KeyPath:        logger.Path{Text: "<runtime>"},
+	PrettyPath:     "<runtime>",
+	IdentifierName: "runtime",
+	Contents: `
+		self.esbuildChunks || (function() {
+			var chunks = {}
+			function getChunk(url) {
+				var chunk = chunks[url]
+				if (!chunk) {
+					chunk = chunks[url] = {}
+					chunk.promise = new Promise(function(resolve, reject) {
+						chunk.resolve = resolve
+						chunk.reject = reject
+					})
+				}
+				return chunk
+			}
+			function loaderFor(base) {
+				return function(path) {
+					var url = new URL(path, base).href
+					var chunk = getChunk(url)
+					if (!chunk.loading) {
+						var script = document.createElement('script')
+						chunk.loading = true
+						script.src = url
+						script.onerror = function() {
+							chunk.reject(new Error('Failed to load chunk "' + url + '"'))
+						}
+						document.head.appendChild(script)
+					}
+					return chunk.promise
+				}
+			}
+			self.esbuildChunks = {
+				register: function(deps, factory) {
+					var currentScript = document.currentScript
+					var base = currentScript ? currentScript.src : location.href
+					var load = loaderFor(base)
+					var chunk = getChunk(base)
+					chunk.loading = true
+					Promise.all(deps.map(load)).then(function(exports) {
+						return factory.apply(null, [load].concat(exports))
+					}).then(chunk.resolve, chunk.reject)
+				}
+			}
+		})()
+	`,
+}
+
 // The TypeScript decorator transform behaves similar to the official
 // TypeScript compiler.
 //
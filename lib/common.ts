@@ -57,12 +57,21 @@ type CommonOptions = types.BuildOptions | types.TransformOptions;
 function pushLogFlags(flags: string[], options: CommonOptions, keys: OptionKeys, isTTY: boolean, logLevelDefault: types.LogLevel): void {
   let color = getFlag(options, keys, 'color', mustBeBoolean);
   let logLevel = getFlag(options, keys, 'logLevel', mustBeString);
+  let logOverride = getFlag(options, keys, 'logOverride', mustBeObject);
+  let logFormat = getFlag(options, keys, 'logFormat', mustBeString);
   let errorLimit = getFlag(options, keys, 'errorLimit', mustBeInteger);
 
   if (color) flags.push(`--color=${color}`);
   else if (isTTY) flags.push(`--color=true`); // This is needed to fix "execFileSync" which buffers stderr
   flags.push(`--log-level=${logLevel || logLevelDefault}`);
   flags.push(`--error-limit=${errorLimit || 0}`);
+  if (logFormat) flags.push(`--log-format=${logFormat}`);
+  if (logOverride) {
+    for (let id in logOverride) {
+      if (id.indexOf('=') >= 0) throw new Error(`Invalid log override: ${id}`);
+      flags.push(`--log-override:${id}=${logOverride[id]}`);
+    }
+  }
 }
 
 function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKeys): void {
@@ -116,14 +125,21 @@ function flagsForBuildOptions(options: types.BuildOptions, isTTY: boolean, logLe
   let sourcemap = getFlag(options, keys, 'sourcemap', mustBeStringOrBoolean);
   let bundle = getFlag(options, keys, 'bundle', mustBeBoolean);
   let splitting = getFlag(options, keys, 'splitting', mustBeBoolean);
+  let manualChunks = getFlag(options, keys, 'manualChunks', mustBeObject);
+  let minChunkSize = getFlag(options, keys, 'minChunkSize', mustBeInteger);
   let metafile = getFlag(options, keys, 'metafile', mustBeString);
   let outfile = getFlag(options, keys, 'outfile', mustBeString);
   let outdir = getFlag(options, keys, 'outdir', mustBeString);
   let platform = getFlag(options, keys, 'platform', mustBeString);
   let tsconfig = getFlag(options, keys, 'tsconfig', mustBeString);
+  let importMap = getFlag(options, keys, 'importMap', mustBeString);
   let resolveExtensions = getFlag(options, keys, 'resolveExtensions', mustBeArray);
   let mainFields = getFlag(options, keys, 'mainFields', mustBeArray);
+  let conditions = getFlag(options, keys, 'conditions', mustBeArray);
   let external = getFlag(options, keys, 'external', mustBeArray);
+  let packages = getFlag(options, keys, 'packages', mustBeString);
+  let alias = getFlag(options, keys, 'alias', mustBeObject);
+  let globals = getFlag(options, keys, 'globals', mustBeObject);
   let loader = getFlag(options, keys, 'loader', mustBeObject);
   let outExtension = getFlag(options, keys, 'outExtension', mustBeObject);
   let publicPath = getFlag(options, keys, 'publicPath', mustBeString);
@@ -136,15 +152,37 @@ function flagsForBuildOptions(options: types.BuildOptions, isTTY: boolean, logLe
   if (sourcemap) flags.push(`--sourcemap${sourcemap === true ? '' : `=${sourcemap}`}`);
   if (bundle) flags.push('--bundle');
   if (splitting) flags.push('--splitting');
+  if (manualChunks) {
+    for (let name in manualChunks) {
+      if (name.indexOf('=') >= 0) throw new Error(`Invalid manual chunk name: ${name}`);
+      flags.push(`--manual-chunk:${name}=${manualChunks[name].join(',')}`);
+    }
+  }
+  if (minChunkSize) flags.push(`--min-chunk-size=${minChunkSize}`);
   if (metafile) flags.push(`--metafile=${metafile}`);
   if (outfile) flags.push(`--outfile=${outfile}`);
   if (outdir) flags.push(`--outdir=${outdir}`);
   if (platform) flags.push(`--platform=${platform}`);
   if (tsconfig) flags.push(`--tsconfig=${tsconfig}`);
+  if (importMap) flags.push(`--import-map=${importMap}`);
   if (resolveExtensions) flags.push(`--resolve-extensions=${resolveExtensions.join(',')}`);
   if (publicPath) flags.push(`--public-path=${publicPath}`);
   if (mainFields) flags.push(`--main-fields=${mainFields.join(',')}`);
+  if (conditions) flags.push(`--conditions=${conditions.join(',')}`);
   if (external) for (let name of external) flags.push(`--external:${name}`);
+  if (packages) flags.push(`--packages=${packages}`);
+  if (alias) {
+    for (let old in alias) {
+      if (old.indexOf('=') >= 0) throw new Error(`Invalid alias name: ${old}`);
+      flags.push(`--alias:${old}=${alias[old]}`);
+    }
+  }
+  if (globals) {
+    for (let path in globals) {
+      if (path.indexOf('=') >= 0) throw new Error(`Invalid global path: ${path}`);
+      flags.push(`--global:${path}=${globals[path]}`);
+    }
+  }
   if (inject) for (let path of inject) flags.push(`--inject:${path}`);
   if (loader) {
     for (let ext in loader) {
@@ -499,7 +537,7 @@ function extractErrorMessageV8(e: any, streamIn: StreamIn): types.Message {
   } catch {
   }
 
-  return { text, location }
+  return { id: '', text, location, notes: [] }
 }
 
 function failureErrorWithLog(text: string, errors: types.Message[], warnings: types.Message[]): Error {
@@ -38,6 +38,13 @@ export interface TransformResponse {
   mapFS: boolean;
 }
 
+// This stops the build or transform request with the given packet id early.
+// That request still gets a response, which will contain an error.
+export interface CancelRequest {
+  command: 'cancel';
+  requestId: number;
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 export interface Packet {
@@ -1,5 +1,5 @@
 export type Platform = 'browser' | 'node';
-export type Format = 'iife' | 'cjs' | 'esm';
+export type Format = 'iife' | 'cjs' | 'esm' | 'umd' | 'system';
 export type Loader = 'js' | 'jsx' | 'ts' | 'tsx' | 'css' | 'json' | 'text' | 'base64' | 'file' | 'dataurl' | 'binary';
 export type LogLevel = 'info' | 'warning' | 'error' | 'silent';
 export type Charset = 'ascii' | 'utf8';
@@ -24,23 +24,32 @@ interface CommonOptions {
 
   color?: boolean;
   logLevel?: LogLevel;
+  logOverride?: { [id: string]: 'warning' | 'error' | 'silent' };
+  logFormat?: 'text' | 'json';
   errorLimit?: number;
 }
 
 export interface BuildOptions extends CommonOptions {
   bundle?: boolean;
   splitting?: boolean;
+  manualChunks?: { [name: string]: string[] };
+  minChunkSize?: number;
   outfile?: string;
   metafile?: string;
   outdir?: string;
   platform?: Platform;
   color?: boolean;
   external?: string[];
+  packages?: 'external';
+  alias?: { [old: string]: string };
+  globals?: { [path: string]: string };
   loader?: { [ext: string]: Loader };
   resolveExtensions?: string[];
   mainFields?: string[];
+  conditions?: string[];
   write?: boolean;
   tsconfig?: string;
+  importMap?: string;
   outExtension?: { [ext: string]: string };
   publicPath?: string;
   inject?: string[];
@@ -57,6 +66,13 @@ export interface StdinOptions {
 }
 
 export interface Message {
+  id: string;
+  text: string;
+  location: Location | null;
+  notes: Note[];
+}
+
+export interface Note {
   text: string;
   location: Location | null;
 }
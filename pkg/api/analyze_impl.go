@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Analyze API
+
+// Only this many of the largest inputs are listed for each output file. The
+// remaining inputs are summarized together on a single line.
+const analyzeInputLimit = 10
+
+const analyzeColorReset = "\033[0m"
+const analyzeColorDim = "\033[37m"
+const analyzeColorBold = "\033[1m"
+
+type analyzeEntry struct {
+	path  string
+	bytes int
+}
+
+type analyzeEntryArray []analyzeEntry
+
+func (a analyzeEntryArray) Len() int          { return len(a) }
+func (a analyzeEntryArray) Swap(i int, j int) { a[i], a[j] = a[j], a[i] }
+
+// Bigger entries come first, and entries of the same size are sorted by path
+// so the report is deterministic
+func (a analyzeEntryArray) Less(i int, j int) bool {
+	return a[i].bytes > a[j].bytes || (a[i].bytes == a[j].bytes && a[i].path < a[j].path)
+}
+
+type analyzeRow struct {
+	isOutput bool
+	prefix   string
+	name     string
+	size     string
+	percent  string
+	extra    string
+}
+
+func analyzeMetafileImpl(metafile string, options AnalyzeMetafileOptions) (string, error) {
+	var data struct {
+		Outputs map[string]struct {
+			Bytes  int `json:"bytes"`
+			Inputs map[string]struct {
+				BytesInOutput int `json:"bytesInOutput"`
+			} `json:"inputs"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal([]byte(metafile), &data); err != nil {
+		return "", fmt.Errorf("Invalid metafile: %s", err.Error())
+	}
+
+	outputs := make(analyzeEntryArray, 0, len(data.Outputs))
+	for path, output := range data.Outputs {
+		// Source maps don't have any inputs and aren't interesting here
+		if strings.HasSuffix(path, ".map") {
+			continue
+		}
+		outputs = append(outputs, analyzeEntry{path: path, bytes: output.Bytes})
+	}
+	sort.Sort(outputs)
+
+	var rows []analyzeRow
+	for _, output := range outputs {
+		inputs := make(analyzeEntryArray, 0, len(data.Outputs[output.path].Inputs))
+		for path, input := range data.Outputs[output.path].Inputs {
+			inputs = append(inputs, analyzeEntry{path: path, bytes: input.BytesInOutput})
+		}
+		sort.Sort(inputs)
+
+		row := analyzeRow{
+			isOutput: true,
+			name:     output.path,
+			size:     logger.PrettyPrintedBytes(output.bytes),
+			percent:  analyzePercent(output.bytes, output.bytes),
+		}
+		if options.Gzip {
+			if contents, err := ioutil.ReadFile(output.path); err == nil {
+				row.extra = fmt.Sprintf("(gzip: %s)", logger.PrettyPrintedBytes(gzipSize(contents)))
+			}
+		}
+		rows = append(rows, row)
+
+		// Summarize the smallest inputs together if there are too many
+		if len(inputs) > analyzeInputLimit {
+			rest := analyzeEntry{path: fmt.Sprintf("(%d more files)", len(inputs)-analyzeInputLimit)}
+			for _, input := range inputs[analyzeInputLimit:] {
+				rest.bytes += input.bytes
+			}
+			inputs = append(inputs[:analyzeInputLimit], rest)
+		}
+
+		for i, input := range inputs {
+			prefix := " ├ "
+			if i+1 == len(inputs) {
+				prefix = " └ "
+			}
+			rows = append(rows, analyzeRow{
+				prefix:  prefix,
+				name:    input.path,
+				size:    logger.PrettyPrintedBytes(input.bytes),
+				percent: analyzePercent(input.bytes, output.bytes),
+			})
+		}
+	}
+
+	// Align the size and percentage columns across the whole report
+	maxName := 0
+	maxSize := 0
+	maxPercent := 0
+	for _, row := range rows {
+		if n := utf8.RuneCountInString(row.prefix) + len(row.name); n > maxName {
+			maxName = n
+		}
+		if n := len(row.size); n > maxSize {
+			maxSize = n
+		}
+		if n := len(row.percent); n > maxPercent {
+			maxPercent = n
+		}
+	}
+
+	colorReset := ""
+	colorDim := ""
+	colorBold := ""
+	if options.Color {
+		colorReset = analyzeColorReset
+		colorDim = analyzeColorDim
+		colorBold = analyzeColorBold
+	}
+
+	sb := strings.Builder{}
+	for _, row := range rows {
+		padding := strings.Repeat(" ", maxName-utf8.RuneCountInString(row.prefix)-len(row.name))
+		size := strings.Repeat(" ", maxSize-len(row.size)) + row.size
+		percent := strings.Repeat(" ", maxPercent-len(row.percent)) + row.percent
+		if row.isOutput {
+			sb.WriteString(fmt.Sprintf("\n  %s%s%s  %s%s  %s", colorBold, row.name, padding, size, colorReset, percent))
+		} else {
+			sb.WriteString(fmt.Sprintf("  %s%s%s%s%s  %s  %s%s%s", colorDim, row.prefix, colorReset,
+				row.name, padding, size, colorDim, percent, colorReset))
+		}
+		if row.extra != "" {
+			sb.WriteString("  " + row.extra)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
+
+func analyzePercent(n int, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
+
+func gzipSize(contents []byte) int {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Write(contents)
+	writer.Close()
+	return buffer.Len()
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalyzeMetafile(t *testing.T) {
+	metafile := `{
+		"inputs": {},
+		"outputs": {
+			"out/small.js": {"imports": [], "inputs": {"b.js": {"bytesInOutput": 10}}, "bytes": 20},
+			"out/small.js.map": {"imports": [], "inputs": {}, "bytes": 100},
+			"out/big.js": {
+				"imports": [],
+				"inputs": {
+					"a.js": {"bytesInOutput": 1024},
+					"node_modules/pkg/index.js": {"bytesInOutput": 2560},
+					"c.js": {"bytesInOutput": 1024}
+				},
+				"bytes": 5120
+			}
+		}
+	}`
+
+	report, err := AnalyzeMetafile(metafile, AnalyzeMetafileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `
+  out/big.js                    5.0kb  100.0%
+   ├ node_modules/pkg/index.js  2.5kb   50.0%
+   ├ a.js                       1.0kb   20.0%
+   └ c.js                       1.0kb   20.0%
+
+  out/small.js                    20b  100.0%
+   └ b.js                         10b   50.0%
+
+`
+	if report != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, report)
+	}
+
+	if _, err := AnalyzeMetafile("{", AnalyzeMetafileOptions{}); err == nil {
+		t.Fatal("Expected an error for an invalid metafile")
+	}
+}
+
+func TestMetafileWithImportedCSS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-metafile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	result := Build(BuildOptions{
+		EntryPoints: []string{filepath.Join(dir, "entry.js")},
+		Outdir:      filepath.Join(dir, "out"),
+		Metafile:    filepath.Join(dir, "meta.json"),
+		Bundle:      true,
+		FileSystem: Overlay{Files: map[string]string{
+			filepath.Join(dir, "entry.js"):  "import './style.css'; console.log(1)",
+			filepath.Join(dir, "style.css"): "body { color: red }",
+		}},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+
+	for _, file := range result.OutputFiles {
+		if filepath.Base(file.Path) == "meta.json" {
+			var data interface{}
+			if err := json.Unmarshal(file.Contents, &data); err != nil {
+				t.Fatalf("Invalid metafile: %s\n%s", err.Error(), file.Contents)
+			}
+			return
+		}
+	}
+	t.Fatal("Expected a metafile in the output files")
+}
@@ -77,6 +77,8 @@
 //
 package api
 
+import "context"
+
 type SourceMap uint8
 
 const (
@@ -102,7 +104,8 @@ const (
 type Loader uint8
 
 const (
-	LoaderJS Loader = iota
+	LoaderNone Loader = iota
+	LoaderJS
 	LoaderJSX
 	LoaderTS
 	LoaderTSX
@@ -122,6 +125,13 @@ const (
 	PlatformNode
 )
 
+type Packages uint8
+
+const (
+	PackagesDefault Packages = iota
+	PackagesExternal
+)
+
 type Format uint8
 
 const (
@@ -129,6 +139,8 @@ const (
 	FormatIIFE
 	FormatCommonJS
 	FormatESModule
+	FormatUMD
+	FormatSystem
 )
 
 type EngineName uint8
@@ -156,6 +168,15 @@ type Location struct {
 }
 
 type Message struct {
+	// This identifies the category of a warning so it can be overridden with
+	// "LogOverride". It's empty for messages without a category.
+	ID       string
+	Text     string
+	Location *Location
+	Notes    []Note
+}
+
+type Note struct {
 	Text     string
 	Location *Location
 }
@@ -177,6 +198,13 @@ const (
 	LogLevelError
 )
 
+type LogFormat uint8
+
+const (
+	LogFormatText LogFormat = iota
+	LogFormatJSON // One JSON object per line, followed by a summary object
+)
+
 type Charset uint8
 
 const (
@@ -189,9 +217,11 @@ const (
 // Build API
 
 type BuildOptions struct {
-	Color      StderrColor
-	ErrorLimit int
-	LogLevel   LogLevel
+	Color       StderrColor
+	ErrorLimit  int
+	LogLevel    LogLevel
+	LogOverride map[string]LogLevel // Only "LogLevelWarning", "LogLevelError", and "LogLevelSilent" are allowed
+	LogFormat   LogFormat
 
 	Sourcemap SourceMap
 	Target    Target
@@ -210,15 +240,22 @@ type BuildOptions struct {
 	AvoidTDZ bool
 
 	GlobalName        string
+	Globals           map[string]string // Global variable names for external imports with the "umd" format
 	Bundle            bool
 	Splitting         bool
+	ManualChunks      map[string][]string // Maps chunk names to package names or paths, e.g. "vendor" -> ["react", "./src/vendor/*"]
+	MinChunkSize      int                 // Shared chunks smaller than this many bytes are merged with other shared chunks
 	Outfile           string
 	Metafile          string
 	Outdir            string
 	Platform          Platform
 	Format            Format
 	External          []string
+	Packages          Packages
+	Alias             map[string]string // Substitutes one package for another, e.g. "react" -> "preact/compat"
+	ImportMap         string            // The path to a browser import map JSON file
 	MainFields        []string
+	Conditions        []string // For the "exports" and "imports" fields in "package.json"
 	Loader            map[string]Loader
 	ResolveExtensions []string
 	Tsconfig          string
@@ -229,6 +266,59 @@ type BuildOptions struct {
 	EntryPoints []string
 	Stdin       *StdinOptions
 	Write       bool
+	Incremental bool
+	Watch       *WatchMode
+	Plugins     []Plugin
+
+	// Only used by "Serve". Changed modules are pushed to the browser and
+	// re-executed in place instead of reloading the page.
+	HotModuleReplacement bool
+
+	// If present, this is layered on top of the real file system
+	FileSystem FileSystem
+
+	// Each of these is a path or a package name. The result will contain the
+	// shortest import chain from an entry point to a matching file.
+	Explain []string
+
+	// Generate a warning for each cycle of import statements. Cycles where a
+	// module reads an imported binding during module evaluation use the message
+	// ID "import-cycle" and other cycles use "import-cycle-harmless".
+	DetectCycles bool
+}
+
+// A file system that is layered on top of the real file system. All paths are
+// absolute. Returning an error for which "os.IsNotExist()" is true from either
+// method means the path is read from the real file system instead. Directory
+// listings are merged with the listings from the real file system.
+type FileSystem interface {
+	ReadFile(path string) (string, error)
+	ReadDirectory(path string) ([]DirEntry, error)
+}
+
+type DirEntry struct {
+	Name  string
+	IsDir bool
+}
+
+// This is a file system that holds files in memory. Directory listings are
+// generated for the parent directories of all files, so "Dirs" is only needed
+// for additional entries such as empty directories.
+type Overlay struct {
+	Files map[string]string     // Maps absolute paths to file contents
+	Dirs  map[string][]DirEntry // Maps absolute paths to extra entries
+}
+
+func (overlay Overlay) ReadFile(path string) (string, error) {
+	return overlayReadFileImpl(overlay, path)
+}
+
+func (overlay Overlay) ReadDirectory(path string) ([]DirEntry, error) {
+	return overlayReadDirectoryImpl(overlay, path)
+}
+
+type WatchMode struct {
+	OnRebuild func(BuildResult)
 }
 
 type StdinOptions struct {
@@ -242,7 +332,12 @@ type BuildResult struct {
 	Errors   []Message
 	Warnings []Message
 
-	OutputFiles []OutputFile
+	OutputFiles  []OutputFile
+	ImportChains []ImportChain // One for each item in "Explain"
+
+	Rebuild func() BuildResult // Only when "Incremental" is true
+	Dispose func()             // Only when "Incremental" is true
+	Stop    func()             // Only when "Watch" is non-nil
 }
 
 type OutputFile struct {
@@ -250,17 +345,38 @@ type OutputFile struct {
 	Contents []byte
 }
 
+type ImportChain struct {
+	Query string            // The path or package name from "Explain"
+	File  string            // Empty if no file in the bundle matches the query
+	Links []ImportChainLink // Starts with an entry point, empty if "File" is one
+}
+
+type ImportChainLink struct {
+	Importer   string
+	ImportPath string
+	Kind       string // "static", "require", "dynamic", "require-resolve", "at-rule", or "url"
+}
+
 func Build(options BuildOptions) BuildResult {
-	return buildImpl(options)
+	return buildImpl(context.Background(), options)
+}
+
+// This is the same as "Build" except that the build stops early if the context
+// is done before the build has finished. In that case the result contains an
+// error and no output files are written.
+func BuildWithContext(ctx context.Context, options BuildOptions) BuildResult {
+	return buildImpl(ctx, options)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // Transform API
 
 type TransformOptions struct {
-	Color      StderrColor
-	ErrorLimit int
-	LogLevel   LogLevel
+	Color       StderrColor
+	ErrorLimit  int
+	LogLevel    LogLevel
+	LogOverride map[string]LogLevel // Only "LogLevelWarning", "LogLevelError", and "LogLevelSilent" are allowed
+	LogFormat   LogFormat
 
 	Sourcemap  SourceMap
 	Target     Target
@@ -294,5 +410,154 @@ type TransformResult struct {
 }
 
 func Transform(input string, options TransformOptions) TransformResult {
-	return transformImpl(input, options)
+	return transformImpl(context.Background(), input, options)
+}
+
+// This is the same as "Transform" except that the transform stops early if the
+// context is done before the transform has finished. In that case the result
+// contains an error.
+func TransformWithContext(ctx context.Context, input string, options TransformOptions) TransformResult {
+	return transformImpl(ctx, input, options)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Resolve API
+
+type ResolveKind uint8
+
+const (
+	ResolveImportStatement ResolveKind = iota
+	ResolveRequireCall
+	ResolveDynamicImport
+)
+
+type ResolveOptions struct {
+	Platform          Platform
+	MainFields        []string
+	Conditions        []string
+	ResolveExtensions []string
+	External          []string
+	Packages          Packages
+	Alias             map[string]string
+	Tsconfig          string
+	ImportMap         string
+	Kind              ResolveKind
+	FileSystem        FileSystem
+}
+
+type ResolveResult struct {
+	Errors   []Message
+	Warnings []Message
+
+	Path      string
+	Namespace string
+	External  bool
+
+	// This is true if the nearest "package.json" file has a "sideEffects" field
+	// that doesn't include this file
+	SideEffectFree bool
+
+	// The "package.json" and "tsconfig.json" files that affected the result
+	AffectingFiles []string
+}
+
+// This resolves an import path the same way the bundler would if it were
+// imported from a file in the directory "resolveDir"
+func Resolve(importPath string, resolveDir string, options ResolveOptions) ResolveResult {
+	return resolveImpl(importPath, resolveDir, options)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Analyze API
+
+type AnalyzeMetafileOptions struct {
+	Color bool
+
+	// This reads each output file using the path in the metafile and includes
+	// its gzip-compressed size in the report. Paths in the metafile are relative
+	// to the directory the build was run from.
+	Gzip bool
+}
+
+// This turns the contents of a metafile into a human-readable report of the
+// input files that contribute the most bytes to each output file
+func AnalyzeMetafile(metafile string, options AnalyzeMetafileOptions) (string, error) {
+	return analyzeMetafileImpl(metafile, options)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Serve API
+
+type ServeOptions struct {
+	Port     uint16 // Zero means pick any available port
+	Host     string // Empty means "127.0.0.1"
+	Servedir string // Static files are served from here if present
+}
+
+type ServeResult struct {
+	Port uint16
+	Host string
+	Wait func() error
+	Stop func()
+}
+
+func Serve(serveOptions ServeOptions, buildOptions BuildOptions) (ServeResult, error) {
+	return serveImpl(serveOptions, buildOptions)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Plugin API
+
+type Plugin struct {
+	Name  string
+	Setup func(PluginBuild)
+}
+
+type PluginBuild interface {
+	OnResolve(options OnResolveOptions, callback func(OnResolveArgs) (OnResolveResult, error))
+	OnLoad(options OnLoadOptions, callback func(OnLoadArgs) (OnLoadResult, error))
+}
+
+type OnResolveOptions struct {
+	Filter    string
+	Namespace string
+}
+
+type OnResolveArgs struct {
+	Path       string
+	Importer   string
+	Namespace  string
+	ResolveDir string
+}
+
+type OnResolveResult struct {
+	PluginName string
+
+	Errors   []Message
+	Warnings []Message
+
+	Path      string
+	External  bool
+	Namespace string
+}
+
+type OnLoadOptions struct {
+	Filter    string
+	Namespace string
+}
+
+type OnLoadArgs struct {
+	Path      string
+	Namespace string
+}
+
+type OnLoadResult struct {
+	PluginName string
+
+	Errors   []Message
+	Warnings []Message
+
+	Contents   *string
+	ResolveDir *string
+	Loader     Loader
 }
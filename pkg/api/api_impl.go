@@ -1,16 +1,22 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/evanw/esbuild/internal/ast"
 	"github.com/evanw/esbuild/internal/bundler"
+	"github.com/evanw/esbuild/internal/cache"
 	"github.com/evanw/esbuild/internal/compat"
 	"github.com/evanw/esbuild/internal/config"
 	"github.com/evanw/esbuild/internal/fs"
@@ -32,6 +38,17 @@ func validatePlatform(value Platform) config.Platform {
 	}
 }
 
+func validatePackages(value Packages) bool {
+	switch value {
+	case PackagesDefault:
+		return false
+	case PackagesExternal:
+		return true
+	default:
+		panic("Invalid packages")
+	}
+}
+
 func validateFormat(value Format) config.Format {
 	switch value {
 	case FormatDefault:
@@ -42,6 +59,10 @@ func validateFormat(value Format) config.Format {
 		return config.FormatCommonJS
 	case FormatESModule:
 		return config.FormatESModule
+	case FormatUMD:
+		return config.FormatUMD
+	case FormatSystem:
+		return config.FormatSystem
 	default:
 		panic("Invalid format")
 	}
@@ -90,6 +111,38 @@ func validateLogLevel(value LogLevel) logger.LogLevel {
 	}
 }
 
+func validateLogFormat(value LogFormat) logger.StderrFormat {
+	switch value {
+	case LogFormatText:
+		return logger.FormatText
+	case LogFormatJSON:
+		return logger.FormatJSON
+	default:
+		panic("Invalid log format")
+	}
+}
+
+func validateLogOverrides(log logger.Log, value map[string]LogLevel) map[logger.MsgID]logger.LogLevel {
+	if len(value) == 0 {
+		return nil
+	}
+	overrides := make(map[logger.MsgID]logger.LogLevel, len(value))
+	for id, level := range value {
+		if !logger.IsKnownMsgID(logger.MsgID(id)) {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid log override %q", id))
+			continue
+		}
+		switch level {
+		case LogLevelWarning, LogLevelError, LogLevelSilent:
+			overrides[logger.MsgID(id)] = validateLogLevel(level)
+		default:
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf(
+				"The log override for %q must be \"warning\", \"error\", or \"silent\"", id))
+		}
+	}
+	return overrides
+}
+
 func validateASCIIOnly(value Charset) bool {
 	switch value {
 	case CharsetDefault, CharsetASCII:
@@ -103,6 +156,8 @@ func validateASCIIOnly(value Charset) bool {
 
 func validateLoader(value Loader) config.Loader {
 	switch value {
+	case LoaderNone:
+		return config.LoaderNone
 	case LoaderJS:
 		return config.LoaderJS
 	case LoaderJSX:
@@ -130,6 +185,14 @@ func validateLoader(value Loader) config.Loader {
 	}
 }
 
+func validateStdinLoader(value Loader) config.Loader {
+	// Input without a loader is JavaScript by default
+	if value == LoaderNone {
+		return config.LoaderJS
+	}
+	return validateLoader(value)
+}
+
 func validateEngine(value EngineName) compat.Engine {
 	switch value {
 	case EngineChrome:
@@ -226,13 +289,49 @@ func validateGlobalName(log logger.Log, text string) []string {
 	return nil
 }
 
+func validateGlobals(log logger.Log, globals map[string]string) map[string][]string {
+	if globals == nil {
+		return nil
+	}
+	result := make(map[string][]string, len(globals))
+	for path, text := range globals {
+		if path == "" {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid global path: %q", path))
+			continue
+		}
+		if name := validateGlobalName(log, text); name != nil {
+			result[path] = name
+		}
+	}
+	return result
+}
+
 func validateExternals(log logger.Log, fs fs.FS, paths []string) config.ExternalModules {
 	result := config.ExternalModules{
 		NodeModules: make(map[string]bool),
 		AbsPaths:    make(map[string]bool),
 	}
 	for _, path := range paths {
-		if resolver.IsPackagePath(path) {
+		if index := strings.IndexByte(path, '*'); index != -1 {
+			if strings.ContainsRune(path[index+1:], '*') {
+				log.AddError(nil, logger.Loc{}, fmt.Sprintf("External path %q cannot have more than one \"*\" wildcard", path))
+			} else {
+				pattern := config.WildcardPattern{Prefix: path[:index], Suffix: path[index+1:]}
+				result.Patterns = append(result.Patterns, pattern)
+
+				// Relative patterns should also match resolved paths, which are absolute
+				if pattern.Prefix != "" && !resolver.IsPackagePath(pattern.Prefix) && !fs.IsAbs(pattern.Prefix) {
+					if absPrefix := validatePath(log, fs, pattern.Prefix); absPrefix != "" {
+						// Cleaning the path removes the trailing separator, so add it back
+						if last := pattern.Prefix[len(pattern.Prefix)-1]; last == '/' || last == '\\' {
+							absPrefix = strings.TrimSuffix(fs.Join(absPrefix, "_"), "_")
+						}
+						pattern.Prefix = absPrefix
+						result.Patterns = append(result.Patterns, pattern)
+					}
+				}
+			}
+		} else if resolver.IsPackagePath(path) {
 			result.NodeModules[path] = true
 		} else if absPath := validatePath(log, fs, path); absPath != "" {
 			result.AbsPaths[absPath] = true
@@ -241,6 +340,60 @@ func validateExternals(log logger.Log, fs fs.FS, paths []string) config.External
 	return result
 }
 
+func validateAlias(log logger.Log, alias map[string]string) map[string]string {
+	if alias == nil {
+		return nil
+	}
+	result := make(map[string]string, len(alias))
+	for old, new := range alias {
+		// Only package paths can be aliased
+		if old == "" || !resolver.IsPackagePath(old) || strings.HasPrefix(old, "#") || strings.HasSuffix(old, "/") {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid alias name: %q", old))
+			continue
+		}
+		if new == "" {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid alias substitution: %q", new))
+			continue
+		}
+		result[old] = new
+	}
+	return result
+}
+
+func validateManualChunks(log logger.Log, fs fs.FS, manualChunks map[string][]string) []config.ManualChunk {
+	if manualChunks == nil {
+		return nil
+	}
+
+	// Sort the names for determinism
+	names := make([]string, 0, len(manualChunks))
+	for name := range manualChunks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]config.ManualChunk, 0, len(names))
+	for _, name := range names {
+		// The name is used in the file name of the chunk
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid manual chunk name: %q", name))
+			continue
+		}
+		chunk := config.ManualChunk{Name: name}
+		for _, pattern := range manualChunks[name] {
+			if pattern == "" {
+				log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid pattern for manual chunk %q: %q", name, pattern))
+			} else if !fs.IsAbs(pattern) && resolver.IsPackagePath(pattern) {
+				chunk.PackageNames = append(chunk.PackageNames, pattern)
+			} else if absPath := validatePath(log, fs, pattern); absPath != "" {
+				chunk.AbsPaths = append(chunk.AbsPaths, absPath)
+			}
+		}
+		result = append(result, chunk)
+	}
+	return result
+}
+
 func isValidExtension(ext string) bool {
 	return len(ext) >= 2 && ext[0] == '.' && ext[len(ext)-1] != '.'
 }
@@ -386,26 +539,70 @@ func validateOutputExtensions(log logger.Log, outExtensions map[string]string) m
 	return result
 }
 
+func convertMessagesToInternal(msgs []logger.Msg, kind logger.MsgKind, messages []Message) []logger.Msg {
+	for _, message := range messages {
+		var notes []logger.MsgData
+		for _, note := range message.Notes {
+			notes = append(notes, logger.MsgData{
+				Text:     note.Text,
+				Location: convertLocationToInternal(note.Location),
+			})
+		}
+
+		msgs = append(msgs, logger.Msg{
+			ID:       logger.MsgID(message.ID),
+			Kind:     kind,
+			Text:     message.Text,
+			Location: convertLocationToInternal(message.Location),
+			Notes:    notes,
+		})
+	}
+	return msgs
+}
+
+func convertLocationToInternal(loc *Location) *logger.MsgLocation {
+	if loc == nil {
+		return nil
+	}
+	return &logger.MsgLocation{
+		File:     loc.File,
+		Line:     loc.Line,
+		Column:   loc.Column,
+		Length:   loc.Length,
+		LineText: loc.LineText,
+	}
+}
+
+func convertLocationToPublic(loc *logger.MsgLocation) *Location {
+	if loc == nil {
+		return nil
+	}
+	return &Location{
+		File:     loc.File,
+		Line:     loc.Line,
+		Column:   loc.Column,
+		Length:   loc.Length,
+		LineText: loc.LineText,
+	}
+}
+
 func messagesOfKind(kind logger.MsgKind, msgs []logger.Msg) []Message {
 	var filtered []Message
 	for _, msg := range msgs {
 		if msg.Kind == kind {
-			var location *Location
-
-			if msg.Location != nil {
-				loc := msg.Location
-				location = &Location{
-					File:     loc.File,
-					Line:     loc.Line,
-					Column:   loc.Column,
-					Length:   loc.Length,
-					LineText: loc.LineText,
-				}
+			var notes []Note
+			for _, note := range msg.Notes {
+				notes = append(notes, Note{
+					Text:     note.Text,
+					Location: convertLocationToPublic(note.Location),
+				})
 			}
 
 			filtered = append(filtered, Message{
+				ID:       string(msg.ID),
 				Text:     msg.Text,
-				Location: location,
+				Location: convertLocationToPublic(msg.Location),
+				Notes:    notes,
 			})
 		}
 	}
@@ -415,16 +612,154 @@ func messagesOfKind(kind logger.MsgKind, msgs []logger.Msg) []Message {
 ////////////////////////////////////////////////////////////////////////////////
 // Build API
 
-func buildImpl(buildOpts BuildOptions) BuildResult {
+// This holds everything that is kept alive in between incremental builds
+type rebuildState struct {
+	mutex      sync.Mutex
+	buildOpts  BuildOptions
+	plugins    []config.Plugin
+	pluginMsgs []logger.Msg
+	caches     *cache.CacheSet
+	dirCache   *resolver.DirCache
+	zipCache   *fs.ZipCache
+	watcher    *watcher
+	isDisposed bool
+
+	// This is true if the caller needs to know which files were read
+	wantWatchData bool
+
+	// This is true if the output files are for the development server
+	serveFromMemory bool
+}
+
+func newRebuildState(buildOpts BuildOptions, keepCaches bool) *rebuildState {
+	// Plugins are only set up once, even if there are multiple builds
+	pluginLog := logger.NewDeferLog()
+	state := &rebuildState{
+		buildOpts:  buildOpts,
+		plugins:    loadPlugins(pluginLog, fs.RealFS(fs.RealFSOptions{}), buildOpts.Plugins),
+		pluginMsgs: pluginLog.Done(),
+	}
+
+	// Only keep the caches around if they will be used again
+	if keepCaches {
+		state.caches = cache.MakeCacheSet()
+		state.dirCache = resolver.NewDirCache()
+		state.zipCache = fs.NewZipCache()
+	}
+	return state
+}
+
+func buildImpl(ctx context.Context, buildOpts BuildOptions) BuildResult {
+	// Only the initial build can be cancelled. Rebuilds don't use the context.
+	cancel, release := cancelFlagForContext(ctx)
+	defer release()
+
+	if !buildOpts.Incremental && buildOpts.Watch == nil {
+		result, _ := rebuildImpl(newRebuildState(buildOpts, false), cancel)
+		return result
+	}
+	state := newRebuildState(buildOpts, true)
+	state.wantWatchData = buildOpts.Watch != nil
+
+	result, watchData := rebuildImpl(state, cancel)
+	state.decorateResult(&result)
+
+	// Start watching after the first build has finished
+	if buildOpts.Watch != nil {
+		state.watcher = &watcher{
+			data:    watchData,
+			rebuild: state.rebuildForWatch,
+		}
+		state.watcher.start()
+	}
+	return result
+}
+
+func (state *rebuildState) decorateResult(result *BuildResult) {
+	if state.buildOpts.Incremental {
+		result.Rebuild = state.rebuild
+		result.Dispose = state.dispose
+	}
+	if state.buildOpts.Watch != nil {
+		result.Stop = state.stop
+	}
+}
+
+func (state *rebuildState) rebuild() BuildResult {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if state.isDisposed {
+		return BuildResult{
+			Errors: []Message{{Text: "Cannot rebuild after dispose() has been called"}},
+		}
+	}
+
+	result, watchData := rebuildImpl(state, nil)
+	state.decorateResult(&result)
+	if state.watcher != nil {
+		state.watcher.setWatchData(watchData)
+	}
+	return result
+}
+
+func (state *rebuildState) rebuildForWatch() {
+	result := state.rebuild()
+	if onRebuild := state.buildOpts.Watch.OnRebuild; onRebuild != nil {
+		onRebuild(result)
+	}
+}
+
+func (state *rebuildState) stop() {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	if state.watcher != nil {
+		state.watcher.stop()
+	}
+}
+
+func (state *rebuildState) dispose() {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+
+	// Disposing also stops watching since rebuilding is no longer possible
+	if state.watcher != nil {
+		state.watcher.stop()
+	}
+
+	// Release the caches so their memory can be reclaimed
+	state.isDisposed = true
+	state.caches = nil
+	state.dirCache = nil
+	state.zipCache = nil
+}
+
+func rebuildImpl(state *rebuildState, cancel *config.CancelFlag) (BuildResult, fs.WatchData) {
+	buildOpts := state.buildOpts
 	log := logger.NewStderrLog(logger.StderrOptions{
 		IncludeSource: true,
 		ErrorLimit:    buildOpts.ErrorLimit,
 		Color:         validateColor(buildOpts.Color),
 		LogLevel:      validateLogLevel(buildOpts.LogLevel),
+		Format:        validateLogFormat(buildOpts.LogFormat),
+	})
+	log = logger.OverrideLog(log, validateLogOverrides(log, buildOpts.LogOverride))
+	for _, msg := range state.pluginMsgs {
+		log.AddMsg(msg)
+	}
+
+	// Convert and validate the buildOpts. A new file system object is created
+	// for each build since it caches directory listings.
+	realFS := fs.RealFS(fs.RealFSOptions{
+		WantWatchData: state.wantWatchData,
 	})
+	if buildOpts.FileSystem != nil {
+		realFS = fs.LayeredFS(fsLayer{buildOpts.FileSystem}, realFS)
+	}
 
-	// Convert and validate the buildOpts
-	realFS := fs.RealFS()
+	// Yarn Plug'n'Play stores packages in zip archives
+	realFS = fs.ZipFS(realFS, state.zipCache)
 	jsFeatures, cssFeatures := validateFeatures(log, buildOpts.Target, buildOpts.Engines)
 	options := config.Options{
 		UnsupportedJSFeatures:  jsFeatures,
@@ -441,7 +776,10 @@ func buildImpl(buildOpts BuildOptions) BuildResult {
 		MinifyIdentifiers: buildOpts.MinifyIdentifiers,
 		ASCIIOnly:         validateASCIIOnly(buildOpts.Charset),
 		ModuleName:        validateGlobalName(log, buildOpts.GlobalName),
+		UMDGlobals:        validateGlobals(log, buildOpts.Globals),
 		CodeSplitting:     buildOpts.Splitting,
+		ManualChunks:      validateManualChunks(log, realFS, buildOpts.ManualChunks),
+		MinChunkSize:      buildOpts.MinChunkSize,
 		OutputFormat:      validateFormat(buildOpts.Format),
 		AbsOutputFile:     validatePath(log, realFS, buildOpts.Outfile),
 		AbsOutputDir:      validatePath(log, realFS, buildOpts.Outdir),
@@ -450,12 +788,22 @@ func buildImpl(buildOpts BuildOptions) BuildResult {
 		ExtensionToLoader: validateLoaders(log, buildOpts.Loader),
 		ExtensionOrder:    validateResolveExtensions(log, buildOpts.ResolveExtensions),
 		ExternalModules:   validateExternals(log, realFS, buildOpts.External),
+		ExternalPackages:  validatePackages(buildOpts.Packages),
 		TsConfigOverride:  validatePath(log, realFS, buildOpts.Tsconfig),
 		MainFields:        buildOpts.MainFields,
+		Conditions:        buildOpts.Conditions,
+		PackageAliases:    validateAlias(log, buildOpts.Alias),
+		ImportMapPath:     validatePath(log, realFS, buildOpts.ImportMap),
 		PublicPath:        buildOpts.PublicPath,
 		AvoidTDZ:          buildOpts.AvoidTDZ,
 		InjectAbsPaths:    make([]string, len(buildOpts.Inject)),
+		ServeFromMemory:   state.serveFromMemory,
+		CancelFlag:        cancel,
+
+		HotModuleReplacement: buildOpts.HotModuleReplacement,
+		DetectCycles:         buildOpts.DetectCycles,
 	}
+	options.Plugins = state.plugins
 	for i, path := range buildOpts.Inject {
 		options.InjectAbsPaths[i] = validatePath(log, realFS, path)
 	}
@@ -470,7 +818,7 @@ func buildImpl(buildOpts BuildOptions) BuildResult {
 	if buildOpts.Stdin != nil {
 		entryPathCount++
 		options.Stdin = &config.StdinInfo{
-			Loader:        validateLoader(buildOpts.Stdin.Loader),
+			Loader:        validateStdinLoader(buildOpts.Stdin.Loader),
 			Contents:      buildOpts.Stdin.Contents,
 			SourceFile:    buildOpts.Stdin.Sourcefile,
 			AbsResolveDir: validatePath(log, realFS, buildOpts.Stdin.ResolveDir),
@@ -532,23 +880,63 @@ func buildImpl(buildOpts BuildOptions) BuildResult {
 		options.Mode = config.ModeConvertFormat
 	}
 
-	// Code splitting is experimental and currently only enabled for ES6 modules
-	if options.CodeSplitting && options.OutputFormat != config.FormatESModule {
-		log.AddError(nil, logger.Loc{}, "Splitting currently only works with the \"esm\" format")
+	// Code splitting is experimental and currently only enabled for formats
+	// that support loading other chunks
+	if options.CodeSplitting {
+		// The format must be explicit because the default format depends on the
+		// platform, and splitting changes how the generated code is loaded
+		if buildOpts.Format == FormatDefault || options.OutputFormat == config.FormatUMD {
+			log.AddError(nil, logger.Loc{}, "Splitting currently only works with the \"esm\", \"system\", \"cjs\", and \"iife\" formats")
+		} else if options.OutputFormat == config.FormatIIFE && len(options.ModuleName) > 0 {
+			// Chunks are loaded asynchronously, so there's nothing to assign to the
+			// global variable when the entry point is evaluated
+			log.AddError(nil, logger.Loc{}, "Cannot use \"globalName\" with \"splitting\" and the \"iife\" format")
+		}
+	} else if len(options.ManualChunks) > 0 {
+		log.AddError(nil, logger.Loc{}, "Cannot use \"manualChunks\" without \"splitting\"")
+	} else if options.MinChunkSize != 0 {
+		log.AddError(nil, logger.Loc{}, "Cannot use \"minChunkSize\" without \"splitting\"")
+	}
+	if options.MinChunkSize < 0 {
+		log.AddError(nil, logger.Loc{}, fmt.Sprintf("Invalid minimum chunk size: %d", options.MinChunkSize))
+	}
+
+	// Hot module replacement needs the development server to push updates and
+	// needs every module to be wrapped, which only happens when bundling
+	if options.HotModuleReplacement {
+		if !state.serveFromMemory {
+			log.AddError(nil, logger.Loc{}, "Hot module replacement only works with \"serve\"")
+		} else if !buildOpts.Bundle {
+			log.AddError(nil, logger.Loc{}, "Cannot use hot module replacement without \"bundle\"")
+		} else if options.CodeSplitting {
+			log.AddError(nil, logger.Loc{}, "Cannot use hot module replacement with \"splitting\"")
+		} else if options.OutputFormat != config.FormatIIFE && options.OutputFormat != config.FormatESModule {
+			log.AddError(nil, logger.Loc{}, "Hot module replacement only works with the \"iife\" and \"esm\" formats")
+		}
 	}
 
 	var outputFiles []OutputFile
+	var importChains []ImportChain
 
 	// Stop now if there were errors
 	if !log.HasErrors() {
 		// Scan over the bundle
-		resolver := resolver.NewResolver(realFS, log, options)
-		bundle := bundler.ScanBundle(log, realFS, resolver, entryPaths, options)
+		resolver := resolver.NewResolver(realFS, log, state.dirCache, options)
+		bundle := bundler.ScanBundle(log, realFS, resolver, state.caches, entryPaths, options)
+		checkForCancellation(log, cancel, "build")
+
+		// Explaining import chains only needs the import graph
+		if !log.HasErrors() {
+			for _, query := range buildOpts.Explain {
+				importChains = append(importChains, convertImportChain(query, bundle))
+			}
+		}
 
 		// Stop now if there were errors
 		if !log.HasErrors() {
 			// Compile the bundle
 			results := bundle.Compile(log, options)
+			checkForCancellation(log, cancel, "build")
 
 			// Stop now if there were errors
 			if !log.HasErrors() {
@@ -607,22 +995,295 @@ func buildImpl(buildOpts BuildOptions) BuildResult {
 
 	msgs := log.Done()
 	return BuildResult{
-		Errors:      messagesOfKind(logger.Error, msgs),
-		Warnings:    messagesOfKind(logger.Warning, msgs),
-		OutputFiles: outputFiles,
+		Errors:       messagesOfKind(logger.Error, msgs),
+		Warnings:     messagesOfKind(logger.Warning, msgs),
+		OutputFiles:  outputFiles,
+		ImportChains: importChains,
+	}, realFS.WatchData()
+}
+
+func convertImportChain(query string, bundle bundler.Bundle) ImportChain {
+	result := ImportChain{Query: query}
+	if chain, ok := bundle.FindImportChain(query); ok {
+		result.File = chain.PrettyPath
+		for _, link := range chain.Links {
+			var kind string
+			switch link.Kind {
+			case ast.ImportStmt:
+				kind = "static"
+			case ast.ImportRequire:
+				kind = "require"
+			case ast.ImportDynamic:
+				kind = "dynamic"
+			case ast.ImportRequireResolve:
+				kind = "require-resolve"
+			case ast.ImportAt:
+				kind = "at-rule"
+			case ast.ImportURL:
+				kind = "url"
+			}
+			result.Links = append(result.Links, ImportChainLink{
+				Importer:   link.ImporterPrettyPath,
+				ImportPath: link.ImportPath,
+				Kind:       kind,
+			})
+		}
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Resolve API
+
+func validateResolveKind(value ResolveKind) ast.ImportKind {
+	switch value {
+	case ResolveImportStatement:
+		return ast.ImportStmt
+	case ResolveRequireCall:
+		return ast.ImportRequire
+	case ResolveDynamicImport:
+		return ast.ImportDynamic
+	default:
+		panic("Invalid resolve kind")
+	}
+}
+
+func resolveImpl(importPath string, resolveDir string, resolveOpts ResolveOptions) ResolveResult {
+	log := logger.NewDeferLog()
+	realFS := fs.RealFS(fs.RealFSOptions{})
+	if resolveOpts.FileSystem != nil {
+		realFS = fs.LayeredFS(fsLayer{resolveOpts.FileSystem}, realFS)
+	}
+
+	// Yarn Plug'n'Play stores packages in zip archives
+	realFS = fs.ZipFS(realFS, nil)
+
+	// Convert and validate the resolveOpts
+	options := config.Options{
+		Platform:         validatePlatform(resolveOpts.Platform),
+		MainFields:       resolveOpts.MainFields,
+		Conditions:       resolveOpts.Conditions,
+		ExtensionOrder:   validateResolveExtensions(log, resolveOpts.ResolveExtensions),
+		ExternalModules:  validateExternals(log, realFS, resolveOpts.External),
+		ExternalPackages: validatePackages(resolveOpts.Packages),
+		TsConfigOverride: validatePath(log, realFS, resolveOpts.Tsconfig),
+		PackageAliases:   validateAlias(log, resolveOpts.Alias),
+		ImportMapPath:    validatePath(log, realFS, resolveOpts.ImportMap),
+	}
+	absResolveDir := validatePath(log, realFS, resolveDir)
+	if absResolveDir == "" {
+		absResolveDir = realFS.Cwd()
 	}
+	kind := validateResolveKind(resolveOpts.Kind)
+
+	var result ResolveResult
+
+	// Stop now if there were errors
+	if !log.HasErrors() {
+		res := resolver.NewResolver(realFS, log, nil, options)
+		resolveResult, affectingFiles, debug := res.ResolveWithAffectingFiles(absResolveDir, "", importPath, kind)
+		if resolveResult == nil {
+			log.AddMsg(logger.Msg{
+				Kind:  logger.Error,
+				Text:  fmt.Sprintf("Could not resolve %q", importPath),
+				Notes: debug.Notes,
+			})
+		} else {
+			result.Path = resolveResult.PathPair.Primary.Text
+			result.Namespace = resolveResult.PathPair.Primary.Namespace
+			result.External = resolveResult.IsExternal
+			result.SideEffectFree = resolveResult.IgnoreIfUnused
+			result.AffectingFiles = affectingFiles
+		}
+	}
+
+	msgs := log.Done()
+	result.Errors = messagesOfKind(logger.Error, msgs)
+	result.Warnings = messagesOfKind(logger.Warning, msgs)
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// File system API
+
+// This adapts a file system from the public API to the internal interface
+type fsLayer struct {
+	fs FileSystem
+}
+
+func (layer fsLayer) ReadDirectory(path string) (map[string]fs.EntryKind, error) {
+	dirEntries, err := layer.fs.ReadDirectory(path)
+	if err != nil {
+		return nil, err
+	}
+	kinds := make(map[string]fs.EntryKind, len(dirEntries))
+	for _, entry := range dirEntries {
+		if entry.IsDir {
+			kinds[entry.Name] = fs.DirEntry
+		} else {
+			kinds[entry.Name] = fs.FileEntry
+		}
+	}
+	return kinds, nil
+}
+
+func (layer fsLayer) ReadFile(path string) (string, error) {
+	return layer.fs.ReadFile(path)
+}
+
+func overlayReadFileImpl(overlay Overlay, path string) (string, error) {
+	if contents, ok := overlay.Files[path]; ok {
+		return contents, nil
+	}
+	return "", os.ErrNotExist
+}
+
+func overlayReadDirectoryImpl(overlay Overlay, dir string) ([]DirEntry, error) {
+	found := false
+	isDir := make(map[string]bool)
+
+	// Each path inside the directory adds the first component of the relative
+	// path to the listing, which is a directory unless that's the whole path
+	addPath := func(path string, isDirPath bool) {
+		rel, err := filepath.Rel(dir, filepath.Clean(path))
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return
+		}
+		name := rel
+		if slash := strings.IndexRune(rel, filepath.Separator); slash != -1 {
+			name = rel[:slash]
+			isDirPath = true
+		}
+		isDir[name] = isDir[name] || isDirPath
+		found = true
+	}
+	for path := range overlay.Files {
+		addPath(path, false)
+	}
+	for path, entries := range overlay.Dirs {
+		if filepath.Clean(path) == dir {
+			found = true
+			for _, entry := range entries {
+				isDir[entry.Name] = isDir[entry.Name] || entry.IsDir
+			}
+		} else {
+			addPath(path, true)
+		}
+	}
+
+	if !found {
+		return nil, os.ErrNotExist
+	}
+	names := make([]string, 0, len(isDir))
+	for name := range isDir {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Sort for determinism
+	entries := make([]DirEntry, len(names))
+	for i, name := range names {
+		entries[i] = DirEntry{Name: name, IsDir: isDir[name]}
+	}
+	return entries, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Cancellation
+
+// The bundler checks a flag instead of a context, so this sets the flag when
+// the context is done. The returned function must be called once the work is
+// over to stop waiting on the context.
+func cancelFlagForContext(ctx context.Context) (*config.CancelFlag, func()) {
+	flag := &config.CancelFlag{}
+	if ctx.Err() != nil {
+		flag.Cancel()
+	}
+
+	// Contexts that can never be done don't need a goroutine
+	if ctx.Done() == nil {
+		return flag, func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			flag.Cancel()
+		case <-done:
+		}
+	}()
+	return flag, func() { close(done) }
+}
+
+// Partial results from a cancelled build are incomplete, so this adds an
+// error to make sure they are never used
+func checkForCancellation(log logger.Log, cancel *config.CancelFlag, kind string) {
+	if cancel.DidCancel() && !log.HasErrors() {
+		log.AddError(nil, logger.Loc{}, fmt.Sprintf("The %s was canceled", kind))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Watch mode
+
+// The file system is polled since that works everywhere without needing any
+// platform-specific APIs
+const watchPollInterval = 100 * time.Millisecond
+
+type watcher struct {
+	mutex      sync.Mutex
+	data       fs.WatchData
+	rebuild    func()
+	shouldStop int32
+}
+
+func (w *watcher) setWatchData(data fs.WatchData) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.data = data
+}
+
+func (w *watcher) start() {
+	go func() {
+		for atomic.LoadInt32(&w.shouldStop) == 0 {
+			time.Sleep(watchPollInterval)
+			if atomic.LoadInt32(&w.shouldStop) == 0 && w.findDirtyPath() != "" {
+				w.rebuild()
+			}
+		}
+	}()
+}
+
+func (w *watcher) stop() {
+	atomic.StoreInt32(&w.shouldStop, 1)
+}
+
+func (w *watcher) findDirtyPath() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, isDirty := range w.data.Paths {
+		if dirtyPath := isDirty(); dirtyPath != "" {
+			return dirtyPath
+		}
+	}
+	return ""
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // Transform API
 
-func transformImpl(input string, transformOpts TransformOptions) TransformResult {
+func transformImpl(ctx context.Context, input string, transformOpts TransformOptions) TransformResult {
+	cancel, release := cancelFlagForContext(ctx)
+	defer release()
+
 	log := logger.NewStderrLog(logger.StderrOptions{
 		IncludeSource: true,
 		ErrorLimit:    transformOpts.ErrorLimit,
 		Color:         validateColor(transformOpts.Color),
 		LogLevel:      validateLogLevel(transformOpts.LogLevel),
+		Format:        validateLogFormat(transformOpts.LogFormat),
 	})
+	log = logger.OverrideLog(log, validateLogOverrides(log, transformOpts.LogOverride))
 
 	// Settings from the user come first
 	preserveUnusedImportsTS := false
@@ -673,8 +1334,9 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		AvoidTDZ:                transformOpts.AvoidTDZ,
 		UseDefineForClassFields: useDefineForClassFieldsTS,
 		PreserveUnusedImportsTS: preserveUnusedImportsTS,
+		CancelFlag:              cancel,
 		Stdin: &config.StdinInfo{
-			Loader:     validateLoader(transformOpts.Loader),
+			Loader:     validateStdinLoader(transformOpts.Loader),
 			Contents:   input,
 			SourceFile: transformOpts.Sourcefile,
 		},
@@ -699,13 +1361,15 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 	if !log.HasErrors() {
 		// Scan over the bundle
 		mockFS := fs.MockFS(make(map[string]string))
-		resolver := resolver.NewResolver(mockFS, log, options)
-		bundle := bundler.ScanBundle(log, mockFS, resolver, nil, options)
+		resolver := resolver.NewResolver(mockFS, log, nil, options)
+		bundle := bundler.ScanBundle(log, mockFS, resolver, nil, nil, options)
+		checkForCancellation(log, cancel, "transform")
 
 		// Stop now if there were errors
 		if !log.HasErrors() {
 			// Compile the bundle
 			results = bundle.Compile(log, options)
+			checkForCancellation(log, cancel, "transform")
 		}
 	}
 
@@ -733,3 +1397,123 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		Map:      sourceMap,
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// Plugin API
+
+type pluginImpl struct {
+	log    logger.Log
+	fs     fs.FS
+	plugin config.Plugin
+}
+
+func (impl *pluginImpl) OnResolve(options OnResolveOptions, callback func(OnResolveArgs) (OnResolveResult, error)) {
+	filter := impl.validateFilter("OnResolve", options.Filter)
+	if filter == nil {
+		return
+	}
+
+	impl.plugin.OnResolve = append(impl.plugin.OnResolve, config.OnResolve{
+		Filter:    filter,
+		Namespace: options.Namespace,
+		Callback: func(args config.OnResolveArgs) (result config.OnResolveResult) {
+			response, err := callback(OnResolveArgs{
+				Path:       args.Path,
+				Importer:   args.Importer.Text,
+				Namespace:  args.Importer.Namespace,
+				ResolveDir: args.ResolveDir,
+			})
+			result.PluginName = response.PluginName
+
+			if err != nil {
+				result.ThrownError = err
+				return
+			}
+
+			// Paths without a namespace are assumed to be file system paths
+			result.Path = logger.Path{Text: response.Path, Namespace: response.Namespace}
+			if result.Path.Namespace == "" && !response.External {
+				result.Path.Namespace = "file"
+			}
+			result.External = response.External
+			result.Msgs = convertMessagesToInternal(result.Msgs, logger.Error, response.Errors)
+			result.Msgs = convertMessagesToInternal(result.Msgs, logger.Warning, response.Warnings)
+			return
+		},
+	})
+}
+
+func (impl *pluginImpl) OnLoad(options OnLoadOptions, callback func(OnLoadArgs) (OnLoadResult, error)) {
+	filter := impl.validateFilter("OnLoad", options.Filter)
+	if filter == nil {
+		return
+	}
+
+	impl.plugin.OnLoad = append(impl.plugin.OnLoad, config.OnLoad{
+		Filter:    filter,
+		Namespace: options.Namespace,
+		Callback: func(args config.OnLoadArgs) (result config.OnLoadResult) {
+			response, err := callback(OnLoadArgs{
+				Path:      args.Path.Text,
+				Namespace: args.Path.Namespace,
+			})
+			result.PluginName = response.PluginName
+
+			if err != nil {
+				result.ThrownError = err
+				return
+			}
+
+			result.Contents = response.Contents
+			result.Loader = validateLoader(response.Loader)
+			if response.ResolveDir != nil {
+				if absPath, ok := impl.fs.Abs(*response.ResolveDir); ok {
+					result.AbsResolveDir = absPath
+				} else {
+					result.Msgs = append(result.Msgs, logger.Msg{Kind: logger.Error,
+						Text: fmt.Sprintf("Invalid resolve directory: %s", *response.ResolveDir)})
+				}
+			}
+			result.Msgs = convertMessagesToInternal(result.Msgs, logger.Error, response.Errors)
+			result.Msgs = convertMessagesToInternal(result.Msgs, logger.Warning, response.Warnings)
+			return
+		},
+	})
+}
+
+func (impl *pluginImpl) validateFilter(kind string, filter string) *regexp.Regexp {
+	if filter == "" {
+		impl.log.AddError(nil, logger.Loc{}, fmt.Sprintf("[%s] %s is missing a filter", impl.plugin.Name, kind))
+		return nil
+	}
+
+	result, err := regexp.Compile(filter)
+	if err != nil {
+		impl.log.AddError(nil, logger.Loc{}, fmt.Sprintf("[%s] %s filter is not a valid Go regular expression: %q",
+			impl.plugin.Name, kind, filter))
+		return nil
+	}
+
+	return result
+}
+
+func loadPlugins(log logger.Log, fs fs.FS, plugins []Plugin) (results []config.Plugin) {
+	for i, item := range plugins {
+		if item.Name == "" {
+			log.AddError(nil, logger.Loc{}, fmt.Sprintf("Plugin at index %d is missing a name", i))
+			continue
+		}
+
+		impl := &pluginImpl{
+			log:    log,
+			fs:     fs,
+			plugin: config.Plugin{Name: item.Name},
+		}
+
+		if item.Setup != nil {
+			item.Setup(impl)
+		}
+		results = append(results, impl.plugin)
+	}
+	return
+}
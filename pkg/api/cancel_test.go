@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildWithContextCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "in.js"), []byte("console.log(123)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	options := BuildOptions{
+		EntryPoints: []string{filepath.Join(dir, "in.js")},
+		Outfile:     filepath.Join(dir, "out.js"),
+		Bundle:      true,
+		Write:       true,
+	}
+
+	// A cancelled build reports an error and doesn't write anything
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := BuildWithContext(ctx, options)
+	if len(result.Errors) != 1 || result.Errors[0].Text != "The build was canceled" {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if len(result.OutputFiles) != 0 {
+		t.Fatalf("Unexpected output files: %v", result.OutputFiles)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out.js")); !os.IsNotExist(err) {
+		t.Fatal("Expected no output file to be written")
+	}
+
+	// The same build works if the context isn't cancelled
+	result = BuildWithContext(context.Background(), options)
+	if len(result.Errors) != 0 || len(result.OutputFiles) != 1 {
+		t.Fatalf("Unexpected result: %v", result)
+	}
+}
+
+func TestTransformWithContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := TransformWithContext(ctx, "let x = 1", TransformOptions{})
+	if len(result.Errors) != 1 || result.Errors[0].Text != "The transform was canceled" {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if len(result.Code) != 0 {
+		t.Fatalf("Unexpected code: %q", result.Code)
+	}
+
+	result = TransformWithContext(context.Background(), "let x = 1", TransformOptions{})
+	if len(result.Errors) != 0 || string(result.Code) != "let x = 1;\n" {
+		t.Fatalf("Unexpected result: %v", result)
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExplainImportChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-explain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Paths in the results are relative to the current directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel := func(name string) string {
+		t.Helper()
+		path, err := filepath.Rel(cwd, filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return filepath.ToSlash(path)
+	}
+
+	result := Build(BuildOptions{
+		EntryPoints: []string{filepath.Join(dir, "entry.js")},
+		Outdir:      filepath.Join(dir, "out"),
+		Bundle:      true,
+		Format:      FormatESModule,
+		Explain:     []string{"pkg", filepath.Join(dir, "style.css"), filepath.Join(dir, "entry.js"), "missing"},
+		FileSystem: Overlay{Files: map[string]string{
+			filepath.Join(dir, "entry.js"):                        "import './a'; import('./b')",
+			filepath.Join(dir, "a.js"):                            "require('pkg'); import './style.css'",
+			filepath.Join(dir, "b.js"):                            "import 'pkg/other'",
+			filepath.Join(dir, "style.css"):                       "a { color: red }",
+			filepath.Join(dir, "node_modules", "pkg", "index.js"): "module.exports = 1",
+			filepath.Join(dir, "node_modules", "pkg", "other.js"): "export default 2",
+		}},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+
+	expected := []ImportChain{
+		{
+			Query: "pkg",
+			File:  rel("node_modules/pkg/index.js"),
+			Links: []ImportChainLink{
+				{Importer: rel("entry.js"), ImportPath: "./a", Kind: "static"},
+				{Importer: rel("a.js"), ImportPath: "pkg", Kind: "require"},
+			},
+		},
+		{
+			Query: filepath.Join(dir, "style.css"),
+			File:  rel("style.css"),
+			Links: []ImportChainLink{
+				{Importer: rel("entry.js"), ImportPath: "./a", Kind: "static"},
+				{Importer: rel("a.js"), ImportPath: "./style.css", Kind: "static"},
+			},
+		},
+		{
+			Query: filepath.Join(dir, "entry.js"),
+			File:  rel("entry.js"),
+		},
+		{
+			Query: "missing",
+		},
+	}
+	if !reflect.DeepEqual(result.ImportChains, expected) {
+		t.Fatalf("Expected %+v, got %+v", expected, result.ImportChains)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestMessageIDsAndNotes(t *testing.T) {
+	result := Transform(`{"a": 1, "a": 2}`, TransformOptions{Loader: LoaderJSON})
+	if len(result.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if len(result.Warnings) != 1 {
+		t.Fatalf("Expected one warning, got %v", result.Warnings)
+	}
+	msg := result.Warnings[0]
+	if msg.ID != "duplicate-object-key" || msg.Text != `Duplicate key: "a"` || msg.Location == nil || msg.Location.Column != 9 {
+		t.Fatalf("Unexpected warning: %+v", msg)
+	}
+	if len(msg.Notes) != 1 || msg.Notes[0].Location == nil || msg.Notes[0].Location.Column != 1 {
+		t.Fatalf("Unexpected notes: %+v", msg.Notes)
+	}
+}
+
+func TestLogOverride(t *testing.T) {
+	const code = "switch (x) { case 1: case 1: }"
+
+	check := func(overrides map[string]LogLevel, expectedErrors []string, expectedWarnings []string) {
+		t.Helper()
+		result := Transform(code, TransformOptions{LogOverride: overrides})
+		if len(result.Errors) != len(expectedErrors) || len(result.Warnings) != len(expectedWarnings) {
+			t.Fatalf("Expected errors %v and warnings %v, got errors %v and warnings %v",
+				expectedErrors, expectedWarnings, result.Errors, result.Warnings)
+		}
+		for i, msg := range result.Errors {
+			if msg.Text != expectedErrors[i] {
+				t.Fatalf("Expected errors %v, got %v", expectedErrors, result.Errors)
+			}
+		}
+		for i, msg := range result.Warnings {
+			if msg.Text != expectedWarnings[i] {
+				t.Fatalf("Expected warnings %v, got %v", expectedWarnings, result.Warnings)
+			}
+		}
+	}
+
+	duplicate := "This case clause will never be evaluated because it duplicates an earlier case clause"
+	check(nil, nil, []string{duplicate})
+	check(map[string]LogLevel{"duplicate-case": LogLevelWarning}, nil, []string{duplicate})
+	check(map[string]LogLevel{"duplicate-case": LogLevelError}, []string{duplicate}, nil)
+	check(map[string]LogLevel{"duplicate-case": LogLevelSilent}, nil, nil)
+	check(map[string]LogLevel{"duplicate-object-key": LogLevelError}, nil, []string{duplicate})
+
+	// Invalid overrides are errors, which stop the transform early
+	check(map[string]LogLevel{"not-a-real-id": LogLevelError}, []string{`Invalid log override "not-a-real-id"`}, nil)
+	check(map[string]LogLevel{"duplicate-case": LogLevelInfo},
+		[]string{`The log override for "duplicate-case" must be "warning", "error", or "silent"`}, nil)
+}
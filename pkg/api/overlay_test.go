@@ -0,0 +1,95 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Only one file exists on disk and it's hidden by the overlay
+	if err := ioutil.WriteFile(filepath.Join(dir, "disk.js"), []byte("export let disk = 'disk'"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "hidden.js"), []byte("export let hidden = 'disk'"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := Build(BuildOptions{
+		EntryPoints: []string{filepath.Join(dir, "entry.js")},
+		Bundle:      true,
+		FileSystem: Overlay{
+			Files: map[string]string{
+				filepath.Join(dir, "entry.js"): `
+					import {disk} from './disk'
+					import {hidden} from './hidden'
+					import {gen} from './gen/nested'
+					import pkg from 'pkg'
+					console.log(disk, hidden, gen, pkg)
+				`,
+				filepath.Join(dir, "hidden.js"):                           "export let hidden = 'overlay'",
+				filepath.Join(dir, "gen", "nested.js"):                    "export let gen = 'generated'",
+				filepath.Join(dir, "node_modules", "pkg", "package.json"): `{"main": "main.js"}`,
+				filepath.Join(dir, "node_modules", "pkg", "main.js"):      "module.exports = 'pkg'",
+			},
+		},
+	})
+	if len(result.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	if len(result.OutputFiles) != 1 {
+		t.Fatalf("Expected one output file, got %d", len(result.OutputFiles))
+	}
+	code := string(result.OutputFiles[0].Contents)
+	for _, text := range []string{`"disk"`, `"overlay"`, `"generated"`, `"pkg"`} {
+		if !strings.Contains(code, text) {
+			t.Fatalf("Expected %s in output:\n%s", text, code)
+		}
+	}
+	if strings.Contains(code, `hidden = "disk"`) {
+		t.Fatalf("Expected the overlay to hide the file on disk:\n%s", code)
+	}
+}
+
+func TestOverlayReadDirectory(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "root")
+	overlay := Overlay{
+		Files: map[string]string{
+			filepath.Join(root, "a.js"):        "",
+			filepath.Join(root, "sub", "b.js"): "",
+		},
+		Dirs: map[string][]DirEntry{
+			root:                         {{Name: "c.js"}},
+			filepath.Join(root, "empty"): {},
+		},
+	}
+
+	entries, err := overlay.ReadDirectory(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []DirEntry{{Name: "a.js"}, {Name: "c.js"}, {Name: "empty", IsDir: true}, {Name: "sub", IsDir: true}}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, entries)
+	}
+	for i, entry := range entries {
+		if entry != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, entries)
+		}
+	}
+
+	if _, err := overlay.ReadDirectory(filepath.Join(root, "missing")); !os.IsNotExist(err) {
+		t.Fatalf("Expected a missing directory, got %v", err)
+	}
+	if _, err := overlay.ReadFile(filepath.Join(root, "missing.js")); !os.IsNotExist(err) {
+		t.Fatalf("Expected a missing file, got %v", err)
+	}
+}
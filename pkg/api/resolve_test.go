@@ -0,0 +1,140 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-resolve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pkgJSON := filepath.Join(dir, "node_modules", "pkg", "package.json")
+	tsconfigJSON := filepath.Join(dir, "tsconfig.json")
+	importMapJSON := filepath.Join(dir, "importmap.json")
+	overlay := Overlay{Files: map[string]string{
+		filepath.Join(dir, "src", "entry.ts"):  "",
+		filepath.Join(dir, "src", "util.ts"):   "",
+		filepath.Join(dir, "lib", "helper.ts"): "",
+		tsconfigJSON:                           `{"compilerOptions": {"baseUrl": ".", "paths": {"@lib/*": ["lib/*"]}}}`,
+		importMapJSON:                          `{"imports": {"mapped": "./lib/helper.ts"}, "scopes": {"/src/": {"scoped": "./src/util.ts"}}}`,
+		pkgJSON:                                `{"main": "main.js", "module": "module.js", "sideEffects": false}`,
+		filepath.Join(dir, "node_modules", "pkg", "main.js"):   "",
+		filepath.Join(dir, "node_modules", "pkg", "module.js"): "",
+	}}
+	srcDir := filepath.Join(dir, "src")
+
+	// Relative alias substitutions are resolved from the current directory
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shimJS := filepath.Join(cwd, "shim-for-resolve-test.js")
+	overlay.Files[shimJS] = ""
+
+	check := func(importPath string, options ResolveOptions, expected ResolveResult) {
+		t.Helper()
+		options.FileSystem = overlay
+		result := Resolve(importPath, srcDir, options)
+		if len(result.Errors) != len(expected.Errors) {
+			t.Fatalf("%s: expected errors %v, got %v", importPath, expected.Errors, result.Errors)
+		}
+		for i, msg := range result.Errors {
+			if msg.Text != expected.Errors[i].Text {
+				t.Fatalf("%s: expected errors %v, got %v", importPath, expected.Errors, result.Errors)
+			}
+		}
+		if result.Path != expected.Path || result.Namespace != expected.Namespace ||
+			result.External != expected.External || result.SideEffectFree != expected.SideEffectFree {
+			t.Fatalf("%s: expected %+v, got %+v", importPath, expected, result)
+		}
+		if len(result.AffectingFiles) != len(expected.AffectingFiles) {
+			t.Fatalf("%s: expected affecting files %v, got %v", importPath, expected.AffectingFiles, result.AffectingFiles)
+		}
+		for i, file := range result.AffectingFiles {
+			if file != expected.AffectingFiles[i] {
+				t.Fatalf("%s: expected affecting files %v, got %v", importPath, expected.AffectingFiles, result.AffectingFiles)
+			}
+		}
+	}
+
+	check("./util", ResolveOptions{}, ResolveResult{
+		Path:           filepath.Join(dir, "src", "util.ts"),
+		Namespace:      "file",
+		AffectingFiles: []string{},
+	})
+	check("@lib/helper", ResolveOptions{}, ResolveResult{
+		Path:           filepath.Join(dir, "lib", "helper.ts"),
+		Namespace:      "file",
+		AffectingFiles: []string{tsconfigJSON},
+	})
+	check("pkg", ResolveOptions{}, ResolveResult{
+		Path:           filepath.Join(dir, "node_modules", "pkg", "module.js"),
+		Namespace:      "file",
+		SideEffectFree: true,
+		AffectingFiles: []string{pkgJSON},
+	})
+	check("pkg", ResolveOptions{Kind: ResolveRequireCall}, ResolveResult{
+		Path:           filepath.Join(dir, "node_modules", "pkg", "main.js"),
+		Namespace:      "file",
+		SideEffectFree: true,
+		AffectingFiles: []string{pkgJSON},
+	})
+	check("pkg", ResolveOptions{External: []string{"pkg"}}, ResolveResult{
+		Path:     "pkg",
+		External: true,
+	})
+	check("@lib/helper", ResolveOptions{External: []string{"@lib/*"}}, ResolveResult{
+		Path:     "@lib/helper",
+		External: true,
+	})
+	check("./util", ResolveOptions{External: []string{"*.ts"}}, ResolveResult{
+		Path:      filepath.Join(dir, "src", "util.ts"),
+		Namespace: "file",
+		External:  true,
+	})
+	check("pkg", ResolveOptions{Packages: PackagesExternal}, ResolveResult{
+		Path:     "pkg",
+		External: true,
+	})
+	check("@lib/helper", ResolveOptions{Packages: PackagesExternal}, ResolveResult{
+		Path:           filepath.Join(dir, "lib", "helper.ts"),
+		Namespace:      "file",
+		AffectingFiles: []string{tsconfigJSON},
+	})
+	check("pkg", ResolveOptions{External: []string{"*pkg*"}}, ResolveResult{
+		Errors: []Message{{Text: `External path "*pkg*" cannot have more than one "*" wildcard`}},
+	})
+	check("react", ResolveOptions{Alias: map[string]string{"react": "pkg"}}, ResolveResult{
+		Path:           filepath.Join(dir, "node_modules", "pkg", "module.js"),
+		Namespace:      "file",
+		SideEffectFree: true,
+		AffectingFiles: []string{pkgJSON},
+	})
+	check("shim", ResolveOptions{Alias: map[string]string{"shim": "./shim-for-resolve-test.js"}}, ResolveResult{
+		Path:           shimJS,
+		Namespace:      "file",
+		AffectingFiles: []string{},
+	})
+	check("shim", ResolveOptions{Alias: map[string]string{"./shim": "./shim-for-resolve-test.js"}}, ResolveResult{
+		Errors: []Message{{Text: `Invalid alias name: "./shim"`}},
+	})
+	check("mapped", ResolveOptions{ImportMap: importMapJSON}, ResolveResult{
+		Path:           filepath.Join(dir, "lib", "helper.ts"),
+		Namespace:      "file",
+		AffectingFiles: []string{},
+	})
+	check("scoped", ResolveOptions{ImportMap: importMapJSON}, ResolveResult{
+		Path:           filepath.Join(dir, "src", "util.ts"),
+		Namespace:      "file",
+		AffectingFiles: []string{},
+	})
+	check("./missing", ResolveOptions{}, ResolveResult{
+		Errors: []Message{{Text: `Could not resolve "./missing"`}},
+	})
+}
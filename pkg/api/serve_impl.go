@@ -0,0 +1,353 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/evanw/esbuild/internal/bundler"
+	"github.com/evanw/esbuild/internal/fs"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Serve API
+
+type apiHandler struct {
+	mutex        sync.Mutex
+	state        *rebuildState
+	absServeRoot string
+	fileServer   http.Handler
+
+	// This is nil until the first build has finished
+	result    *BuildResult
+	watchData fs.WatchData
+
+	// These are only used for hot module replacement
+	watcher         *watcher
+	lastOutputFiles []OutputFile
+	clientsMutex    sync.Mutex
+	clients         map[chan []byte]bool
+}
+
+// This blocks while a build is in progress. Builds only happen if something
+// that the previous build read from the file system has changed since then.
+// This is checked when each request comes in instead of in the background so
+// that a request made right after a change never gets the old output.
+func (h *apiHandler) build() BuildResult {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.result == nil || h.isStale() {
+		result, watchData := rebuildImpl(h.state, nil)
+		if h.watcher != nil {
+			h.watcher.setWatchData(watchData)
+
+			// Don't push anything while the build is broken. The next successful
+			// build will be compared against the last successful one instead.
+			if len(result.Errors) == 0 {
+				if h.result != nil {
+					h.broadcastChanges(h.lastOutputFiles, result.OutputFiles)
+				}
+				h.lastOutputFiles = result.OutputFiles
+			}
+		}
+		h.result = &result
+		h.watchData = watchData
+	}
+	return *h.result
+}
+
+func (h *apiHandler) isStale() bool {
+	for _, isDirty := range h.watchData.Paths {
+		if isDirty() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Requests for files in the static directory don't need to wait for a build.
+// This returns false if the request is for a static file according to the
+// previous build, which may be out of date.
+func (h *apiHandler) needsBuild(urlPath string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.fileServer == nil || h.result == nil || len(h.result.Errors) > 0 {
+		return true
+	}
+	for _, file := range h.result.OutputFiles {
+		if relPath, ok := relativeURLPath(h.absServeRoot, file.Path); ok && relPath == urlPath {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *apiHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Browsers connect here to be notified about changes
+	if h.clients != nil && req.URL.Path == hmrEventsPath {
+		h.serveEvents(res, req)
+		return
+	}
+
+	urlPath := path.Clean("/" + req.URL.Path)
+	if !h.needsBuild(urlPath) {
+		h.fileServer.ServeHTTP(res, req)
+		return
+	}
+
+	// Builds happen lazily when a request comes in
+	result := h.build()
+
+	// Report build errors instead of serving stale or missing output files
+	if len(result.Errors) > 0 {
+		res.Header().Set("Content-Type", "text/plain;charset=utf-8")
+		res.WriteHeader(http.StatusServiceUnavailable)
+		res.Write([]byte(formatBuildErrors(result.Errors)))
+		return
+	}
+
+	// Check the output files first
+	for _, file := range result.OutputFiles {
+		if relPath, ok := relativeURLPath(h.absServeRoot, file.Path); ok && relPath == urlPath {
+			res.Header().Set("Content-Type", bundler.GuessMimeType(path.Ext(urlPath), string(file.Contents)))
+			res.Header().Set("Content-Length", strconv.Itoa(len(file.Contents)))
+			res.WriteHeader(http.StatusOK)
+			if req.Method == "GET" {
+				res.Write(file.Contents)
+			}
+			return
+		}
+	}
+
+	// Then fall back to files in the static directory, if there is one
+	if h.fileServer != nil {
+		h.fileServer.ServeHTTP(res, req)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/plain;charset=utf-8")
+	res.WriteHeader(http.StatusNotFound)
+	res.Write([]byte("404 - Not Found"))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Hot module replacement
+
+// This must match the URL that the runtime connects to
+const hmrEventsPath = "/esbuild"
+
+// This streams "change" events to the browser using server-sent events. Each
+// event lists the URL paths of the output files that changed in a rebuild.
+func (h *apiHandler) serveEvents(res http.ResponseWriter, req *http.Request) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	events := make(chan []byte, 16)
+	h.clientsMutex.Lock()
+	h.clients[events] = true
+	h.clientsMutex.Unlock()
+	defer func() {
+		h.clientsMutex.Lock()
+		delete(h.clients, events)
+		h.clientsMutex.Unlock()
+	}()
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.WriteHeader(http.StatusOK)
+	res.Write([]byte(": connected\n\n"))
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-events:
+			res.Write(event)
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+func (h *apiHandler) broadcastChanges(oldFiles []OutputFile, newFiles []OutputFile) {
+	oldContents := make(map[string]string)
+	for _, file := range oldFiles {
+		oldContents[file.Path] = string(file.Contents)
+	}
+	updated := []string{}
+	for _, file := range newFiles {
+		if contents, ok := oldContents[file.Path]; ok && contents == string(file.Contents) {
+			continue
+		}
+		if urlPath, ok := relativeURLPath(h.absServeRoot, file.Path); ok {
+			updated = append(updated, urlPath)
+		}
+	}
+	if len(updated) == 0 {
+		return
+	}
+
+	data, _ := json.Marshal(struct {
+		Updated []string `json:"updated"`
+	}{updated})
+	event := []byte(fmt.Sprintf("event: change\ndata: %s\n\n", data))
+
+	h.clientsMutex.Lock()
+	defer h.clientsMutex.Unlock()
+	for client := range h.clients {
+		// Drop the event instead of blocking the build on a slow client
+		select {
+		case client <- event:
+		default:
+		}
+	}
+}
+
+// This returns the path relative to the root directory as a URL path starting
+// with a slash, or false if the path isn't inside the root directory
+func relativeURLPath(absRootDir string, absPath string) (string, bool) {
+	relPath, err := filepath.Rel(absRootDir, absPath)
+	if err != nil {
+		return "", false
+	}
+	relPath = filepath.ToSlash(relPath)
+	if relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", false
+	}
+	return "/" + relPath, true
+}
+
+func formatBuildErrors(errors []Message) string {
+	sb := strings.Builder{}
+	for _, msg := range errors {
+		if loc := msg.Location; loc != nil {
+			sb.WriteString(fmt.Sprintf("%s:%d:%d: ", loc.File, loc.Line, loc.Column))
+		}
+		sb.WriteString(fmt.Sprintf("error: %s\n", msg.Text))
+	}
+	return sb.String()
+}
+
+func serveImpl(serveOptions ServeOptions, buildOptions BuildOptions) (ServeResult, error) {
+	realFS := fs.RealFS(fs.RealFSOptions{})
+
+	// Validate the static directory
+	var servedir string
+	if serveOptions.Servedir != "" {
+		absPath, ok := realFS.Abs(serveOptions.Servedir)
+		if !ok {
+			return ServeResult{}, fmt.Errorf("Invalid serve path: %s", serveOptions.Servedir)
+		}
+		if _, err := realFS.ReadDirectory(absPath); err != nil {
+			return ServeResult{}, fmt.Errorf("Cannot read serve directory %q: %s", serveOptions.Servedir, err.Error())
+		}
+		servedir = absPath
+	}
+
+	// Output files are served relative to the output directory. If there is no
+	// output directory, pretend the static directory is the output directory.
+	// Otherwise use the current directory.
+	if buildOptions.Outdir == "" && buildOptions.Outfile == "" {
+		if servedir != "" {
+			buildOptions.Outdir = servedir
+		} else {
+			buildOptions.Outdir = realFS.Cwd()
+		}
+	}
+	var absOutputDir string
+	if buildOptions.Outdir != "" {
+		absOutputDir, _ = realFS.Abs(buildOptions.Outdir)
+	} else {
+		absOutputFile, _ := realFS.Abs(buildOptions.Outfile)
+		absOutputDir = realFS.Dir(absOutputFile)
+	}
+
+	// If the output directory is inside the static directory, serve the output
+	// files at the URL paths they would have if they were written there
+	absServeRoot := absOutputDir
+	if servedir != "" {
+		if _, ok := relativeURLPath(servedir, absOutputDir); ok {
+			absServeRoot = servedir
+		}
+	}
+
+	// The build happens in memory
+	buildOptions.Write = false
+	buildOptions.Watch = nil
+	buildOptions.Incremental = false
+
+	// Pick the port
+	host := serveOptions.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(int(serveOptions.Port))))
+	if err != nil {
+		return ServeResult{}, err
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+
+	state := newRebuildState(buildOptions, true)
+	state.wantWatchData = true
+	state.serveFromMemory = true
+	handler := &apiHandler{
+		state:        state,
+		absServeRoot: absServeRoot,
+	}
+	if servedir != "" {
+		handler.fileServer = http.FileServer(http.Dir(servedir))
+	}
+
+	// With hot module replacement, rebuild as soon as something changes so that
+	// connected browsers can be notified without waiting for another request
+	if buildOptions.HotModuleReplacement {
+		handler.clients = make(map[chan []byte]bool)
+		handler.watcher = &watcher{rebuild: func() { handler.build() }}
+		handler.build()
+		handler.watcher.start()
+	}
+
+	// Run the server in the background
+	server := &http.Server{Handler: handler}
+	var serveErr error
+	serveWaitGroup := sync.WaitGroup{}
+	serveWaitGroup.Add(1)
+	go func() {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			serveErr = err
+		}
+		serveWaitGroup.Done()
+	}()
+
+	return ServeResult{
+		Port: uint16(addr.Port),
+		Host: addr.IP.String(),
+		Wait: func() error {
+			serveWaitGroup.Wait()
+			return serveErr
+		},
+		Stop: func() {
+			if handler.watcher != nil {
+				handler.watcher.stop()
+			}
+			server.Close()
+			serveWaitGroup.Wait()
+		},
+	}, nil
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, contents string) {
+		t.Helper()
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("in.js", "import {x} from './x'; console.log(x)")
+	write("x.js", "export let x = 'first'")
+	write("static.html", "<html></html>")
+
+	result, err := Serve(ServeOptions{Servedir: dir}, BuildOptions{
+		EntryPoints: []string{filepath.Join(dir, "in.js")},
+		Outdir:      filepath.Join(dir, "out"),
+		Bundle:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Stop()
+
+	get := func(urlPath string, expectedStatus int, expectedType string, expectedBody string) {
+		t.Helper()
+		res, err := http.Get(fmt.Sprintf("http://%s:%d%s", result.Host, result.Port, urlPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != expectedStatus {
+			t.Fatalf("%s: expected status %d, got %d", urlPath, expectedStatus, res.StatusCode)
+		}
+		if contentType := res.Header.Get("Content-Type"); !strings.HasPrefix(contentType, expectedType) {
+			t.Fatalf("%s: expected content type %q, got %q", urlPath, expectedType, contentType)
+		}
+		if !strings.Contains(string(body), expectedBody) {
+			t.Fatalf("%s: expected body to contain %q, got %q", urlPath, expectedBody, body)
+		}
+	}
+
+	// Output files are built in memory and nothing is written
+	get("/out/in.js", http.StatusOK, "text/javascript", "first")
+	if _, err := os.Stat(filepath.Join(dir, "out")); err == nil {
+		t.Fatal("Expected the output directory to not exist")
+	}
+
+	// Changing an input file causes a rebuild
+	write("x.js", "export let x = 'second'")
+	get("/out/in.js", http.StatusOK, "text/javascript", "second")
+
+	// Build errors are reported in the response
+	write("x.js", "export let x = ")
+	get("/out/in.js", http.StatusServiceUnavailable, "text/plain", "x.js:1:15: error: Unexpected end of file")
+	write("x.js", "export let x = 'third'")
+	get("/out/in.js", http.StatusOK, "text/javascript", "third")
+
+	// Other files come from the static directory
+	get("/static.html", http.StatusOK, "text/html", "<html></html>")
+	get("/missing.html", http.StatusNotFound, "text/plain", "404")
+
+	// Requests for static files don't cause a rebuild, even if the output is
+	// out of date. The next request for an output file does the build.
+	write("x.js", "export let x = ")
+	get("/static.html", http.StatusOK, "text/html", "<html></html>")
+	get("/out/in.js", http.StatusServiceUnavailable, "text/plain", "x.js:1:15: error: Unexpected end of file")
+}
+
+// This runs the hot module replacement runtime in node to check that updates
+// re-run changed modules and call the "accept" and "dispose" callbacks
+func TestServeHotModuleReplacement(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("Skipping since node isn't installed")
+	}
+
+	dir, err := ioutil.TempDir("", "esbuild-hmr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, contents string) {
+		t.Helper()
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("entry.js", `
+		import {value} from './foo'
+		log.push('entry ' + value)
+		import.meta.hot.accept(() => log.push('accepted'))
+	`)
+	fooJS := `
+		export let value = 'v1'
+		log.push('foo ' + value)
+		import.meta.hot.dispose(data => {
+			log.push('dispose foo ' + value)
+			data.old = value
+		})
+		if (import.meta.hot.data.old) log.push('data ' + import.meta.hot.data.old)
+	`
+	write("foo.js", fooJS)
+
+	result, err := Serve(ServeOptions{}, BuildOptions{
+		EntryPoints:          []string{filepath.Join(dir, "entry.js")},
+		Outdir:               filepath.Join(dir, "out"),
+		Bundle:               true,
+		Format:               FormatIIFE,
+		HotModuleReplacement: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Stop()
+
+	fetch := func(urlPath string) string {
+		t.Helper()
+		res, err := http.Get(fmt.Sprintf("http://%s:%d%s", result.Host, result.Port, urlPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	// Load the first version of the bundle, then change "foo.js" and wait for
+	// the server to rebuild in the background
+	write("v1.js", fetch("/entry.js"))
+	write("foo.js", strings.Replace(fooJS, "'v1'", "'v2'", 1))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if code := fetch("/entry.js"); strings.Contains(code, "v2") {
+			write("v2.js", code)
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for a rebuild")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Running the second version of the bundle after the first one updates the
+	// modules that were loaded by the first one
+	write("package.json", `{"type": "commonjs"}`)
+	write("run.js", `
+		const fs = require('fs')
+		const vm = require('vm')
+		globalThis.log = []
+		vm.runInThisContext(fs.readFileSync(__dirname + '/v1.js', 'utf8'))
+		vm.runInThisContext(fs.readFileSync(__dirname + '/v2.js', 'utf8'))
+		setTimeout(() => console.log(log.join('\n')), 10)
+	`)
+	stdout, err := exec.Command(node, filepath.Join(dir, "run.js")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s: %s", err.Error(), stdout)
+	}
+	expected := strings.Join([]string{
+		"foo v1",
+		"entry v1",
+		"dispose foo v1",
+		"foo v2",
+		"data v1",
+		"entry v2",
+		"accepted",
+	}, "\n") + "\n"
+	if string(stdout) != expected {
+		t.Fatalf("Expected %q, got %q", expected, stdout)
+	}
+}
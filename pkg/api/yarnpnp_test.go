@@ -0,0 +1,68 @@
+package api
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestYarnPnPZipArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "esbuild-yarnpnp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Packages installed by Yarn are stored in zip archives in the cache
+	cacheDir := filepath.Join(dir, ".yarn", "cache")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(filepath.Join(cacheDir, "pkg-npm-1.0.0-abc123.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(file)
+	for name, contents := range map[string]string{
+		"node_modules/pkg/package.json":  `{"main": "./lib/main.js"}`,
+		"node_modules/pkg/lib/main.js":   `module.exports = require('./helper') + ' from a zip file'`,
+		"node_modules/pkg/lib/helper.js": `module.exports = 'pkg'`,
+	} {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(contents))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	result := Build(BuildOptions{
+		EntryPoints: []string{filepath.Join(dir, "entry.js")},
+		Bundle:      true,
+		FileSystem: Overlay{Files: map[string]string{
+			filepath.Join(dir, "entry.js"): "import pkg from 'pkg'; console.log(pkg)",
+			filepath.Join(dir, ".pnp.data.json"): `{
+				"packageRegistryData": [
+					[null, [[null, {"packageLocation": "./", "packageDependencies": [["pkg", "npm:1.0.0"]]}]]],
+					["pkg", [["npm:1.0.0", {
+						"packageLocation": "./.yarn/cache/pkg-npm-1.0.0-abc123.zip/node_modules/pkg/",
+						"packageDependencies": [["pkg", "npm:1.0.0"]]
+					}]]]
+				]
+			}`,
+		}},
+	})
+	if len(result.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", result.Errors)
+	}
+	code := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(code, `"pkg"`) || !strings.Contains(code, `" from a zip file"`) {
+		t.Fatalf("Expected the package in the zip file to be bundled:\n%s", code)
+	}
+}
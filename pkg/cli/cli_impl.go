@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"sort"
 	"strconv"
@@ -37,6 +38,15 @@ func parseOptionsImpl(osArgs []string, buildOpts *api.BuildOptions, transformOpt
 		case arg == "--splitting" && buildOpts != nil:
 			buildOpts.Splitting = true
 
+		case arg == "--watch" && buildOpts != nil:
+			buildOpts.Watch = &api.WatchMode{}
+
+		case arg == "--hmr" && buildOpts != nil:
+			buildOpts.HotModuleReplacement = true
+
+		case arg == "--detect-cycles" && buildOpts != nil:
+			buildOpts.DetectCycles = true
+
 		case arg == "--minify":
 			if buildOpts != nil {
 				buildOpts.MinifySyntax = true
@@ -133,6 +143,12 @@ func parseOptionsImpl(osArgs []string, buildOpts *api.BuildOptions, transformOpt
 		case strings.HasPrefix(arg, "--main-fields=") && buildOpts != nil:
 			buildOpts.MainFields = strings.Split(arg[len("--main-fields="):], ",")
 
+		case strings.HasPrefix(arg, "--conditions=") && buildOpts != nil:
+			buildOpts.Conditions = strings.Split(arg[len("--conditions="):], ",")
+
+		case strings.HasPrefix(arg, "--import-map=") && buildOpts != nil:
+			buildOpts.ImportMap = arg[len("--import-map="):]
+
 		case strings.HasPrefix(arg, "--public-path=") && buildOpts != nil:
 			buildOpts.PublicPath = arg[len("--public-path="):]
 
@@ -143,6 +159,9 @@ func parseOptionsImpl(osArgs []string, buildOpts *api.BuildOptions, transformOpt
 				transformOpts.GlobalName = arg[len("--global-name="):]
 			}
 
+		case strings.HasPrefix(arg, "--explain=") && buildOpts != nil:
+			buildOpts.Explain = append(buildOpts.Explain, arg[len("--explain="):])
+
 		case strings.HasPrefix(arg, "--metafile=") && buildOpts != nil:
 			buildOpts.Metafile = arg[len("--metafile="):]
 
@@ -170,6 +189,48 @@ func parseOptionsImpl(osArgs []string, buildOpts *api.BuildOptions, transformOpt
 				transformOpts.Define[value[:equals]] = value[equals+1:]
 			}
 
+		case strings.HasPrefix(arg, "--alias:") && buildOpts != nil:
+			value := arg[len("--alias:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return fmt.Errorf("Missing \"=\": %q", value)
+			}
+			if buildOpts.Alias == nil {
+				buildOpts.Alias = make(map[string]string)
+			}
+			buildOpts.Alias[value[:equals]] = value[equals+1:]
+
+		case strings.HasPrefix(arg, "--min-chunk-size=") && buildOpts != nil:
+			value := arg[len("--min-chunk-size="):]
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 0 {
+				return fmt.Errorf("Invalid minimum chunk size: %q", value)
+			}
+			buildOpts.MinChunkSize = size
+
+		case strings.HasPrefix(arg, "--manual-chunk:") && buildOpts != nil:
+			value := arg[len("--manual-chunk:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return fmt.Errorf("Missing \"=\": %q", value)
+			}
+			if buildOpts.ManualChunks == nil {
+				buildOpts.ManualChunks = make(map[string][]string)
+			}
+			name := value[:equals]
+			buildOpts.ManualChunks[name] = append(buildOpts.ManualChunks[name], strings.Split(value[equals+1:], ",")...)
+
+		case strings.HasPrefix(arg, "--global:") && buildOpts != nil:
+			value := arg[len("--global:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return fmt.Errorf("Missing \"=\": %q", value)
+			}
+			if buildOpts.Globals == nil {
+				buildOpts.Globals = make(map[string]string)
+			}
+			buildOpts.Globals[value[:equals]] = value[equals+1:]
+
 		case strings.HasPrefix(arg, "--pure:"):
 			value := arg[len("--pure:"):]
 			if buildOpts != nil {
@@ -233,6 +294,15 @@ func parseOptionsImpl(osArgs []string, buildOpts *api.BuildOptions, transformOpt
 			}
 			buildOpts.OutExtensions[value[:equals]] = value[equals+1:]
 
+		case strings.HasPrefix(arg, "--packages=") && buildOpts != nil:
+			value := arg[len("--packages="):]
+			switch value {
+			case "external":
+				buildOpts.Packages = api.PackagesExternal
+			default:
+				return fmt.Errorf("Invalid packages: %q (valid: external)", value)
+			}
+
 		case strings.HasPrefix(arg, "--platform=") && buildOpts != nil:
 			value := arg[len("--platform="):]
 			switch value {
@@ -265,8 +335,20 @@ func parseOptionsImpl(osArgs []string, buildOpts *api.BuildOptions, transformOpt
 				} else {
 					transformOpts.Format = api.FormatESModule
 				}
+			case "umd":
+				if buildOpts != nil {
+					buildOpts.Format = api.FormatUMD
+				} else {
+					transformOpts.Format = api.FormatUMD
+				}
+			case "system":
+				if buildOpts != nil {
+					buildOpts.Format = api.FormatSystem
+				} else {
+					transformOpts.Format = api.FormatSystem
+				}
 			default:
-				return fmt.Errorf("Invalid format: %q (valid: iife, cjs, esm)", value)
+				return fmt.Errorf("Invalid format: %q (valid: iife, cjs, esm, umd, system)", value)
 			}
 
 		case strings.HasPrefix(arg, "--external:") && buildOpts != nil:
@@ -343,6 +425,53 @@ func parseOptionsImpl(osArgs []string, buildOpts *api.BuildOptions, transformOpt
 				transformOpts.LogLevel = logLevel
 			}
 
+		// Make sure this stays in sync with "PrintErrorToStderr"
+		case strings.HasPrefix(arg, "--log-format="):
+			value := arg[len("--log-format="):]
+			var logFormat api.LogFormat
+			switch value {
+			case "text":
+				logFormat = api.LogFormatText
+			case "json":
+				logFormat = api.LogFormatJSON
+			default:
+				return fmt.Errorf("Invalid log format: %q (valid: text, json)", arg)
+			}
+			if buildOpts != nil {
+				buildOpts.LogFormat = logFormat
+			} else {
+				transformOpts.LogFormat = logFormat
+			}
+
+		case strings.HasPrefix(arg, "--log-override:"):
+			value := arg[len("--log-override:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return fmt.Errorf("Missing \"=\": %q", value)
+			}
+			var logLevel api.LogLevel
+			switch value[equals+1:] {
+			case "warning":
+				logLevel = api.LogLevelWarning
+			case "error":
+				logLevel = api.LogLevelError
+			case "silent":
+				logLevel = api.LogLevelSilent
+			default:
+				return fmt.Errorf("Invalid log override: %q (valid: warning, error, silent)", arg)
+			}
+			if buildOpts != nil {
+				if buildOpts.LogOverride == nil {
+					buildOpts.LogOverride = make(map[string]api.LogLevel)
+				}
+				buildOpts.LogOverride[value[:equals]] = logLevel
+			} else {
+				if transformOpts.LogOverride == nil {
+					transformOpts.LogOverride = make(map[string]api.LogLevel)
+				}
+				transformOpts.LogOverride[value[:equals]] = logLevel
+			}
+
 		case !strings.HasPrefix(arg, "-") && buildOpts != nil:
 			buildOpts.EntryPoints = append(buildOpts.EntryPoints, arg)
 
@@ -483,9 +612,103 @@ func parseOptionsForRun(osArgs []string) (*api.BuildOptions, *api.TransformOptio
 	return nil, &options, nil
 }
 
+// The serve flags only apply to the command-line interface, so they are
+// removed here before the remaining flags are parsed as build options
+func parseServeOptionsImpl(osArgs []string) (*api.ServeOptions, []string, error) {
+	host := ""
+	portText := ""
+	servedir := ""
+	isServing := false
+
+	filteredArgs := make([]string, 0, len(osArgs))
+	for _, arg := range osArgs {
+		switch {
+		case strings.HasPrefix(arg, "--serve="):
+			isServing = true
+			portText = arg[len("--serve="):]
+			if colon := strings.LastIndexByte(portText, ':'); colon != -1 {
+				host = portText[:colon]
+				portText = portText[colon+1:]
+			}
+
+		case strings.HasPrefix(arg, "--servedir="):
+			servedir = arg[len("--servedir="):]
+
+		default:
+			filteredArgs = append(filteredArgs, arg)
+		}
+	}
+
+	if !isServing {
+		if servedir != "" {
+			return nil, nil, fmt.Errorf("Cannot use \"servedir\" without \"serve\"")
+		}
+		return nil, osArgs, nil
+	}
+
+	port, err := strconv.Atoi(portText)
+	if err != nil || port < 0 || port > 0xFFFF {
+		return nil, nil, fmt.Errorf("Invalid port number: %s", portText)
+	}
+
+	return &api.ServeOptions{
+		Port:     uint16(port),
+		Host:     host,
+		Servedir: servedir,
+	}, filteredArgs, nil
+}
+
+// The analyze flag also only applies to the command-line interface. It prints
+// a report using the metafile after the build has finished.
+func parseAnalyzeOptionsImpl(osArgs []string) (*api.AnalyzeMetafileOptions, []string) {
+	var analyzeOptions *api.AnalyzeMetafileOptions
+
+	filteredArgs := make([]string, 0, len(osArgs))
+	for _, arg := range osArgs {
+		switch arg {
+		case "--analyze":
+			analyzeOptions = &api.AnalyzeMetafileOptions{}
+
+		case "--analyze=gzip":
+			analyzeOptions = &api.AnalyzeMetafileOptions{Gzip: true}
+
+		default:
+			filteredArgs = append(filteredArgs, arg)
+		}
+	}
+
+	return analyzeOptions, filteredArgs
+}
+
+func formatImportChain(chain api.ImportChain) string {
+	if chain.File == "" {
+		return fmt.Sprintf("\n  No file in the bundle matches %q\n", chain.Query)
+	}
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("\n  Import chain for %q:\n\n", chain.Query))
+	for _, link := range chain.Links {
+		sb.WriteString(fmt.Sprintf("    %s\n      imports %q (%s)\n", link.Importer, link.ImportPath, link.Kind))
+	}
+	sb.WriteString(fmt.Sprintf("    %s\n", chain.File))
+	return sb.String()
+}
+
 func runImpl(osArgs []string) int {
+	serveOptions, filteredArgs, err := parseServeOptionsImpl(osArgs)
+	if err != nil {
+		logger.PrintErrorToStderr(osArgs, err.Error())
+		return 1
+	}
+	analyzeOptions, filteredArgs := parseAnalyzeOptionsImpl(filteredArgs)
+	osArgs = filteredArgs
+
 	buildOptions, transformOptions, err := parseOptionsForRun(osArgs)
 
+	// Serving always builds, even if there are no entry points on the command line
+	if serveOptions != nil && err == nil && buildOptions == nil {
+		err = fmt.Errorf("Must specify entry points when using \"serve\"")
+	}
+
 	switch {
 	case buildOptions != nil:
 		// Read from stdin when there are no entry points
@@ -512,12 +735,90 @@ func runImpl(osArgs []string) int {
 			return 1
 		}
 
+		// The report is generated from the metafile that the build writes out
+		if analyzeOptions != nil {
+			if buildOptions.Metafile == "" {
+				logger.PrintErrorToStderr(osArgs, "Cannot use \"analyze\" without \"metafile\"")
+				return 1
+			}
+			if serveOptions != nil || buildOptions.Watch != nil {
+				logger.PrintErrorToStderr(osArgs, "Cannot use \"analyze\" with \"serve\" or \"watch\"")
+				return 1
+			}
+		}
+
+		// Serve mode builds in memory whenever a request comes in
+		if serveOptions != nil {
+			if buildOptions.Watch != nil {
+				logger.PrintErrorToStderr(osArgs, "Cannot use \"watch\" with \"serve\"")
+				return 1
+			}
+			result, err := api.Serve(*serveOptions, *buildOptions)
+			if err != nil {
+				logger.PrintErrorToStderr(osArgs, err.Error())
+				return 1
+			}
+			if buildOptions.LogLevel == api.LogLevelInfo {
+				fmt.Fprintf(os.Stderr, "\n > Serving on http://%s/\n\n",
+					net.JoinHostPort(result.Host, strconv.Itoa(int(result.Port))))
+			}
+			if err := result.Wait(); err != nil {
+				logger.PrintErrorToStderr(osArgs, err.Error())
+				return 1
+			}
+			return 0
+		}
+
+		// Watch mode keeps running and rebuilds whenever an input changes
+		if buildOptions.Watch != nil {
+			logWatch := func(text string) {
+				if buildOptions.LogLevel == api.LogLevelInfo {
+					fmt.Fprintf(os.Stderr, "[watch] %s\n", text)
+				}
+			}
+			buildOptions.Watch.OnRebuild = func(result api.BuildResult) {
+				logWatch("build finished")
+			}
+			api.Build(*buildOptions)
+			logWatch("build finished, watching for changes...")
+
+			// Never exit
+			select {}
+		}
+
 		// Run the build and stop if there were errors
 		result := api.Build(*buildOptions)
 		if len(result.Errors) > 0 {
 			return 1
 		}
 
+		// Print the import chains after the build summary
+		for _, chain := range result.ImportChains {
+			os.Stderr.WriteString(formatImportChain(chain))
+		}
+
+		// Print the report after the build summary
+		if analyzeOptions != nil {
+			metafile, err := ioutil.ReadFile(buildOptions.Metafile)
+			if err != nil {
+				logger.PrintErrorToStderr(osArgs, fmt.Sprintf(
+					"Could not read metafile: %s", err.Error()))
+				return 1
+			}
+			switch buildOptions.Color {
+			case api.ColorAlways:
+				analyzeOptions.Color = logger.SupportsColorEscapes
+			case api.ColorIfTerminal:
+				analyzeOptions.Color = logger.GetTerminalInfo(os.Stderr).UseColorEscapes
+			}
+			report, err := api.AnalyzeMetafile(string(metafile), *analyzeOptions)
+			if err != nil {
+				logger.PrintErrorToStderr(osArgs, err.Error())
+				return 1
+			}
+			os.Stderr.WriteString(report)
+		}
+
 	case transformOptions != nil:
 		// Read the input from stdin
 		bytes, err := ioutil.ReadAll(os.Stdin)
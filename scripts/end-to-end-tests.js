@@ -1572,6 +1572,168 @@ in.js:24:30: warning: Writing to getter-only property "#getter" will throw
         }
       `,
     }, { async: true }),
+
+    // Code splitting with CommonJS output uses live bindings between chunks
+    test(['a.js', 'b.js', '--outdir=out', '--splitting', '--format=cjs', '--bundle'], {
+      'a.js': `
+        import {foo, setFoo} from './common'
+        export let a = () => { setFoo(234); return foo }
+      `,
+      'b.js': `
+        import {foo} from './common'
+        export let b = () => foo
+      `,
+      'common.js': `
+        export let foo = 123
+        export function setFoo(value) { foo = value }
+      `,
+      'node.js': `
+        const {a} = require('./out/a.js')
+        const {b} = require('./out/b.js')
+        if (b() !== 123 || a() !== 234 || b() !== 234) throw 'fail'
+      `,
+    }),
+
+    // Code splitting with CommonJS output and async imports
+    test(['a.js', '--outdir=out', '--splitting', '--format=cjs', '--bundle'], {
+      'a.js': `
+        import * as ns1 from './b'
+        export default async function () {
+          const ns2 = await import('./b')
+          return [ns1.foo, -ns2.foo]
+        }
+      `,
+      'b.js': `
+        export let foo = 123
+      `,
+      'node.js': `
+        exports.async = async () => {
+          const {default: fn} = require('./out/a.js')
+          const [a, b] = await fn()
+          if (a !== 123 || b !== -123) throw 'fail'
+        }
+      `,
+    }, { async: true }),
+
+    // Code splitting with IIFE output loads chunks using script tags. This runs
+    // the chunks in a fake browser where script tags evaluate the output files.
+    test(['a.js', 'b.js', '--outdir=out', '--splitting', '--format=iife', '--bundle'], {
+      'a.js': `
+        import {foo, setFoo} from './common'
+        self.a = async () => {
+          setFoo(234)
+          const {c} = await import('./c')
+          return [foo, c]
+        }
+      `,
+      'b.js': `
+        import {foo} from './common'
+        self.b = () => foo
+      `,
+      'c.js': `
+        export let c = 'c'
+      `,
+      'common.js': `
+        self.commonCount = (self.commonCount || 0) + 1
+        export let foo = 123
+        export function setFoo(value) { foo = value }
+      `,
+      'node.js': `
+        const fs = require('fs')
+        const path = require('path')
+        const vm = require('vm')
+        const base = 'http://example.com/out/'
+        const browser = { URL, Promise, setTimeout, location: { href: base + 'index.html' } }
+        browser.self = browser
+        browser.document = {
+          currentScript: null,
+          createElement: () => ({}),
+          head: { appendChild: script => setTimeout(() => load(script)) },
+        }
+        vm.createContext(browser)
+        function load(script) {
+          const file = path.join(__dirname, 'out', script.src.slice(base.length))
+          if (!fs.existsSync(file)) return script.onerror()
+          browser.document.currentScript = script
+          vm.runInContext(fs.readFileSync(file, 'utf8'), browser)
+          browser.document.currentScript = null
+        }
+        exports.async = async () => {
+          load({ src: base + 'a.js' })
+          load({ src: base + 'b.js' })
+          while (!browser.a || !browser.b) await new Promise(r => setTimeout(r, 10))
+          if (browser.b() !== 123) throw 'fail: b'
+          const [foo, c] = await browser.a()
+          if (foo !== 234 || c !== 'c' || browser.b() !== 234) throw 'fail: a'
+          if (browser.commonCount !== 1) throw 'fail: common'
+
+          // Only entry point chunks define the chunk loader
+          for (const name of fs.readdirSync(path.join(__dirname, 'out'))) {
+            const code = fs.readFileSync(path.join(__dirname, 'out', name), 'utf8')
+            if (name.startsWith('chunk.') !== code.startsWith('self.esbuildChunks.register(')) throw 'fail: ' + name
+          }
+        }
+      `,
+    }, { async: true }),
+
+    // Code splitting with a manual chunk that pulls in a shared dependency
+    test(['a.js', 'b.js', '--outdir=out', '--splitting', '--format=cjs', '--bundle', '--manual-chunk:vendor=pkg'], {
+      'a.js': `
+        import {inc} from 'pkg'
+        export let a = () => inc()
+      `,
+      'b.js': `
+        import {count} from './common'
+        export let b = () => count()
+      `,
+      'common.js': `
+        let value = 0
+        export let count = () => value
+        export function setCount(v) { value = v }
+      `,
+      'node_modules/pkg/package.json': `{ "name": "pkg" }`,
+      'node_modules/pkg/index.js': `
+        import {count, setCount} from '../../common'
+        export function inc() { setCount(count() + 1); return count() }
+      `,
+      'node.js': `
+        const fs = require('fs')
+        const {a} = require('./out/a.js')
+        const {b} = require('./out/b.js')
+        if (!fs.readdirSync('out').some(name => /^vendor\.\w+\.js$/.test(name))) throw 'fail: no vendor chunk'
+        if (a() !== 1 || b() !== 1) throw 'fail'
+      `,
+    }),
+
+    // Code splitting doesn't copy a small chunk into entry points if another
+    // shared chunk uses it, since that chunk wouldn't have a copy
+    test(['a.js', 'b.js', 'c.js', '--outdir=out', '--splitting', '--format=esm', '--bundle', '--min-chunk-size=100000'], {
+      'a.js': `
+        import {state} from './x'
+        export let a = 'a' + state
+      `,
+      'b.js': `
+        import {state} from './x'
+        export let b = 'b' + state
+      `,
+      'c.js': `
+        import {d} from './util'
+        export let c = 'c' + d()
+      `,
+      'x.js': `
+        import {d} from './util'
+        export let state = d()
+      `,
+      'util.js': `
+        export function d() { return 123 }
+      `,
+      'node.js': `
+        import {a} from './out/a.js'
+        import {b} from './out/b.js'
+        import {c} from './out/c.js'
+        if (a !== 'a123' || b !== 'b123' || c !== 'c123') throw 'fail'
+      `,
+    }),
   )
 
   // Test the binary loader